@@ -0,0 +1,194 @@
+package arc
+
+import (
+	"container/list"
+	"geecache/lru"
+)
+
+// Cache is an ARC (Adaptive Replacement Cache) bounded by bytes. It keeps
+// recently used entries (t1) and frequently used entries (t2), and uses
+// ghost lists of recently evicted keys (b1, b2) to adapt the target size of
+// t1. It is not safe for concurrent access.
+type Cache struct {
+	maxBytes int64
+	p        int64 // t1 的目标字节数，根据幽灵链表的命中自适应调整
+
+	t1, t2, b1, b2 *segment
+	cache          map[string]*list.Element // key -> 所在链表中的元素
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value Value)
+}
+
+// Value use Len to count how many bytes it takes
+type Value = lru.Value
+
+type entry struct {
+	key   string
+	value Value // 幽灵链表中的条目 value 为 nil
+	size  int64
+	seg   *segment
+}
+
+// segment 是一个带字节统计的 LRU 链表，表头为最近使用
+type segment struct {
+	ll    *list.List
+	bytes int64
+}
+
+func newSegment() *segment {
+	return &segment{ll: list.New()}
+}
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		t1:        newSegment(),
+		t2:        newSegment(),
+		b1:        newSegment(),
+		b2:        newSegment(),
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Add adds a value to the cache.
+func (c *Cache) Add(key string, value Value) {
+	size := int64(len(key)) + int64(value.Len())
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		switch kv.seg {
+		case c.t1, c.t2:
+			// 已缓存：更新值并提升到 t2
+			kv.seg.bytes += size - kv.size
+			kv.value, kv.size = value, size
+			c.move(ele, c.t2)
+			c.replace(false)
+			return
+		case c.b1:
+			// 近期被淘汰的条目再次出现，说明 t1 偏小
+			c.p = min64(c.maxBytes, c.p+max64(size, size*c.b2.bytes/max64(c.b1.bytes, 1)))
+			c.drop(ele)
+			c.push(c.t2, key, value, size)
+			c.replace(false)
+			return
+		case c.b2:
+			// 高频条目被淘汰后再次出现，说明 t2 偏小
+			c.p = max64(0, c.p-max64(size, size*c.b1.bytes/max64(c.b2.bytes, 1)))
+			c.drop(ele)
+			c.push(c.t2, key, value, size)
+			c.replace(true)
+			return
+		}
+	}
+
+	// 新条目：先限制幽灵链表的大小，为新条目腾出历史记录空间
+	if c.maxBytes != 0 {
+		for c.t1.bytes+c.b1.bytes+size > c.maxBytes && c.b1.ll.Len() > 0 {
+			c.drop(c.b1.ll.Back())
+		}
+		for c.t1.bytes+c.t2.bytes+c.b1.bytes+c.b2.bytes+size > 2*c.maxBytes && c.b2.ll.Len() > 0 {
+			c.drop(c.b2.ll.Back())
+		}
+	}
+	c.push(c.t1, key, value, size)
+	c.replace(false)
+}
+
+// Get look ups a key's value
+func (c *Cache) Get(key string) (value Value, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	kv := ele.Value.(*entry)
+	if kv.seg != c.t1 && kv.seg != c.t2 {
+		return nil, false
+	}
+	c.move(ele, c.t2)
+	return kv.value, true
+}
+
+// Remove removes the provided key from the cache.
+func (c *Cache) Remove(key string) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	kv := ele.Value.(*entry)
+	c.drop(ele)
+	if kv.value != nil && c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
+
+// Len the number of cache entries
+func (c *Cache) Len() int {
+	return c.t1.ll.Len() + c.t2.ll.Len()
+}
+
+// Size returns the number of bytes used by the cache
+func (c *Cache) Size() int64 {
+	return c.t1.bytes + c.t2.bytes
+}
+
+func (c *Cache) push(seg *segment, key string, value Value, size int64) {
+	kv := &entry{key: key, value: value, size: size, seg: seg}
+	c.cache[key] = seg.ll.PushFront(kv)
+	seg.bytes += size
+}
+
+func (c *Cache) move(ele *list.Element, to *segment) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.bytes -= kv.size
+	kv.seg = to
+	c.cache[kv.key] = to.ll.PushFront(kv)
+	to.bytes += kv.size
+}
+
+func (c *Cache) drop(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.bytes -= kv.size
+	delete(c.cache, kv.key)
+}
+
+// replace 在超出容量时将 t1 或 t2 中最久未使用的条目淘汰到对应的幽灵链表
+func (c *Cache) replace(hitB2 bool) {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.t1.bytes+c.t2.bytes > c.maxBytes {
+		if c.t1.ll.Len() > 0 && (c.t1.bytes > c.p || (hitB2 && c.t1.bytes == c.p) || c.t2.ll.Len() == 0) {
+			c.evict(c.t1, c.b1)
+		} else {
+			c.evict(c.t2, c.b2)
+		}
+	}
+}
+
+func (c *Cache) evict(from, ghost *segment) {
+	ele := from.ll.Back()
+	kv := ele.Value.(*entry)
+	value := kv.value
+	c.move(ele, ghost)
+	kv.value = nil
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, value)
+	}
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
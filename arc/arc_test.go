@@ -0,0 +1,94 @@
+package arc
+
+import (
+	"reflect"
+	"testing"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	arc := New(int64(0), nil)
+	arc.Add("key1", String("1234"))
+	if v, ok := arc.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := arc.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestScanResistance(t *testing.T) {
+	// 每个条目 4 字节，可容纳 4 个条目
+	arc := New(int64(16), nil)
+	arc.Add("h1", String("aa"))
+	arc.Add("h2", String("bb"))
+	// 热点数据被多次访问后进入 t2
+	arc.Get("h1")
+	arc.Get("h2")
+
+	// 一次性扫描大量冷数据
+	for _, k := range []string{"s1", "s2", "s3", "s4", "s5", "s6"} {
+		arc.Add(k, String("xx"))
+	}
+
+	for _, k := range []string{"h1", "h2"} {
+		if _, ok := arc.Get(k); !ok {
+			t.Fatalf("hot key %s should survive the scan", k)
+		}
+	}
+	if arc.Size() > 16 || arc.Len() != 4 {
+		t.Fatalf("expected 4 entries within 16 bytes, got %d entries, %d bytes", arc.Len(), arc.Size())
+	}
+}
+
+func TestGhostHit(t *testing.T) {
+	arc := New(int64(8), nil)
+	arc.Add("k1", String("11"))
+	arc.Add("k2", String("22"))
+	arc.Add("k3", String("33")) // k1 被淘汰到 b1
+
+	if _, ok := arc.Get("k1"); ok {
+		t.Fatalf("k1 should be evicted")
+	}
+	arc.Add("k1", String("11")) // 命中 b1，增大 t1 的目标大小并直接进入 t2
+	if arc.p == 0 {
+		t.Fatalf("ghost hit in b1 should increase p")
+	}
+	if v, ok := arc.Get("k1"); !ok || string(v.(String)) != "11" {
+		t.Fatalf("k1 should be cached again")
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	keys := make([]string, 0)
+	callback := func(key string, value Value) {
+		keys = append(keys, key)
+	}
+	arc := New(int64(10), callback)
+	arc.Add("key1", String("123456"))
+	arc.Add("k2", String("k2"))
+	arc.Add("k3", String("k3"))
+	arc.Add("k4", String("k4"))
+
+	expect := []string{"key1", "k2"}
+
+	if !reflect.DeepEqual(expect, keys) {
+		t.Fatalf("Call OnEvicted failed, expect keys equals to %s, got %s", expect, keys)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	arc := New(int64(0), nil)
+	arc.Add("key1", String("1234"))
+	arc.Get("key1")
+	arc.Remove("key1")
+
+	if arc.Len() != 0 || arc.Size() != 0 {
+		t.Fatalf("Remove key1 failed, len=%d size=%d", arc.Len(), arc.Size())
+	}
+}
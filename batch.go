@@ -0,0 +1,214 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"log"
+	"sync"
+)
+
+// GetMany gets values for multiple keys. Keys owned by the same peer are
+// fetched with a single batched request, and keys owned by this node are
+// loaded concurrently. It returns the values that were found together with
+// the errors of the keys that failed.
+func (g *Group) GetMany(keys []string) (map[string]ByteView, map[string]error) {
+	return g.GetManyContext(context.Background(), keys)
+}
+
+// GetManyContext is like GetMany but the deadline and cancellation of ctx
+// are propagated to remote peers and the Getter. With replication enabled
+// each key is read through its replicas like GetContext, without batching.
+func (g *Group) GetManyContext(ctx context.Context, keys []string) (map[string]ByteView, map[string]error) {
+	b := &batch{
+		values: make(map[string]ByteView, len(keys)),
+		errs:   make(map[string]error),
+	}
+	// 多副本时按一致性级别逐个读取副本，批量请求只能发给单个节点
+	if _, replicated := g.replicaPicker(); replicated {
+		g.getManyReplicated(ctx, keys, b)
+		return b.values, b.errs
+	}
+
+	// 先查本地缓存，未命中的key按拥有者节点分组
+	var local []string
+	remote := make(map[PeerGetter][]string)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if key == "" {
+			b.fail(key, fmt.Errorf("key is required"))
+			continue
+		}
+		if v, ok := g.lookupCache(key); ok {
+			g.stats.hits.Add(1)
+			g.recordAccess(key)
+			b.set(key, v)
+			continue
+		}
+
+		g.stats.misses.Add(1)
+		if v, ok := g.getFromL2(key); ok {
+			g.recordAccess(key)
+			b.set(key, v)
+			continue
+		}
+		if g.negativeHit(key) {
+			b.fail(key, notFoundError(key))
+			continue
+		}
+		if g.peers != nil {
+			if peer, ok := g.peers.PickPeer(key); ok {
+				remote[peer] = append(remote[peer], key)
+				continue
+			}
+		}
+		local = append(local, key)
+	}
+
+	var wg sync.WaitGroup
+	// 每个远程节点只发送一次批量请求
+	for peer, peerKeys := range remote {
+		wg.Add(1)
+		go func(peer PeerGetter, peerKeys []string) {
+			defer wg.Done()
+			if err := g.getManyFromPeer(ctx, peer, peerKeys, b); err != nil {
+				log.Println("[GeeCache] Failed to get batch from peer", err)
+				// 节点不可用时在本节点加载
+				g.loadManyLocally(ctx, peerKeys, b)
+			}
+		}(peer, peerKeys)
+	}
+	// 本节点负责的key并发加载，同一key仍由singleflight去重
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.loadManyLocally(ctx, local, b)
+	}()
+	wg.Wait()
+
+	return b.values, b.errs
+}
+
+// getManyReplicated 对每个key并发调用 GetContext
+func (g *Group) getManyReplicated(ctx context.Context, keys []string, b *batch) {
+	var wg sync.WaitGroup
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			view, err := g.GetContext(ctx, key)
+			if err != nil {
+				b.fail(key, err)
+				return
+			}
+			b.set(key, view)
+		}(key)
+	}
+	wg.Wait()
+}
+
+// batch 收集批量获取的结果，可被多个goroutine并发写入
+type batch struct {
+	mu     sync.Mutex
+	values map[string]ByteView
+	errs   map[string]error
+}
+
+func (b *batch) set(key string, value ByteView) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[key] = value
+}
+
+func (b *batch) fail(key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errs[key] = err
+}
+
+// loadManyLocally 并发在本节点加载key，哈希环刚发生变化时与 Get 一样先从之前的拥有者读取
+func (g *Group) loadManyLocally(ctx context.Context, keys []string, b *batch) {
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+				return g.loadLocally(ctx, key)
+			})
+			if err != nil {
+				b.fail(key, err)
+				return
+			}
+			b.set(key, viewi.(ByteView))
+		}(key)
+	}
+	wg.Wait()
+}
+
+func (g *Group) getManyFromPeer(ctx context.Context, peer PeerGetter, keys []string, b *batch) error {
+	req := &pb.BatchRequest{
+		Group:      g.name,
+		Keys:       keys,
+		Generation: g.Generation(),
+	}
+	res := &pb.BatchResponse{}
+	if err := peer.GetMany(ctx, req, res); err != nil {
+		return err
+	}
+
+	returned := make(map[string]bool, len(res.GetItems()))
+	for _, item := range res.GetItems() {
+		returned[item.GetKey()] = true
+		if item.GetNotFound() {
+			err := notFoundError(item.GetKey())
+			g.cacheNotFound(item.GetKey(), err)
+			b.fail(item.GetKey(), err)
+			continue
+		}
+		if item.GetError() != "" {
+			b.fail(item.GetKey(), errors.New(item.GetError()))
+			continue
+		}
+		b.set(item.GetKey(), viewFromResponse(&pb.Response{
+			Value:  item.GetValue(),
+			Expire: item.GetExpire(),
+		}))
+	}
+	for _, key := range keys {
+		if !returned[key] {
+			b.fail(key, fmt.Errorf("peer returned no result for key %s", key))
+		}
+	}
+	return nil
+}
+
+// batchResponse 将批量获取的结果转换为发送给远程节点的响应
+func batchResponse(keys []string, values map[string]ByteView, errs map[string]error) *pb.BatchResponse {
+	res := &pb.BatchResponse{Items: make([]*pb.BatchItem, 0, len(keys))}
+	for _, key := range keys {
+		item := &pb.BatchItem{Key: key}
+		if err, ok := errs[key]; ok {
+			item.Error = err.Error()
+			item.NotFound = errors.Is(err, ErrNotFound)
+		} else if view, ok := values[key]; ok {
+			r := responseFromView(view)
+			item.Value, item.Expire = r.Value, r.Expire
+		} else {
+			continue
+		}
+		res.Items = append(res.Items, item)
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// ErrIncompatible is returned when merging filters of different sizes.
+var ErrIncompatible = errors.New("bloom: filters have different parameters")
+
+// Filter is a Bloom filter of keys. Keys that were added are always reported
+// as possibly present, other keys are reported present with about the false
+// positive rate the filter was sized for. It is safe for concurrent access.
+type Filter struct {
+	k    uint32 // number of hash functions
+	mask uint64 // number of bits minus one, a power of two
+	bits []uint64
+}
+
+// New returns a filter sized to hold n keys with false positive rate p. The
+// number of bits is rounded up to a power of two, so filters created with
+// the same n and p can be merged.
+func New(n int, p float64) *Filter {
+	if n <= 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	bits := uint64(64)
+	for bits < m {
+		bits <<= 1
+	}
+	k := uint32(math.Round(float64(bits) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > 16 {
+		k = 16
+	}
+	return newFilter(bits, k)
+}
+
+func newFilter(bits uint64, k uint32) *Filter {
+	return &Filter{
+		k:    k,
+		mask: bits - 1,
+		bits: make([]uint64, bits/64),
+	}
+}
+
+// 使用双重哈希生成 k 个下标，与 cmsketch 相同
+func hash(key string) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum, sum>>32 | 1
+}
+
+// Add adds key to the filter.
+func (f *Filter) Add(key string) {
+	h1, h2 := hash(key)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		idx := (h1 + i*h2) & f.mask
+		word, bit := &f.bits[idx/64], uint64(1)<<(idx%64)
+		if atomic.LoadUint64(word)&bit == 0 {
+			atomic.OrUint64(word, bit)
+		}
+	}
+}
+
+// MayContain reports whether key may have been added. A false result means
+// the key was definitely never added.
+func (f *Filter) MayContain(key string) bool {
+	h1, h2 := hash(key)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		idx := (h1 + i*h2) & f.mask
+		if atomic.LoadUint64(&f.bits[idx/64])&(uint64(1)<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge adds all keys of other to f. Both filters must have been created
+// with the same parameters.
+func (f *Filter) Merge(other *Filter) error {
+	if f.k != other.k || f.mask != other.mask {
+		return ErrIncompatible
+	}
+	for i := range other.bits {
+		if w := atomic.LoadUint64(&other.bits[i]); w != 0 {
+			atomic.OrUint64(&f.bits[i], w)
+		}
+	}
+	return nil
+}
+
+// Compatible reports whether other can be merged into f.
+func (f *Filter) Compatible(other *Filter) bool {
+	return f.k == other.k && f.mask == other.mask
+}
+
+// EmptyCopy returns an empty filter with the same parameters as f.
+func (f *Filter) EmptyCopy() *Filter {
+	return newFilter(f.mask+1, f.k)
+}
+
+// MarshalBinary encodes the filter as the number of hash functions, the
+// number of bits and the bit array, all big endian.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 12+8*len(f.bits))
+	binary.BigEndian.PutUint32(data, f.k)
+	binary.BigEndian.PutUint64(data[4:], f.mask+1)
+	for i := range f.bits {
+		binary.BigEndian.PutUint64(data[12+8*i:], atomic.LoadUint64(&f.bits[i]))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary into f.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return errors.New("bloom: data too short")
+	}
+	k := binary.BigEndian.Uint32(data)
+	bits := binary.BigEndian.Uint64(data[4:])
+	if k == 0 || bits < 64 || bits&(bits-1) != 0 || uint64(len(data)-12) != bits/8 {
+		return errors.New("bloom: invalid data")
+	}
+	*f = *newFilter(bits, k)
+	for i := range f.bits {
+		f.bits[i] = binary.BigEndian.Uint64(data[12+8*i:])
+	}
+	return nil
+}
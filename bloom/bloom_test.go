@@ -0,0 +1,55 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFalsePositiveRate(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add("key" + strconv.Itoa(i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.MayContain("key" + strconv.Itoa(i)) {
+			t.Fatalf("added key%d is reported absent", i)
+		}
+	}
+
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if f.MayContain("other" + strconv.Itoa(i)) {
+			positives++
+		}
+	}
+	if positives > 200 {
+		t.Fatalf("expected false positive rate around 1%%, got %d of 10000", positives)
+	}
+}
+
+func TestMergeAndMarshal(t *testing.T) {
+	a, b := New(100, 0.01), New(100, 0.01)
+	a.Add("a")
+	b.Add("b")
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !a.MayContain("a") || !a.MayContain("b") {
+		t.Fatal("merged filter should contain keys of both filters")
+	}
+	if err := a.Merge(New(10000, 0.01)); err != ErrIncompatible {
+		t.Fatalf("Merge of different sizes error = %v, want ErrIncompatible", err)
+	}
+
+	data, _ := a.MarshalBinary()
+	var c Filter
+	if err := c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !c.MayContain("a") || !c.MayContain("b") || !c.Compatible(a) {
+		t.Fatal("decoded filter differs from the original")
+	}
+	if err := c.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error for truncated data")
+	}
+}
@@ -1,8 +1,11 @@
 package geecache
 
+import "time"
+
 // A ByteView holds an immutable view of bytes.
 type ByteView struct {
 	b []byte
+	e time.Time // 过期时间，零值表示永不过期
 }
 
 // Len returns the view's length
@@ -20,6 +23,16 @@ func (v ByteView) String() string {
 	return string(v.b)
 }
 
+// Expire returns the expiration time of the view, zero means never expire.
+func (v ByteView) Expire() time.Time {
+	return v.e
+}
+
+// expired 判断在 now 时刻该值是否已经过期
+func (v ByteView) expired(now time.Time) bool {
+	return !v.e.IsZero() && now.After(v.e)
+}
+
 func cloneBytes(b []byte) []byte {
 	c := make([]byte, len(b))
 	copy(c, b)
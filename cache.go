@@ -1,37 +1,247 @@
 package geecache
 
 import (
+	"geecache/arc"
+	"geecache/lfu"
 	"geecache/lru"
+	"geecache/tinylfu"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Evictor is the eviction policy that cache depends on. Implementations
+// bound the cache by bytes and need not be safe for concurrent access.
+type Evictor interface {
+	Add(key string, value lru.Value)
+	Get(key string) (value lru.Value, ok bool)
+	Remove(key string)
+	Len() int
+	Size() int64
+}
+
+// EvictionPolicy 缓存淘汰策略类型
+type EvictionPolicy string
+
+const (
+	// EvictionLRU 最近最少使用，默认策略
+	EvictionLRU EvictionPolicy = "lru"
+	// EvictionLFU 最不经常使用
+	EvictionLFU EvictionPolicy = "lfu"
+	// EvictionARC 自适应替换缓存，兼顾访问时间和频率
+	EvictionARC EvictionPolicy = "arc"
+	// EvictionTinyLFU W-TinyLFU，使用 count-min sketch 做准入控制，抗扫描
+	EvictionTinyLFU EvictionPolicy = "tinylfu"
+)
+
+// NewEvictor 根据淘汰策略创建一个按字节限制容量的 Evictor
+func NewEvictor(policy EvictionPolicy, maxBytes int64, onEvicted func(key string, value lru.Value)) Evictor {
+	switch policy {
+	case EvictionLFU:
+		return lfu.New(maxBytes, onEvicted)
+	case EvictionARC:
+		return arc.New(maxBytes, onEvicted)
+	case EvictionTinyLFU:
+		return tinylfu.New(maxBytes, onEvicted)
+	default:
+		return lru.New(maxBytes, onEvicted)
+	}
+}
+
+const (
+	defaultCacheShards = 16
+	// 每个分片的最小字节预算，容量较小的缓存会减少分片数量，避免分片后每片过小
+	minShardBytes = 64 << 10
+)
+
+// cache 按key哈希分为多个分片，每个分片有独立的锁、淘汰策略和字节预算，
+// 不同分片上的读写互不阻塞
 type cache struct {
+	cacheBytes int64
+	shards     atomic.Pointer[[]*cacheShard]
+	// onEvicted 在数据因容量不足被淘汰时调用，不持有分片锁。
+	// 主动删除和过期清理不会触发，应在缓存使用之前设置
+	onEvicted func(key string, value ByteView)
+	// gen 是缓存当前的代数，与key共同组成有效的缓存键：
+	// 代数变化后旧代数的数据立即不可见，访问时删除或随淘汰策略移出缓存
+	gen atomic.Uint64
+}
+
+// entry 是分片中保存的值，记录写入时的代数
+type entry struct {
+	ByteView
+	gen uint64
+}
+
+type cacheShard struct {
 	mu         sync.Mutex
-	lru        *lru.Cache
+	evictor    Evictor
+	policy     EvictionPolicy
 	cacheBytes int64
-	bytes      int64 // 当前缓存使用的字节数
+	bytes      atomic.Int64 // 当前分片使用的字节数
+
+	owner    *cache
+	removing bool           // 主动删除期间淘汰策略的回调不视为容量淘汰
+	evicted  []evictedEntry // 本次写入淘汰的数据，释放锁后交给 onEvicted
 }
 
-func (c *cache) add(key string, value ByteView) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+type evictedEntry struct {
+	key   string
+	value ByteView
+}
+
+func newCache(cacheBytes int64, policy EvictionPolicy) *cache {
+	c := &cache{cacheBytes: cacheBytes}
+	c.setPolicy(policy)
+	return c
+}
+
+// shardCount 根据缓存容量计算分片数量，0 表示不限容量
+func shardCount(cacheBytes int64) int {
+	if cacheBytes == 0 || cacheBytes >= defaultCacheShards*minShardBytes {
+		return defaultCacheShards
+	}
+	if n := int(cacheBytes / minShardBytes); n > 1 {
+		return n
 	}
-	c.lru.Add(key, value)
-	c.bytes = c.lru.Size()
+	return 1
+}
+
+func (c *cache) shard(key string) *cacheShard {
+	shards := *c.shards.Load()
+	return shards[fnv32(key)%uint32(len(shards))]
+}
+
+func (c *cache) add(key string, value ByteView) {
+	c.shard(key).add(key, value)
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.lru == nil {
+	value, _, ok = c.shard(key).get(key, 0)
+	return
+}
+
+// getStale 与 get 相同，但过期不超过 grace 的数据仍然返回，并将 stale 置为 true
+func (c *cache) getStale(key string, grace time.Duration) (value ByteView, stale, ok bool) {
+	return c.shard(key).get(key, grace)
+}
+
+func (c *cache) remove(key string) {
+	c.shard(key).remove(key)
+}
+
+// size 返回所有分片使用的字节数之和
+func (c *cache) size() int64 {
+	var total int64
+	for _, s := range *c.shards.Load() {
+		total += s.bytes.Load()
+	}
+	return total
+}
+
+// setGeneration 切换缓存的代数，其他代数的数据不再可见
+func (c *cache) setGeneration(gen uint64) {
+	c.gen.Store(gen)
+}
+
+// clear 清空缓存，保留当前的淘汰策略
+func (c *cache) clear() {
+	c.setPolicy((*c.shards.Load())[0].policy)
+}
+
+// setPolicy 切换淘汰策略，已缓存的数据会被清空
+func (c *cache) setPolicy(policy EvictionPolicy) {
+	n := shardCount(c.cacheBytes)
+	shards := make([]*cacheShard, n)
+	for i := range shards {
+		shards[i] = &cacheShard{
+			policy:     policy,
+			cacheBytes: c.cacheBytes / int64(n),
+			owner:      c,
+		}
+	}
+	c.shards.Store(&shards)
+}
+
+func (s *cacheShard) add(key string, value ByteView) {
+	s.mu.Lock()
+	if s.evictor == nil {
+		s.evictor = NewEvictor(s.policy, s.cacheBytes, s.onEvicted)
+	}
+	s.evictor.Add(key, entry{ByteView: value, gen: s.owner.gen.Load()})
+	s.bytes.Store(s.evictor.Size())
+	evicted := s.evicted
+	s.evicted = nil
+	s.mu.Unlock()
+
+	for _, e := range evicted {
+		s.owner.onEvicted(e.key, e.value)
+	}
+}
+
+// onEvicted 是淘汰策略的回调，只记录因容量被淘汰的当前代数的数据
+func (s *cacheShard) onEvicted(key string, value lru.Value) {
+	e := value.(entry)
+	if s.removing || s.owner.onEvicted == nil || e.gen != s.owner.gen.Load() {
+		return
+	}
+	s.evicted = append(s.evicted, evictedEntry{key: key, value: e.ByteView})
+}
+
+// removeLocked 主动删除key，调用方需持有锁
+func (s *cacheShard) removeLocked(key string) {
+	s.removing = true
+	s.evictor.Remove(key)
+	s.removing = false
+	s.bytes.Store(s.evictor.Size())
+}
+
+func (s *cacheShard) get(key string, grace time.Duration) (value ByteView, stale, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.evictor == nil {
 		return
 	}
 
-	if v, ok := c.lru.Get(key); ok {
-		return v.(ByteView), ok
+	if v, ok := s.evictor.Get(key); ok {
+		e := v.(entry)
+		if e.gen != s.owner.gen.Load() {
+			// 旧代数的数据视为未命中
+			s.removeLocked(key)
+			return ByteView{}, false, false
+		}
+		view := e.ByteView
+		now := time.Now()
+		if view.expired(now) {
+			// 宽限期内的过期数据仍然返回，由调用方在后台刷新
+			if grace > 0 && now.Before(view.e.Add(grace)) {
+				return view, true, true
+			}
+			// 惰性清理：过期的数据视为未命中，并从缓存中移除以释放字节占用
+			s.removeLocked(key)
+			return ByteView{}, false, false
+		}
+		return view, false, ok
 	}
 
 	return
 }
+
+func (s *cacheShard) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.evictor == nil {
+		return
+	}
+	s.removeLocked(key)
+}
+
+// fnv32 计算key的 FNV-1a 哈希，不分配内存
+func fnv32(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
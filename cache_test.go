@@ -0,0 +1,124 @@
+package geecache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var policies = []EvictionPolicy{EvictionLRU, EvictionLFU, EvictionARC, EvictionTinyLFU}
+
+// zipfTrace 生成服从 Zipf 分布的访问序列，少量key占据大部分访问
+func zipfTrace(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.1, 1, 10000)
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("key-%05d", z.Uint64())
+	}
+	return trace
+}
+
+// scanTrace 生成热点访问中夹杂大量一次性顺序扫描的访问序列
+func scanTrace(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	trace := make([]string, 0, n)
+	scan := 0
+	for len(trace) < n {
+		for i := 0; i < 200 && len(trace) < n; i++ {
+			trace = append(trace, fmt.Sprintf("hot-%05d", r.Intn(100)))
+		}
+		for i := 0; i < 300 && len(trace) < n; i++ {
+			trace = append(trace, fmt.Sprintf("scan-%05d", scan))
+			scan++
+		}
+	}
+	return trace
+}
+
+// hitRatio 按照访问序列回放缓存，未命中时写入，返回命中率
+func hitRatio(policy EvictionPolicy, cacheBytes int64, trace []string) float64 {
+	c := newCache(cacheBytes, policy)
+	value := ByteView{b: make([]byte, 6)}
+	hits := 0
+	for _, key := range trace {
+		if _, ok := c.get(key); ok {
+			hits++
+			continue
+		}
+		c.add(key, value)
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	for _, policy := range policies {
+		c := newCache(64, policy)
+		for i := 0; i < 100; i++ {
+			c.add(fmt.Sprintf("key%d", i), ByteView{b: []byte("value")})
+		}
+		if c.size() > 64 {
+			t.Fatalf("%s: cache size %d exceeds limit", policy, c.size())
+		}
+
+		c = newCache(64, policy)
+		c.add("Tom", ByteView{b: []byte("630")})
+		if v, ok := c.get("Tom"); !ok || v.String() != "630" {
+			t.Fatalf("%s: failed to get Tom", policy)
+		}
+		c.remove("Tom")
+		if _, ok := c.get("Tom"); ok {
+			t.Fatalf("%s: Tom should be removed", policy)
+		}
+	}
+}
+
+// BenchmarkHitRatio 对比各淘汰策略在 Zipf 和扫描访问模式下的命中率，
+// 运行: go test -run ^$ -bench HitRatio
+func BenchmarkHitRatio(b *testing.B) {
+	traces := map[string][]string{
+		"zipf": zipfTrace(200000),
+		"scan": scanTrace(200000),
+	}
+	// 每个条目约 15 字节，容量约为 150 个条目
+	const cacheBytes = 150 * 15
+	for _, name := range []string{"zipf", "scan"} {
+		for _, policy := range policies {
+			b.Run(fmt.Sprintf("%s/%s", name, policy), func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					ratio = hitRatio(policy, cacheBytes, traces[name])
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+		}
+	}
+}
+
+// BenchmarkGroupGetParallel 并发读取已缓存的key，衡量分片缓存与无锁统计在多核下的扩展性
+func BenchmarkGroupGetParallel(b *testing.B) {
+	const keys = 1024
+	g := NewGroup("bench-parallel", 64<<20, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	for i := 0; i < keys; i++ {
+		if _, err := g.Get(fmt.Sprintf("key-%d", i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := g.Get(names[i%keys]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
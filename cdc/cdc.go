@@ -0,0 +1,158 @@
+// Package cdc 消费数据源的变更日志，按规则将变更事件映射为组和key，
+// 通过集群失效路径删除所有节点上的缓存。
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"geecache"
+	"log"
+	"strings"
+)
+
+// Event 是数据源的一条变更事件
+type Event struct {
+	Table string                 `json:"table"`
+	Op    string                 `json:"op,omitempty"`   // insert、update、delete 等
+	Data  map[string]interface{} `json:"data,omitempty"` // 变更行的列
+}
+
+// Source 是变更事件的来源
+type Source interface {
+	// Run 读取变更事件并依次交给 emit，直到 ctx 结束或出错。
+	// emit 返回错误时事件未被处理，来源应重试或告知上游重发
+	Run(ctx context.Context, emit func(Event) error) error
+}
+
+// Rule 将一张表的变更映射为一个组中的key
+type Rule struct {
+	// Table 为空表示所有表
+	Table string
+	// Ops 为空表示所有操作
+	Ops []string
+	// Group 是要删除缓存的组名
+	Group string
+	// Key 是key的模板，{列名} 替换为变更行中该列的值，如 "user:{id}"。
+	// 为空表示清空整个组
+	Key string
+}
+
+// Match 判断事件是否匹配规则，返回要删除的key
+func (r Rule) Match(ev Event) (key string, ok bool) {
+	if r.Table != "" && r.Table != ev.Table {
+		return "", false
+	}
+	if len(r.Ops) > 0 && !contains(r.Ops, ev.Op) {
+		return "", false
+	}
+	return r.render(ev.Data)
+}
+
+// render 替换模板中的列名，变更行缺少某列时不匹配
+func (r Rule) render(data map[string]interface{}) (string, bool) {
+	var b strings.Builder
+	tmpl := r.Key
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			b.WriteString(tmpl)
+			return b.String(), true
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			b.WriteString(tmpl)
+			return b.String(), true
+		}
+		value, ok := data[tmpl[start+1:start+end]]
+		if !ok || value == nil {
+			return "", false
+		}
+		b.WriteString(tmpl[:start])
+		fmt.Fprint(&b, value)
+		tmpl = tmpl[start+end+1:]
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Invalidator 删除所有节点上组中的key，keys 为空表示清空整个组
+type Invalidator interface {
+	Invalidate(ctx context.Context, group string, keys ...string) error
+}
+
+// InvalidatorFunc 用函数实现 Invalidator
+type InvalidatorFunc func(ctx context.Context, group string, keys ...string) error
+
+// Invalidate 实现 Invalidator 接口
+func (f InvalidatorFunc) Invalidate(ctx context.Context, group string, keys ...string) error {
+	return f(ctx, group, keys...)
+}
+
+// Groups 调用本进程中同名组的 Group.Invalidate，删除所有节点上的缓存
+var Groups Invalidator = InvalidatorFunc(func(ctx context.Context, group string, keys ...string) error {
+	g := geecache.GetGroup(group)
+	if g == nil {
+		return fmt.Errorf("cdc: no such group %q", group)
+	}
+	return g.Invalidate(ctx, keys...)
+})
+
+// Consumer 从来源读取变更事件，按规则删除缓存
+type Consumer struct {
+	source      Source
+	rules       []Rule
+	invalidator Invalidator
+}
+
+// NewConsumer 创建消费者，invalidator 为 nil 时使用 Groups
+func NewConsumer(source Source, rules []Rule, invalidator Invalidator) *Consumer {
+	if invalidator == nil {
+		invalidator = Groups
+	}
+	return &Consumer{source: source, rules: rules, invalidator: invalidator}
+}
+
+// Run 消费变更事件直到 ctx 结束或来源出错
+func (c *Consumer) Run(ctx context.Context) error {
+	return c.source.Run(ctx, func(ev Event) error {
+		return c.Handle(ctx, ev)
+	})
+}
+
+// Handle 处理一条变更事件，同一组的key合并为一次删除。
+// 删除失败时返回错误，来源会重新投递该事件，删除可以重复执行
+func (c *Consumer) Handle(ctx context.Context, ev Event) error {
+	keys := make(map[string][]string)
+	var groups []string
+	for _, rule := range c.rules {
+		key, ok := rule.Match(ev)
+		if !ok {
+			continue
+		}
+		prev, seen := keys[rule.Group]
+		if !seen {
+			groups = append(groups, rule.Group)
+		}
+		// 已经要清空整个组时不再需要单独的key
+		if key == "" || (seen && prev == nil) {
+			keys[rule.Group] = nil
+			continue
+		}
+		keys[rule.Group] = append(prev, key)
+	}
+
+	for _, group := range groups {
+		if err := c.invalidator.Invalidate(ctx, group, keys[group]...); err != nil {
+			log.Printf("[CDC] Failed to invalidate %s for %s event: %v", group, ev.Table, err)
+			return err
+		}
+	}
+	return nil
+}
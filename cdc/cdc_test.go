@@ -0,0 +1,146 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录每次删除，fail 次数内返回错误
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+	fail  int
+}
+
+func (r *recorder) Invalidate(ctx context.Context, group string, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("bus unavailable")
+	}
+	r.calls = append(r.calls, group+":"+strings.Join(keys, ","))
+	return nil
+}
+
+func (r *recorder) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mu.Lock()
+		calls := append([]string(nil), r.calls...)
+		r.mu.Unlock()
+		if len(calls) >= n {
+			return calls
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d invalidations, got %v", n, calls)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var testRules = []Rule{
+	{Table: "users", Group: "profiles", Key: "user:{id}"},
+	{Table: "users", Group: "avatars", Key: "{id}/{size}"},
+	{Table: "users", Ops: []string{"delete"}, Group: "stats"},
+}
+
+func TestConsumerRules(t *testing.T) {
+	var r recorder
+	c := NewConsumer(nil, testRules, &r)
+	ctx := context.Background()
+
+	c.Handle(ctx, Event{Table: "users", Op: "update", Data: map[string]interface{}{"id": 42.0}})
+	c.Handle(ctx, Event{Table: "users", Op: "delete", Data: map[string]interface{}{"id": "7", "size": 64}})
+	c.Handle(ctx, Event{Table: "orders", Op: "insert", Data: map[string]interface{}{"id": 1}})
+
+	// 缺少列的规则不匹配，没有key的规则清空整个组，其他表的事件被忽略
+	want := []string{"profiles:user:42", "profiles:user:7", "avatars:7/64", "stats:"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("got %v, want %v", r.calls, want)
+	}
+}
+
+func TestTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+	os.WriteFile(path, []byte(`{"table":"users","data":{"id":1}}`+"\n"), 0644)
+
+	r := recorder{fail: 1}
+	tailer := NewTailer(path)
+	tailer.PollInterval = 5 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- NewConsumer(tailer, testRules[:1], &r).Run(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// 只读取之后追加的事件，不完整的行等待换行符，处理失败的事件被重试
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("not json\n{\"table\":\"users\",\"data\":{\"id\":2}}\n{\"table\":\"users\",")
+	time.Sleep(20 * time.Millisecond)
+	f.WriteString("\"data\":{\"id\":3}}\n")
+	f.Close()
+	if calls := r.wait(t, 2); !reflect.DeepEqual(calls, []string{"profiles:user:2", "profiles:user:3"}) {
+		t.Fatalf("got %v", calls)
+	}
+
+	// 文件被截断后从头读取
+	os.WriteFile(path, []byte(`{"table":"users","data":{"id":4}}`+"\n"), 0644)
+	if calls := r.wait(t, 3); calls[2] != "profiles:user:4" {
+		t.Fatalf("got %v after truncation", calls)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v", err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var r recorder
+	hook := NewWebhook()
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+	post := func(body string) int {
+		res, err := http.Post(srv.URL, "application/x-ndjson", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := post(`{"table":"users","data":{"id":1}}`); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before the consumer runs, got %d", code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewConsumer(hook, testRules[:1], &r).Run(ctx)
+	time.Sleep(10 * time.Millisecond)
+
+	if code := post(`{"table":"users","data":{"id":1}}` + "\n" + `{"table":"users","data":{"id":2}}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if code := post(`{"table":`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed body, got %d", code)
+	}
+	// 处理失败时返回 5xx，由发送方重发
+	r.fail = 1
+	if code := post(`{"table":"users","data":{"id":3}}`); code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 when the invalidation fails, got %d", code)
+	}
+	if want := []string{"profiles:user:1", "profiles:user:2"}; !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("got %v, want %v", r.calls, want)
+	}
+}
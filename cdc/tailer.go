@@ -0,0 +1,139 @@
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultPollInterval = 100 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+// Tailer 从文件或命名管道读取换行分隔的 JSON 变更事件。
+// 读到普通文件末尾时等待新数据，文件被截断或轮转时从新文件的开头继续；
+// 管道的写入端关闭时 Run 返回
+type Tailer struct {
+	Path string
+	// FromStart 为 true 时从文件开头读取，否则只读取之后追加的事件
+	FromStart bool
+	// PollInterval 是检查文件新数据的间隔，0 表示使用默认值
+	PollInterval time.Duration
+}
+
+// NewTailer 创建从 path 末尾开始读取的 Tailer
+func NewTailer(path string) *Tailer {
+	return &Tailer{Path: path}
+}
+
+// Run 实现 Source 接口。emit 失败时按指数退避重试同一事件，
+// 无法解析的行记录日志后跳过
+func (t *Tailer) Run(ctx context.Context, emit func(Event) error) error {
+	poll := t.PollInterval
+	if poll <= 0 {
+		poll = defaultPollInterval
+	}
+
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	regular := info.Mode().IsRegular()
+
+	var offset int64
+	if regular && !t.FromStart {
+		if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	r := bufio.NewReader(f)
+	var line []byte
+	for {
+		chunk, err := r.ReadBytes('\n')
+		offset += int64(len(chunk))
+		line = append(line, chunk...)
+		if err == nil {
+			if err := t.emitLine(ctx, line, poll, emit); err != nil {
+				return err
+			}
+			line = line[:0]
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		if !regular {
+			// 管道的写入端已关闭，最后一行可能没有换行符
+			return t.emitLine(ctx, line, poll, emit)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+
+		// 文件被截断或被新文件替换时从头读取
+		current, err := os.Stat(t.Path)
+		if err != nil {
+			continue
+		}
+		if !os.SameFile(info, current) {
+			nf, err := os.Open(t.Path)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f, info = nf, current
+		} else if current.Size() >= offset {
+			continue
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		offset = 0
+		line = line[:0]
+		r.Reset(f)
+	}
+}
+
+// emitLine 解析一行事件并交给 emit，失败时重试直到成功或 ctx 结束
+func (t *Tailer) emitLine(ctx context.Context, line []byte, poll time.Duration, emit func(Event) error) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	var ev Event
+	if err := json.Unmarshal(line, &ev); err != nil {
+		log.Printf("[CDC] Skipping invalid event %q: %v", line, err)
+		return nil
+	}
+
+	backoff := poll
+	for {
+		err := emit(ev)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[CDC] Failed to handle %s event, retrying in %v: %v", ev.Table, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
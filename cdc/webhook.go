@@ -0,0 +1,77 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Webhook 以 HTTP 接收变更事件，请求体为一个或多个(换行分隔的) JSON 事件。
+// 所有事件处理成功后返回 204，失败时返回 5xx，由发送方重发
+type Webhook struct {
+	mu   sync.Mutex // 串行处理请求，保持事件的顺序
+	emit func(Event) error
+}
+
+// NewWebhook 创建 Webhook，需要挂载到 HTTP 服务上并由 Consumer.Run 启动
+func NewWebhook() *Webhook {
+	return &Webhook{}
+}
+
+// Run 实现 Source 接口，直到 ctx 结束前接收请求
+func (w *Webhook) Run(ctx context.Context, emit func(Event) error) error {
+	w.mu.Lock()
+	w.emit = emit
+	w.mu.Unlock()
+
+	<-ctx.Done()
+
+	w.mu.Lock()
+	w.emit = nil
+	w.mu.Unlock()
+	return ctx.Err()
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 先解析全部事件，格式错误时不处理任何事件
+	var events []Event
+	dec := json.NewDecoder(r.Body)
+	for {
+		var ev Event
+		err := dec.Decode(&ev)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			http.Error(rw, "invalid event: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		events = append(events, ev)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.emit == nil {
+		http.Error(rw, "consumer not running", http.StatusServiceUnavailable)
+		return
+	}
+	for _, ev := range events {
+		if err := w.emit(ev); err != nil {
+			log.Printf("[CDC] Failed to handle %s event from webhook: %v", ev.Table, err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
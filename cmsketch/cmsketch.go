@@ -0,0 +1,92 @@
+package cmsketch
+
+import "hash/fnv"
+
+// Sketch is a count-min sketch which estimates the frequency of keys in
+// a fixed amount of memory. It is not safe for concurrent access.
+type Sketch struct {
+	depth int
+	mask  uint64
+	rows  [][]uint32
+}
+
+// New is the Constructor of Sketch. width is rounded up to a power of two,
+// depth is the number of hash rows.
+func New(width, depth int) *Sketch {
+	if depth <= 0 {
+		depth = 4
+	}
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	rows := make([][]uint32, depth)
+	for i := range rows {
+		rows[i] = make([]uint32, w)
+	}
+	return &Sketch{
+		depth: depth,
+		mask:  uint64(w - 1),
+		rows:  rows,
+	}
+}
+
+// 使用双重哈希为每一行生成不同的下标
+func (s *Sketch) hash(key string) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum, sum>>32 | 1
+}
+
+// Increment adds one to the count of key and returns the new estimate.
+func (s *Sketch) Increment(key string) uint32 {
+	h1, h2 := s.hash(key)
+	est := ^uint32(0)
+	for i, row := range s.rows {
+		idx := (h1 + uint64(i)*h2) & s.mask
+		if row[idx] < ^uint32(0) {
+			row[idx]++
+		}
+		if row[idx] < est {
+			est = row[idx]
+		}
+	}
+	return est
+}
+
+// Estimate returns the estimated count of key, which is never lower than
+// the real count.
+func (s *Sketch) Estimate(key string) uint32 {
+	h1, h2 := s.hash(key)
+	est := ^uint32(0)
+	for i, row := range s.rows {
+		if v := row[(h1+uint64(i)*h2)&s.mask]; v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// Halve divides all counters by two, so that old accesses decay.
+func (s *Sketch) Halve() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] >>= 1
+		}
+	}
+}
+
+// Reset clears all counters.
+func (s *Sketch) Reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] = 0
+		}
+	}
+}
+
+// Width returns the number of counters in each row
+func (s *Sketch) Width() int {
+	return int(s.mask + 1)
+}
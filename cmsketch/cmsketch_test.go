@@ -0,0 +1,55 @@
+package cmsketch
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestIncrement(t *testing.T) {
+	s := New(1024, 4)
+	for i := 0; i < 10; i++ {
+		s.Increment("key1")
+	}
+	s.Increment("key2")
+
+	if v := s.Estimate("key1"); v < 10 {
+		t.Fatalf("expected estimate of key1 >= 10, got %d", v)
+	}
+	if v := s.Estimate("key2"); v < 1 || v > 10 {
+		t.Fatalf("expected estimate of key2 in [1, 10], got %d", v)
+	}
+	if v := s.Estimate("key3"); v > 1 {
+		t.Fatalf("expected estimate of key3 <= 1, got %d", v)
+	}
+}
+
+func TestHalve(t *testing.T) {
+	s := New(16, 4)
+	for i := 0; i < 8; i++ {
+		s.Increment("key1")
+	}
+	s.Halve()
+	if v := s.Estimate("key1"); v != 4 {
+		t.Fatalf("expected 4 after halve, got %d", v)
+	}
+	s.Reset()
+	if v := s.Estimate("key1"); v != 0 {
+		t.Fatalf("expected 0 after reset, got %d", v)
+	}
+}
+
+func TestAccuracy(t *testing.T) {
+	s := New(4096, 4)
+	for i := 0; i < 1000; i++ {
+		s.Increment(strconv.Itoa(i))
+	}
+	over := 0
+	for i := 0; i < 1000; i++ {
+		if s.Estimate(strconv.Itoa(i)) > 1 {
+			over++
+		}
+	}
+	if over > 50 {
+		t.Fatalf("too many overestimated keys: %d", over)
+	}
+}
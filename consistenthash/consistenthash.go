@@ -43,6 +43,47 @@ func (m *Map) Add(keys ...string) {
 	sort.Ints(m.keys)
 }
 
+// Remove removes some keys and their virtual nodes from the hash.
+func (m *Map) Remove(keys ...string) {
+	removed := false
+	for _, key := range keys {
+		for i := 0; i < m.replicas; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			// 哈希冲突时虚拟节点可能属于其他真实节点，不能删除
+			if node, ok := m.hashMap[hash]; ok && node == key {
+				delete(m.hashMap, hash)
+				removed = true
+			}
+		}
+	}
+	if !removed {
+		return
+	}
+	n := 0
+	for _, hash := range m.keys {
+		if _, ok := m.hashMap[hash]; ok {
+			m.keys[n] = hash
+			n++
+		}
+	}
+	m.keys = m.keys[:n]
+}
+
+// Clone returns a copy of the hash that can be modified independently.
+func (m *Map) Clone() *Map {
+	c := &Map{
+		hash:     m.hash,
+		replicas: m.replicas,
+		keys:     make([]int, len(m.keys)),
+		hashMap:  make(map[int]string, len(m.hashMap)),
+	}
+	copy(c.keys, m.keys)
+	for k, v := range m.hashMap {
+		c.hashMap[k] = v
+	}
+	return c
+}
+
 // Get gets the closest item in the hash to the provided key.
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
@@ -58,3 +99,95 @@ func (m *Map) Get(key string) string {
 
 	return m.hashMap[m.keys[idx%len(m.keys)]]
 }
+
+// GetN returns up to n distinct nodes for key, walking the ring clockwise
+// from the closest item. The first node is the one returned by Get.
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	// 跳过属于已选真实节点的虚拟节点，最多绕环一周
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Hash returns the position of key on the ring.
+func (m *Map) Hash(key string) uint32 {
+	return m.hash([]byte(key))
+}
+
+// owner 返回哈希值 hash 所在区间的真实节点
+func (m *Map) owner(hash int) string {
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
+
+// Move is a range of hashes whose owner changed between two rings.
+type Move struct {
+	// 哈希区间 (Start, End]，Start >= End 时区间跨越零点
+	Start, End uint32
+	From, To   string
+}
+
+// Contains reports whether hash falls in the range of the move.
+func (mv Move) Contains(hash uint32) bool {
+	if mv.Start < mv.End {
+		return hash > mv.Start && hash <= mv.End
+	}
+	return hash > mv.Start || hash <= mv.End
+}
+
+// Diff returns the ranges of hashes whose owner differs between the rings
+// from and to, in ascending order of End. Adjacent ranges moving between
+// the same pair of nodes are merged. Both rings must use the same hash.
+func Diff(from, to *Map) []Move {
+	if len(from.keys) == 0 || len(to.keys) == 0 {
+		return nil
+	}
+
+	// 两个环的虚拟节点将哈希环切分成若干区间，每个区间在两个环上的拥有者都不变
+	bounds := make([]int, 0, len(from.keys)+len(to.keys))
+	bounds = append(bounds, from.keys...)
+	bounds = append(bounds, to.keys...)
+	sort.Ints(bounds)
+	n := 0
+	for i, b := range bounds {
+		if i == 0 || b != bounds[n-1] {
+			bounds[n] = b
+			n++
+		}
+	}
+	bounds = bounds[:n]
+
+	var moves []Move
+	for i, end := range bounds {
+		start := bounds[(i+n-1)%n] // 第一个区间从最后一个边界绕过零点
+		oldOwner, newOwner := from.owner(end), to.owner(end)
+		if oldOwner == newOwner {
+			continue
+		}
+		if last := len(moves) - 1; last >= 0 && moves[last].End == uint32(start) &&
+			moves[last].From == oldOwner && moves[last].To == newOwner {
+			moves[last].End = uint32(end)
+			continue
+		}
+		moves = append(moves, Move{Start: uint32(start), End: uint32(end), From: oldOwner, To: newOwner})
+	}
+	return moves
+}
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -41,3 +42,111 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	testCases := map[string][]string{
+		"2":  {"2", "4", "6"},
+		"11": {"2", "4", "6"},
+		"23": {"4", "6", "2"},
+		"27": {"2", "4", "6"},
+	}
+	for k, v := range testCases {
+		if got := hash.GetN(k, 3); !reflect.DeepEqual(got, v) {
+			t.Errorf("GetN(%s, 3) = %v, want %v", k, got, v)
+		}
+		if got := hash.GetN(k, 1); got[0] != hash.Get(k) {
+			t.Errorf("GetN(%s, 1) = %v, want [%s]", k, got, hash.Get(k))
+		}
+	}
+
+	// 请求的数量超过真实节点数时返回所有节点
+	if got := hash.GetN("23", 5); len(got) != 3 {
+		t.Errorf("GetN(23, 5) = %v, want 3 distinct nodes", got)
+	}
+	if got := New(3, nil).GetN("key", 2); got != nil {
+		t.Errorf("GetN on empty ring = %v, want nil", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	hashFn := func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	}
+	from := New(3, hashFn)
+	from.Add("6", "4", "2")
+	to := New(3, hashFn)
+	to.Add("6", "4", "2", "8")
+
+	// 新节点 8 接管 (6,8]、(16,18]、(26,28]，这些区间原本属于节点 2
+	expect := []Move{
+		{Start: 6, End: 8, From: "2", To: "8"},
+		{Start: 16, End: 18, From: "2", To: "8"},
+		{Start: 26, End: 28, From: "2", To: "8"},
+	}
+	if moves := Diff(from, to); !reflect.DeepEqual(moves, expect) {
+		t.Fatalf("Diff() = %+v, want %+v", moves, expect)
+	}
+
+	// 移除节点 4 后，所有归属发生变化的key都应落在某个区间内
+	to = New(3, hashFn)
+	to.Add("6", "2")
+	moves := Diff(from, to)
+	for h := 0; h < 40; h++ {
+		key := strconv.Itoa(h)
+		moved := from.Get(key) != to.Get(key)
+		found := false
+		for _, mv := range moves {
+			if mv.Contains(uint32(h)) {
+				found = true
+				if mv.From != from.Get(key) || mv.To != to.Get(key) {
+					t.Errorf("hash %d: move %+v, want %s -> %s", h, mv, from.Get(key), to.Get(key))
+				}
+			}
+		}
+		if moved != found {
+			t.Errorf("hash %d: moved = %v but covered by diff = %v", h, moved, found)
+		}
+	}
+
+	if moves := Diff(New(3, hashFn), to); moves != nil {
+		t.Errorf("Diff from empty ring = %+v, want nil", moves)
+	}
+}
+
+func TestRemoveAndClone(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 8, 12, 14, 16, 18, 22, 24, 26, 28
+	hash.Add("6", "4", "2", "8")
+
+	old := hash.Clone()
+	hash.Remove("8", "unknown")
+
+	// 移除 8 后与只添加 2, 4, 6 的环相同
+	want := New(3, hash.hash)
+	want.Add("6", "4", "2")
+	if !reflect.DeepEqual(hash.keys, want.keys) || !reflect.DeepEqual(hash.hashMap, want.hashMap) {
+		t.Fatalf("after Remove keys = %v, want %v", hash.keys, want.keys)
+	}
+	if got := hash.Get("27"); got != "2" {
+		t.Errorf("Asking for 27, should have yielded 2, got %s", got)
+	}
+
+	// 克隆不受原环修改的影响
+	if got := old.Get("27"); got != "8" {
+		t.Errorf("clone: asking for 27, should have yielded 8, got %s", got)
+	}
+	if moves := Diff(old, hash); len(moves) != 3 {
+		t.Errorf("expected 3 moves, got %+v", moves)
+	}
+}
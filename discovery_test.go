@@ -0,0 +1,160 @@
+package geecache
+
+import (
+	"fmt"
+	"geecache/registry"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDiscovery 同步地把服务变化推送给订阅者
+type fakeDiscovery struct {
+	mu       sync.Mutex
+	services map[string]registry.ServiceInfo
+	subs     []func(registry.Event)
+}
+
+func newFakeDiscovery(addrs ...string) *fakeDiscovery {
+	d := &fakeDiscovery{services: make(map[string]registry.ServiceInfo)}
+	for _, addr := range addrs {
+		d.services[registry.DefaultServicePrefix+addr] = registry.ServiceInfo{Addr: addr}
+	}
+	return d
+}
+
+func (d *fakeDiscovery) GetServices() map[string]registry.ServiceInfo {
+	return d.GetServicesByPrefix("")
+}
+
+func (d *fakeDiscovery) GetService(key string) (registry.ServiceInfo, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.services[key]
+	return s, ok
+}
+
+func (d *fakeDiscovery) GetServicesByPrefix(prefix string) map[string]registry.ServiceInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	services := make(map[string]registry.ServiceInfo, len(d.services))
+	for k, v := range d.services {
+		services[k] = v
+	}
+	return services
+}
+
+func (d *fakeDiscovery) Subscribe(prefix string, fn func(registry.Event)) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for k, v := range d.services {
+		fn(registry.Event{Type: registry.EventPut, Key: k, Service: v})
+	}
+	d.subs = append(d.subs, fn)
+	return func() {}
+}
+
+func (d *fakeDiscovery) Close() error { return nil }
+
+func (d *fakeDiscovery) put(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := registry.DefaultServicePrefix + addr
+	d.services[key] = registry.ServiceInfo{Addr: addr}
+	for _, fn := range d.subs {
+		fn(registry.Event{Type: registry.EventPut, Key: key, Service: d.services[key]})
+	}
+}
+
+func (d *fakeDiscovery) delete(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := registry.DefaultServicePrefix + addr
+	service := d.services[key]
+	delete(d.services, key)
+	for _, fn := range d.subs {
+		fn(registry.Event{Type: registry.EventDelete, Key: key, Service: service})
+	}
+}
+
+// owners 返回一批key在节点池中的拥有者
+func owners(p *HTTPPool) map[string]bool {
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		if peer, ok := p.PickPeer(fmt.Sprintf("key-%d", i)); ok {
+			seen[peer.(*httpGetter).baseURL] = true
+		}
+	}
+	return seen
+}
+
+func TestHTTPPoolWithDiscoveryEvents(t *testing.T) {
+	disc := newFakeDiscovery("http://a", "http://b")
+	pool := NewHTTPPoolWithDiscovery("http://a", disc, "")
+	defer pool.Close()
+
+	getterB := pool.getters["http://b"]
+	if len(pool.getters) != 2 {
+		t.Fatalf("expected 2 peers, got %v", pool.getters)
+	}
+
+	// 新节点加入时只添加新节点，已有的 httpGetter 保持不变
+	disc.put("http://c")
+	if len(pool.getters) != 3 || pool.getters["http://b"] != getterB {
+		t.Fatalf("peer b should be kept after adding c, getters %v", pool.getters)
+	}
+	if seen := owners(pool.HTTPPool); !seen["http://c"+defaultBasePath] {
+		t.Fatalf("no keys picked peer c, owners %v", seen)
+	}
+
+	// 节点下线后不再被选中
+	disc.delete("http://b")
+	if _, ok := pool.getters["http://b"]; ok {
+		t.Fatal("peer b should be removed")
+	}
+	if seen := owners(pool.HTTPPool); seen["http://b"+defaultBasePath] {
+		t.Fatalf("removed peer b still picked, owners %v", seen)
+	}
+
+	// 定期对账补偿遗漏的事件
+	disc.mu.Lock()
+	disc.services[registry.DefaultServicePrefix+"http://d"] = registry.ServiceInfo{Addr: "http://d"}
+	disc.mu.Unlock()
+	pool.refreshPeers()
+	if _, ok := pool.getters["http://d"]; !ok || pool.getters["http://c"] == nil {
+		t.Fatalf("refresh should add peer d and keep c, getters %v", pool.getters)
+	}
+}
+
+func TestHTTPPoolWithGossipDiscovery(t *testing.T) {
+	cfg := registry.GossipConfig{BindAddr: "127.0.0.1:0", ProbeInterval: 50 * time.Millisecond}
+	a, err := registry.NewGossip(cfg)
+	if err != nil {
+		t.Fatalf("Failed to start gossip: %v", err)
+	}
+	defer a.Close()
+	cfg.Seeds = []string{a.LocalAddr()}
+	b, err := registry.NewGossip(cfg)
+	if err != nil {
+		t.Fatalf("Failed to start gossip: %v", err)
+	}
+	defer b.Close()
+
+	if err := RegisterService(a, "http://a", "", nil); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	pool := NewHTTPPoolWithDiscovery("http://a", a, "")
+	defer pool.Close()
+
+	// b 注册的服务通过 gossip 传播并加入 a 的节点池
+	if err := RegisterService(b, "http://b", "", nil); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for !owners(pool.HTTPPool)["http://b"+defaultBasePath] {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for peer b")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
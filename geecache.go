@@ -2,47 +2,101 @@ package geecache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"geecache/bloom"
 	pb "geecache/geecachepb"
+	"geecache/hotspot"
+	"geecache/invalidation"
 	"geecache/singleflight"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // A Group is a cache namespace and associated data loaded spread over
 type Group struct {
 	name      string
-	getter    Getter
-	mainCache cache
+	getter    GetterWithMetadata // 由 NewGroup 传入的 Getter 转换而来
+	mainCache *cache
 	peers     PeerPicker
 	// use singleflight.Group to make sure that
 	// each key is only fetched once
 	loader *singleflight.Group
+	// 默认过期时间，Getter 未指定过期时间时使用，0 表示永不过期
+	defaultTTL time.Duration
 
-	// 统计信息
+	// 统计信息，使用原子操作避免读请求竞争锁
 	stats struct {
-		hits   int64        // 缓存命中次数
-		misses int64        // 缓存未命中次数
-		mu     sync.RWMutex // 用于保护统计字段
+		hits     atomic.Int64 // 一级缓存命中次数
+		misses   atomic.Int64 // 一级缓存未命中次数
+		l2Hits   atomic.Int64 // 二级缓存命中次数
+		l2Misses atomic.Int64 // 二级缓存未命中次数
+		l2Spills atomic.Int64 // 写入二级缓存的次数
+
+		negativeHits  atomic.Int64 // 负缓存命中次数
+		filterRejects atomic.Int64 // 被布隆过滤器拒绝的次数
+
+		staleHits      atomic.Int64 // 返回宽限期内过期数据的次数
+		earlyRefreshes atomic.Int64 // 提前刷新的次数
+
+		leaseWaits   atomic.Int64 // 等待其他租约持有者写入的次数
+		rejectedSets atomic.Int64 // 因租约被拒绝的写入次数
+
+		invalidations atomic.Int64 // 处理的失效消息数量
+		flushes       atomic.Int64 // 清空整个组的次数
+		tagEvictions  atomic.Int64 // 因tag索引已满被删除的key数量
 	}
 
+	// 二级缓存，nil 表示未启用
+	l2 *l2Tier
+
+	// 负缓存，保存数据源中不存在的key，negativeTTL 为 0 时不启用
+	negCache    *cache
+	negativeTTL time.Duration
+	// 已知key的布隆过滤器，nil 表示不启用
+	keyFilter atomic.Pointer[bloom.Filter]
+	// 学习阶段只记录key，不拒绝请求
+	keyFilterLearning atomic.Bool
+	// 正在重建的过滤器，重建期间新增的key同时加入
+	nextKeyFilter atomic.Pointer[bloom.Filter]
+
+	// 后台刷新相关
+	staleGrace time.Duration // 过期数据的宽限期，0 表示不返回过期数据
+	earlyBeta  float64       // XFetch 提前刷新系数，0 表示不提前刷新
+	loadNanos  atomic.Int64  // 加载耗时的滑动平均
+	refreshing sync.Map      // 正在后台刷新的key
+
+	// 拥有者节点上 tag→keys 的索引，Getter 实现 GetterWithTags 时启用
+	tags *tagIndex
+
+	// 写入缓存的租约，nil 表示不启用
+	leases *leaseTable
+
+	// 组的代数，与key共同组成有效的缓存键，递增后旧数据全部失效
+	generation atomic.Uint64
+	genMu      sync.Mutex
+
+	// 集群失效消息总线，nil 表示不启用
+	bus       invalidation.Bus
+	busCancel func()
+
+	// 写入数据源，setter 为 nil 时不支持 Set，writeBehind 为 nil 时使用写穿透
+	setter      Setter
+	writeBehind *writeBehind
+
 	// 热点数据相关
-	hotSpot struct {
-		mu            sync.RWMutex    // 用于保护热点数据字段
-		accessCount   map[string]int  // 记录每个key的访问次数
-		hotKeys       map[string]bool // 热点key集合
-		threshold     int             // 热点判定阈值
-		backupCount   int             // 热点数据备份节点数量
-		lastCleanTime time.Time       // 上次清理时间
-	}
+	hotSpot     hotspot.Detector
+	backupCount atomic.Int64 // 热点数据备份节点数量
+
+	// 副本相关
+	replication int             // 每个key的副本数量，1 表示只存放在主节点
+	consistency ReadConsistency // 读取副本时的一致性级别
 }
 
 func (g *Group) IsHotSpot(key string) bool {
-	g.hotSpot.mu.RLock()
-	defer g.hotSpot.mu.RUnlock()
-	_, exists := g.hotSpot.hotKeys[key]
-	return exists
+	return g.hotSpot.IsHot(key)
 }
 
 // A Getter loads data for a key.
@@ -58,6 +112,110 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// A ContextGetter loads data for a key, honouring the deadline and
+// cancellation of ctx.
+//
+// Deprecated: implement GetterWithMetadata instead.
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// A ContextGetterFunc implements Getter and ContextGetter with a function.
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+// Get implements Getter interface function
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// GetContext implements ContextGetter interface function
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// A GetterWithTTL loads data for a key together with its expiration time.
+// A zero expiration time means the value never expires.
+//
+// Deprecated: implement GetterWithMetadata instead.
+type GetterWithTTL interface {
+	GetWithTTL(key string) ([]byte, time.Time, error)
+}
+
+// A GetterWithTTLFunc implements Getter and GetterWithTTL with a function.
+type GetterWithTTLFunc func(key string) ([]byte, time.Time, error)
+
+// Get implements Getter interface function
+func (f GetterWithTTLFunc) Get(key string) ([]byte, error) {
+	bytes, _, err := f(key)
+	return bytes, err
+}
+
+// GetWithTTL implements GetterWithTTL interface function
+func (f GetterWithTTLFunc) GetWithTTL(key string) ([]byte, time.Time, error) {
+	return f(key)
+}
+
+// Metadata describes a value loaded by a GetterWithMetadata.
+type Metadata struct {
+	// Expire is the expiration time, zero means the group's default TTL.
+	Expire time.Time
+	// Tags name the entities the value was derived from, see GetterWithTags.
+	Tags []string
+}
+
+// A GetterWithMetadata loads data for a key honouring ctx and returns its
+// expiration time and tags in a single call. It is the extension interface
+// for Getters; ContextGetter, GetterWithTTL and GetterWithTags are adapted
+// to it by NewGroup. A Getter implementing several of these interfaces is
+// used through the first one in this order: GetterWithMetadata,
+// ContextGetter, GetterWithTTL, GetterWithTags, Getter.
+type GetterWithMetadata interface {
+	GetWithMetadata(ctx context.Context, key string) ([]byte, Metadata, error)
+}
+
+// A GetterWithMetadataFunc implements Getter and GetterWithMetadata with a function.
+type GetterWithMetadataFunc func(ctx context.Context, key string) ([]byte, Metadata, error)
+
+// Get implements Getter interface function
+func (f GetterWithMetadataFunc) Get(key string) ([]byte, error) {
+	bytes, _, err := f(context.Background(), key)
+	return bytes, err
+}
+
+// GetWithMetadata implements GetterWithMetadata interface function
+func (f GetterWithMetadataFunc) GetWithMetadata(ctx context.Context, key string) ([]byte, Metadata, error) {
+	return f(ctx, key)
+}
+
+// metadataGetter 按 GetterWithMetadata 文档中的顺序将 getter 转换为 GetterWithMetadata，
+// tagged 表示加载的数据可能带有tag
+func metadataGetter(getter Getter) (mg GetterWithMetadata, tagged bool) {
+	switch getter := getter.(type) {
+	case GetterWithMetadata:
+		return getter, true
+	case ContextGetter:
+		return GetterWithMetadataFunc(func(ctx context.Context, key string) ([]byte, Metadata, error) {
+			bytes, err := getter.GetContext(ctx, key)
+			return bytes, Metadata{}, err
+		}), false
+	case GetterWithTTL:
+		return GetterWithMetadataFunc(func(ctx context.Context, key string) ([]byte, Metadata, error) {
+			bytes, expire, err := getter.GetWithTTL(key)
+			return bytes, Metadata{Expire: expire}, err
+		}), false
+	case GetterWithTags:
+		return GetterWithMetadataFunc(func(ctx context.Context, key string) ([]byte, Metadata, error) {
+			bytes, tags, err := getter.GetWithTags(key)
+			return bytes, Metadata{Tags: tags}, err
+		}), true
+	default:
+		return GetterWithMetadataFunc(func(ctx context.Context, key string) ([]byte, Metadata, error) {
+			bytes, err := getter.Get(key)
+			return bytes, Metadata{}, err
+		}), false
+	}
+}
+
 var (
 	mu     sync.RWMutex
 	groups = make(map[string]*Group) // 一个缓存节点可以有多个命名组
@@ -68,20 +226,24 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
+	mg, tagged := metadataGetter(getter)
 	mu.Lock()
 	defer mu.Unlock()
 	g := &Group{
 		name:      name,
-		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
+		getter:    mg,
+		mainCache: newCache(cacheBytes, EvictionLRU),
 		loader:    &singleflight.Group{},
+		// 默认阈值为100，默认备份节点数为2
+		hotSpot:     hotspot.NewSketchDetector(100, 0, 0),
+		replication: 1,
+		consistency: ReadOne,
+	}
+	g.backupCount.Store(2)
+	if tagged {
+		g.tags = newTagIndex(cacheBytes / tagIndexRatio)
+		g.mainCache.onEvicted = g.onEvicted
 	}
-	// 初始化热点数据相关字段
-	g.hotSpot.accessCount = make(map[string]int)
-	g.hotSpot.hotKeys = make(map[string]bool)
-	g.hotSpot.threshold = 100 // 默认阈值
-	g.hotSpot.backupCount = 2 // 默认备份节点数
-	g.hotSpot.lastCleanTime = time.Now()
 	groups[name] = g
 	return g
 }
@@ -102,116 +264,110 @@ func (g *Group) Name() string {
 
 // Stats represents cache statistics
 type Stats struct {
-	Hits   int64 // number of cache hits
-	Misses int64 // number of cache misses
-	Size   int64 // current size of cache
+	Hits     int64 // number of L1 (in-memory) cache hits
+	Misses   int64 // number of L1 (in-memory) cache misses
+	Size     int64 // current size of L1 cache
+	L2Hits   int64 // number of L2 hits among L1 misses
+	L2Misses int64 // number of L2 misses
+	L2Spills int64 // number of entries evicted from L1 and written to L2
+
+	NegativeHits  int64 // number of lookups answered by the negative cache
+	FilterRejects int64 // number of lookups rejected by the key filter
+
+	StaleHits      int64 // number of expired values served within the grace period
+	EarlyRefreshes int64 // number of probabilistic refreshes before expiry
+
+	LeaseWaits   int64 // number of misses that waited for another lease holder
+	RejectedSets int64 // number of cache fills rejected by a lease check
+
+	Invalidations int64 // number of invalidation messages applied
+	Flushes       int64 // number of times the whole group was dropped
+	TagEvictions  int64 // number of entries dropped because the tag index was full
 }
 
 // GetStats returns a copy of current statistics
 func (g *Group) GetStats() Stats {
-	g.stats.mu.RLock()
-	defer g.stats.mu.RUnlock()
 	return Stats{
-		Hits:   g.stats.hits,
-		Misses: g.stats.misses,
-		Size:   g.mainCache.bytes,
-	}
-}
+		Hits:     g.stats.hits.Load(),
+		Misses:   g.stats.misses.Load(),
+		Size:     g.mainCache.size(),
+		L2Hits:   g.stats.l2Hits.Load(),
+		L2Misses: g.stats.l2Misses.Load(),
+		L2Spills: g.stats.l2Spills.Load(),
 
-// 记录key的访问次数并检查是否为热点数据
-func (g *Group) recordAccess(key string) bool {
-	g.hotSpot.mu.Lock()
-	defer g.hotSpot.mu.Unlock()
+		NegativeHits:  g.stats.negativeHits.Load(),
+		FilterRejects: g.stats.filterRejects.Load(),
 
-	// 如果已经是热点数据，直接返回
-	if g.hotSpot.hotKeys[key] {
-		return true
-	}
+		StaleHits:      g.stats.staleHits.Load(),
+		EarlyRefreshes: g.stats.earlyRefreshes.Load(),
 
-	// 增加访问计数
-	g.hotSpot.accessCount[key]++
+		LeaseWaits:   g.stats.leaseWaits.Load(),
+		RejectedSets: g.stats.rejectedSets.Load(),
 
-	// 检查是否达到热点阈值
-	if g.hotSpot.accessCount[key] >= g.hotSpot.threshold {
-		g.hotSpot.hotKeys[key] = true
-		log.Printf("[GeeCache] Key %s becomes hot spot data", key)
-		return true
-	}
-
-	// 每隔一段时间清理过期的访问计数，使用goroutine异步执行，避免长时间持有锁
-	if time.Since(g.hotSpot.lastCleanTime) > 10*time.Minute {
-		// 更新清理时间，避免短时间内多次触发清理
-		g.hotSpot.lastCleanTime = time.Now()
-		go func() {
-			// 使用defer捕获可能的panic，确保异步操作的可靠性
-			defer func() {
-				if r := recover(); r != nil {
-					log.Printf("[GeeCache] Panic in CleanExpiredHotSpot: %v", r)
-				}
-			}()
-			g.CleanExpiredHotSpot()
-		}()
+		Invalidations: g.stats.invalidations.Load(),
+		Flushes:       g.stats.flushes.Load(),
+		TagEvictions:  g.stats.tagEvictions.Load(),
 	}
+}
 
-	return false
+// 记录key的访问次数并检查是否为热点数据
+func (g *Group) recordAccess(key string) bool {
+	return g.hotSpot.Record(key)
 }
 
-// 清理过期的热点数据和访问计数
+// 衰减热点数据的访问计数
 func (g *Group) CleanExpiredHotSpot() {
-	// 独立获取锁，避免在 recordAccess 中长时间持有锁
-	g.hotSpot.mu.Lock()
-	defer g.hotSpot.mu.Unlock()
-
-	// 更新清理时间
-	g.hotSpot.lastCleanTime = time.Now()
-
-	// 清理访问计数
-	newAccessCount := make(map[string]int)
-	for k, v := range g.hotSpot.accessCount {
-		// 保留热点数据和访问次数较高的数据
-		if g.hotSpot.hotKeys[k] || v > g.hotSpot.threshold/2 {
-			newAccessCount[k] = v / 2 // 衰减访问次数
-		}
-	}
-	g.hotSpot.accessCount = newAccessCount
+	g.hotSpot.Decay()
+}
 
-	// 重新评估热点数据
-	newHotKeys := make(map[string]bool)
-	for k, v := range g.hotSpot.accessCount {
-		if v >= g.hotSpot.threshold {
-			newHotKeys[k] = true
-		}
-	}
-	g.hotSpot.hotKeys = newHotKeys
+// HotKeys returns at most n hottest keys of the group with their estimated
+// access rates, hottest first.
+func (g *Group) HotKeys(n int) []hotspot.HotKey {
+	return g.hotSpot.HotKeys(n)
+}
 
-	log.Printf("[GeeCache] Cleaned expired hot spot data, remaining %d hot keys", len(g.hotSpot.hotKeys))
+// SetHotSpotDetector replaces the hot spot detection strategy. It should be
+// called before the group starts serving requests.
+func (g *Group) SetHotSpotDetector(d hotspot.Detector) {
+	g.hotSpot = d
 }
 
 // Get value for a key from cache
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get but the deadline and cancellation of ctx are
+// propagated to remote peers and the Getter.
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
 
-	if v, ok := g.mainCache.get(key); ok {
-		// 记录缓存命中
-		g.stats.mu.Lock()
-		g.stats.hits++
-		g.stats.mu.Unlock()
+	rp, replicated := g.replicaPicker()
+	// 多副本且要求多数或全部副本响应时，本地缓存只是其中一个副本，不能直接返回
+	if !replicated || g.consistency == ReadOne {
+		if v, ok := g.lookupCache(key); ok {
+			// 记录缓存命中
+			g.stats.hits.Add(1)
 
-		// 记录访问并检查是否为热点数据
-		g.recordAccess(key)
-
-		log.Println("[GeeCache] hit")
-		return v, nil
+			// 记录访问并检查是否为热点数据
+			g.recordAccess(key)
+			return v, nil
+		}
+	}
+	// 数据源中不存在的key直接返回
+	if g.negativeHit(key) {
+		return ByteView{}, notFoundError(key)
+	}
+	if replicated {
+		return g.getReplicated(ctx, rp, key)
 	}
 
 	// 记录缓存未命中
-	g.stats.mu.Lock()
-	g.stats.misses++
-	g.stats.mu.Unlock()
+	g.stats.misses.Add(1)
 
-	return g.load(key)
+	return g.load(ctx, key)
 }
 
 // RegisterPeers registers a PeerPicker for choosing remote peer
@@ -222,40 +378,53 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 	g.peers = peers
 }
 
-func (g *Group) load(key string) (value ByteView, err error) {
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
 	// each key is only fetched once (either locally or remotely)
 	// regardless of the number of concurrent callers.
 	// 即确保一定时间范围内对同一key的请求只执行一次
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
+	// 调用方的上下文被取消时不会把错误结果共享给其他等待者
+	viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		defer g.observeLoad(time.Now())
+		// 先查本节点的二级缓存，再查询其他节点
+		if value, ok := g.getFromL2(key); ok {
+			return value, nil
+		}
+
 		// 检查是否为热点数据
 		isHotSpot := g.recordAccess(key)
-		// TODO：添加超時上下文
-		//     ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second) // 示例：设置3秒超时
-		//     defer cancel()
 		if g.peers != nil {
 			// 如果是热点数据且有多个节点可用，使用并行查询(即同时向主数据源和备份源发起请求)
 			if isHotSpot {
-				peers, ok := g.peers.PickPeers(key, g.hotSpot.backupCount)
+				peers, ok := g.peers.PickPeers(key, g.GetBackupCount())
 				if ok && len(peers) > 0 {
 					log.Printf("[GeeCache] Fetching hot spot data %s from %d peers", key, len(peers))
-					if value, err = g.getFromPeers(peers, key); err == nil {
+					if value, err = g.getFromPeers(ctx, peers, key); err == nil {
 						return value, nil
 					}
+					// 拥有者确认数据源中不存在时不再回源
+					if errors.Is(err, ErrNotFound) {
+						g.cacheNotFound(key, err)
+						return nil, err
+					}
 					log.Println("[GeeCache] Failed to get hot spot data from peers", err)
 				}
 			} else {
 				// 非热点数据，使用单节点查询
 				if peer, ok := g.peers.PickPeer(key); ok {
-					if value, err = g.getFromPeer(peer, key); err == nil {
+					if value, err = g.getFromPeer(ctx, peer, key); err == nil {
 						return value, nil
 					}
+					if errors.Is(err, ErrNotFound) {
+						g.cacheNotFound(key, err)
+						return nil, err
+					}
 					log.Println("[GeeCache] Failed to get from peer", err)
 				}
 			}
 		}
 
 		// 从本地获取数据
-		value, err := g.getLocally(key)
+		value, err := g.loadLocally(ctx, key)
 		if err == nil && isHotSpot && g.peers != nil {
 			// 如果是热点数据，异步将数据同步到备份节点
 			go g.syncToBackupPeers(key, value)
@@ -269,51 +438,225 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	return
 }
 
+// loadLocally 在本节点加载key：哈希环刚发生变化时先从之前的拥有者读取，再回源
+func (g *Group) loadLocally(ctx context.Context, key string) (ByteView, error) {
+	// 调用方已放弃时不再回源
+	if err := ctx.Err(); err != nil {
+		return ByteView{}, err
+	}
+	if g.peers != nil {
+		if value, ok := g.getFromPreviousOwner(ctx, key); ok {
+			return value, nil
+		}
+	}
+	return g.getLocally(ctx, key)
+}
+
 func (g *Group) populateCache(key string, value ByteView) {
+	g.forgetNotFound(key)
+	g.rememberKey(key)
 	g.mainCache.add(key, value)
 
+	// 多副本时将数据写入其余副本
+	if rp, ok := g.replicaPicker(); ok {
+		g.replicate(rp, key, value)
+		return
+	}
+
 	// 检查是否为热点数据，如果是则同步到备份节点
-	g.hotSpot.mu.RLock()
-	isHotSpot := g.hotSpot.hotKeys[key]
-	g.hotSpot.mu.RUnlock()
+	isHotSpot := g.hotSpot.IsHot(key)
 
 	if isHotSpot && g.peers != nil {
 		go g.syncToBackupPeers(key, value)
 	}
 }
 
+// Remove 从集群中删除指定key：先删除本地缓存，再通知拥有该key的节点
+// 以及热点数据的备份节点删除各自的副本
+func (g *Group) Remove(key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+
+	g.removeLocally(key)
+	// 通过总线通知持有近端缓存的节点
+	if err := g.publishInvalidation(context.Background(), key); err != nil {
+		return err
+	}
+	if g.peers == nil {
+		return nil
+	}
+
+	// 收集主节点、副本和备份节点，同一节点只通知一次
+	var targets []PeerGetter
+	seen := make(map[PeerGetter]bool)
+	add := func(peers ...PeerGetter) {
+		for _, peer := range peers {
+			if peer != nil && !seen[peer] {
+				targets = append(targets, peer)
+				seen[peer] = true
+			}
+		}
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		add(peer)
+	}
+	if rp, ok := g.replicaPicker(); ok {
+		add(rp.PickReplicas(key, g.replication)...)
+	}
+	if peers, ok := g.peers.PickPeers(key, g.GetBackupCount()); ok {
+		add(peers...)
+	}
+
+	req := &pb.Request{
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
+	}
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, peer := range targets {
+		wg.Add(1)
+		go func(i int, p PeerGetter) {
+			defer wg.Done()
+			errs[i] = p.Delete(req)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			log.Printf("[GeeCache] Failed to remove %s from peer: %v", key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// setLocally 仅将其他节点推送的数据写入本节点缓存，已过期的数据直接丢弃
+func (g *Group) setLocally(key string, value ByteView) {
+	if value.expired(time.Now()) {
+		return
+	}
+	value.b = cloneBytes(value.b)
+	g.forgetNotFound(key)
+	g.rememberKey(key)
+	g.mainCache.add(key, value)
+}
+
+// removeLocally 仅删除本节点缓存中的key
+func (g *Group) removeLocally(key string) {
+	g.mainCache.remove(key)
+	g.removeFromL2(key)
+	g.forgetNotFound(key)
+	if g.tags != nil {
+		g.tags.forget(key)
+	}
+	if g.leases != nil {
+		g.leases.invalidate(key)
+	}
+}
+
+// setFromPeer 写入其他节点推送的数据，lease 为推送携带的租约，被租约拒绝时丢弃
+func (g *Group) setFromPeer(key string, value ByteView, lease uint64) bool {
+	if !g.acceptPeerSet(key, lease) {
+		return false
+	}
+	g.setLocally(key, value)
+	return true
+}
+
 // 相当于从数据库中获取数据
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
+	var (
+		// 加载期间代数发生变化时，加载的值属于旧代数，不写入缓存
+		gen = g.Generation()
+	)
+	// 写回队列中的值比数据源更新
+	if g.writeBehind != nil {
+		if pending, ok := g.writeBehind.get(key); ok {
+			value := ByteView{b: cloneBytes(pending)}
+			if g.defaultTTL > 0 {
+				value.e = time.Now().Add(g.defaultTTL)
+			}
+			g.populateCache(key, value)
+			return value, nil
+		}
+	}
+	// 回源的节点检查已知key的过滤器，其他节点不会学到拥有者上新增的key
+	if !g.keyMayExist(key) {
+		return ByteView{}, notFoundError(key)
+	}
+	// 只有获得租约的调用方回源后写入缓存，其他调用方等待其写入
+	var token uint64
+	if g.leases != nil {
+		var (
+			view ByteView
+			ok   bool
+		)
+		if token, view, ok = g.acquireLease(ctx, key); ok {
+			return view, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return ByteView{}, err
+		}
+	}
+	bytes, meta, err := g.getter.GetWithMetadata(ctx, key)
+	expire, tags := meta.Expire, meta.Tags
 	if err != nil {
+		if g.leases != nil && token != 0 {
+			// 结束租约，等待的调用方自行回源
+			g.leases.fill(key, token)
+		}
+		g.cacheNotFound(key, err)
 		return ByteView{}, err
-
 	}
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	// Getter 未指定过期时间时，使用组的默认过期时间
+	if expire.IsZero() && g.defaultTTL > 0 {
+		expire = time.Now().Add(g.defaultTTL)
+	}
+	value := ByteView{b: cloneBytes(bytes), e: expire}
+	// 租约在加载期间被删除作废时，返回加载的值但不写入缓存
+	if g.fillLease(key, token) && g.Generation() == gen {
+		g.populateCache(key, value)
+		g.tagKey(key, tags)
+	}
 	return value, nil
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+// SetEvictionPolicy 设置组的缓存淘汰策略，应在使用组之前调用，已缓存的数据会被清空
+func (g *Group) SetEvictionPolicy(policy EvictionPolicy) {
+	g.mainCache.setPolicy(policy)
+}
+
+// SetDefaultTTL 设置组的默认过期时间，0 表示永不过期
+func (g *Group) SetDefaultTTL(ttl time.Duration) {
+	g.defaultTTL = ttl
+}
+
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res) // 从远程节点获取指定值
+	err := peer.Get(ctx, req, res) // 从远程节点获取指定值
 	if err != nil {
 		return ByteView{}, err
 	}
-	return ByteView{b: res.Value}, nil
+	g.observeGeneration(res.GetGeneration())
+	return viewFromResponse(res), nil
 }
 
 // 从多个节点并行获取数据
-func (g *Group) getFromPeers(peers []PeerGetter, key string) (ByteView, error) {
+func (g *Group) getFromPeers(parent context.Context, peers []PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	resultChan := make(chan ByteView, 1)
@@ -326,14 +669,15 @@ func (g *Group) getFromPeers(peers []PeerGetter, key string) (ByteView, error) {
 		go func(p PeerGetter) {
 			defer wg.Done()
 			res := &pb.Response{}
-			err := p.Get(req, res)
+			err := p.Get(ctx, req, res)
 			if err != nil {
 				errChan <- err
 				return
 			}
+			g.observeGeneration(res.GetGeneration())
 			// 一旦有一个节点返回结果，就取消其他请求
 			select {
-			case resultChan <- ByteView{b: res.Value}:
+			case resultChan <- viewFromResponse(res):
 				cancel()
 			case <-ctx.Done():
 				// 其他goroutine已经获取到结果
@@ -350,8 +694,14 @@ func (g *Group) getFromPeers(peers []PeerGetter, key string) (ByteView, error) {
 
 	// 等待结果或超时
 	select {
-	case result := <-resultChan:
+	case result, ok := <-resultChan:
+		if !ok {
+			// 所有节点都返回了错误
+			return ByteView{}, <-errChan
+		}
 		return result, nil
+	case <-parent.Done():
+		return ByteView{}, parent.Err()
 	case <-time.After(500 * time.Millisecond):
 		// 所有节点都超时或失败
 		return ByteView{}, fmt.Errorf("timeout waiting for peers")
@@ -366,7 +716,7 @@ func (g *Group) syncToBackupPeers(key string, value ByteView) {
 	}
 
 	// 获取备份节点
-	peers, ok := g.peers.PickPeers(key, g.hotSpot.backupCount)
+	peers, ok := g.peers.PickPeers(key, g.GetBackupCount())
 	if !ok || len(peers) == 0 {
 		return
 	}
@@ -375,12 +725,11 @@ func (g *Group) syncToBackupPeers(key string, value ByteView) {
 
 	// 构建请求
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
-	}
-	res := &pb.Response{
-		Value: value.ByteSlice(),
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
 	}
+	res := responseFromView(value)
 
 	// 异步将数据同步到每个备份节点
 	for _, peer := range peers {
@@ -402,25 +751,39 @@ func (g *Group) syncToBackupPeers(key string, value ByteView) {
 	}
 }
 
-// SetHotSpotThreshold 设置热点数据判定阈值
+// viewFromResponse 将远程节点的响应转换为 ByteView，保留过期时间
+func viewFromResponse(res *pb.Response) ByteView {
+	view := ByteView{b: res.GetValue()}
+	if expire := res.GetExpire(); expire > 0 {
+		view.e = time.Unix(0, expire)
+	}
+	return view
+}
+
+// responseFromView 将 ByteView 转换为发送给远程节点的响应
+func responseFromView(view ByteView) *pb.Response {
+	res := &pb.Response{Value: view.ByteSlice()}
+	if !view.e.IsZero() {
+		res.Expire = view.e.UnixNano()
+	}
+	return res
+}
+
+// SetHotSpotThreshold 设置热点数据判定阈值，检测器不支持阈值时忽略
 func (g *Group) SetHotSpotThreshold(threshold int) {
-	g.hotSpot.mu.Lock()
-	defer g.hotSpot.mu.Unlock()
-	g.hotSpot.threshold = threshold
+	if d, ok := g.hotSpot.(interface{ SetThreshold(int) }); ok {
+		d.SetThreshold(threshold)
+	}
 }
 
 // SetBackupCount 设置热点数据备份节点数量
 func (g *Group) SetBackupCount(count int) {
-	g.hotSpot.mu.Lock()
-	defer g.hotSpot.mu.Unlock()
-	g.hotSpot.backupCount = count
+	g.backupCount.Store(int64(count))
 }
 
 // GetBackupCount 获取当前的备份节点数量
 func (g *Group) GetBackupCount() int {
-	g.hotSpot.mu.RLock()
-	defer g.hotSpot.mu.RUnlock()
-	return g.hotSpot.backupCount
+	return int(g.backupCount.Load())
 }
 
 func (g *Group) GetPeers() PeerPicker {
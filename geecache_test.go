@@ -1,10 +1,17 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
+	pb "geecache/geecachepb"
+	"geecache/hotspot"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -65,3 +72,306 @@ func TestGetGroup(t *testing.T) {
 		t.Fatalf("expect nil, but %s got", group.name)
 	}
 }
+
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	gee := NewGroup("ttl", 2<<10, GetterWithTTLFunc(
+		func(key string) ([]byte, time.Time, error) {
+			loads++
+			return []byte(key), time.Now().Add(50 * time.Millisecond), nil
+		}))
+
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" || view.Expire().IsZero() {
+		t.Fatalf("failed to get value of Tom with expiry")
+	}
+	if _, err := gee.Get("Tom"); err != nil || loads != 1 {
+		t.Fatalf("cache Tom miss before expiry")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := gee.Get("Tom"); err != nil || loads != 2 {
+		t.Fatalf("expired Tom should be reloaded, loads = %d", loads)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	loads := 0
+	gee := NewGroup("default-ttl", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+	gee.SetDefaultTTL(50 * time.Millisecond)
+
+	gee.Get("Jack")
+	time.Sleep(60 * time.Millisecond)
+	if _, err := gee.Get("Jack"); err != nil || loads != 2 {
+		t.Fatalf("expired Jack should be reloaded, loads = %d", loads)
+	}
+	if size := gee.GetStats().Size; size != int64(len("Jack")*2) {
+		t.Fatalf("expected cache size %d, got %d", len("Jack")*2, size)
+	}
+}
+
+type fakePeer struct {
+	deleted []string
+	batches int
+}
+
+func (p *fakePeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (p *fakePeer) Set(in *pb.Request, out *pb.Response) error {
+	return nil
+}
+
+func (p *fakePeer) Delete(in *pb.Request) error {
+	p.deleted = append(p.deleted, in.GetKey())
+	return nil
+}
+
+func (p *fakePeer) GetMany(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error {
+	p.batches++
+	for _, key := range in.GetKeys() {
+		item := &pb.BatchItem{Key: key, Value: []byte("remote-" + key)}
+		if key == "bad" {
+			item = &pb.BatchItem{Key: key, Error: "bad key"}
+		}
+		out.Items = append(out.Items, item)
+	}
+	return nil
+}
+
+type fakePicker struct {
+	owner   *fakePeer
+	backups []*fakePeer
+}
+
+func (p *fakePicker) PickPeer(key string) (PeerGetter, bool) {
+	return p.owner, true
+}
+
+func (p *fakePicker) PickPeers(key string, count int) ([]PeerGetter, bool) {
+	peers := []PeerGetter{p.owner}
+	for _, b := range p.backups {
+		peers = append(peers, b)
+	}
+	return peers, true
+}
+
+func TestRemove(t *testing.T) {
+	loads := 0
+	gee := NewGroup("remove", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+
+	gee.Get("Sam")
+	if err := gee.Remove("Sam"); err != nil {
+		t.Fatalf("remove Sam failed: %v", err)
+	}
+	if _, ok := gee.mainCache.get("Sam"); ok {
+		t.Fatalf("Sam should be removed from local cache")
+	}
+
+	picker := &fakePicker{owner: &fakePeer{}, backups: []*fakePeer{{}, {}}}
+	gee.RegisterPeers(picker)
+	if err := gee.Remove("Sam"); err != nil {
+		t.Fatalf("remove Sam failed: %v", err)
+	}
+	for i, p := range append([]*fakePeer{picker.owner}, picker.backups...) {
+		if !reflect.DeepEqual(p.deleted, []string{"Sam"}) {
+			t.Fatalf("peer %d expected to delete Sam once, got %v", i, p.deleted)
+		}
+	}
+}
+
+func TestServeHTTPDelete(t *testing.T) {
+	gee := NewGroup("http-delete", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	gee.Get("Tom")
+
+	pool := NewHTTPPool("http://localhost:9999")
+	req := httptest.NewRequest(http.MethodDelete, defaultBasePath+"http-delete/Tom", nil)
+	rec := httptest.NewRecorder()
+	pool.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if _, ok := gee.mainCache.get("Tom"); ok {
+		t.Fatalf("Tom should be removed by DELETE request")
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	gee := NewGroup("context", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				return nil, fmt.Errorf("deadline of %s not propagated", key)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(20 * time.Millisecond):
+				return []byte(key), nil
+			}
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if view, err := gee.GetContext(ctx, "Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("failed to get value of Tom: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "Jack"); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestHTTPGetterForwardsDeadline(t *testing.T) {
+	NewGroup("http-deadline", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok || time.Until(deadline) > time.Second {
+				return nil, fmt.Errorf("deadline of %s not forwarded", key)
+			}
+			return []byte(key), nil
+		}))
+
+	srv := httptest.NewServer(NewHTTPPool("http://localhost:9999"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	res := &pb.Response{}
+	if err := getter.Get(ctx, &pb.Request{Group: "http-deadline", Key: "Sam"}, res); err != nil {
+		t.Fatalf("remote get failed: %v", err)
+	}
+	if string(res.GetValue()) != "Sam" {
+		t.Fatalf("expected Sam, got %s", res.GetValue())
+	}
+}
+
+// ownerPicker 按key的首字母决定由远程节点还是本节点负责
+type ownerPicker struct {
+	remote *fakePeer
+}
+
+func (p *ownerPicker) PickPeer(key string) (PeerGetter, bool) {
+	if strings.HasPrefix(key, "r") || key == "bad" {
+		return p.remote, true
+	}
+	return nil, false
+}
+
+func (p *ownerPicker) PickPeers(key string, count int) ([]PeerGetter, bool) {
+	return nil, false
+}
+
+func TestGetMany(t *testing.T) {
+	gee := NewGroup("get-many", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+	picker := &ownerPicker{remote: &fakePeer{}}
+	gee.RegisterPeers(picker)
+
+	keys := []string{"Tom", "Jack", "unknown", "r1", "r2", "bad"}
+	values, errs := gee.GetMany(keys)
+
+	expect := map[string]string{"Tom": "630", "Jack": "589", "r1": "remote-r1", "r2": "remote-r2"}
+	if len(values) != len(expect) {
+		t.Fatalf("expected %d values, got %d", len(expect), len(values))
+	}
+	for k, v := range expect {
+		if values[k].String() != v {
+			t.Fatalf("expected %s=%s, got %s", k, v, values[k])
+		}
+	}
+	if errs["unknown"] == nil || errs["bad"] == nil || len(errs) != 2 {
+		t.Fatalf("expected errors for unknown and bad, got %v", errs)
+	}
+	if picker.remote.batches != 1 {
+		t.Fatalf("expected 1 batch request, got %d", picker.remote.batches)
+	}
+}
+
+func TestHTTPGetterGetMany(t *testing.T) {
+	NewGroup("http-batch", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+
+	srv := httptest.NewServer(NewHTTPPool("http://localhost:9999"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	res := &pb.BatchResponse{}
+	req := &pb.BatchRequest{Group: "http-batch", Keys: []string{"Tom", "Sam", "unknown"}}
+	if err := getter.GetMany(context.Background(), req, res); err != nil {
+		t.Fatalf("remote batch get failed: %v", err)
+	}
+	if len(res.GetItems()) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(res.GetItems()))
+	}
+	for _, item := range res.GetItems() {
+		if item.GetKey() == "unknown" {
+			if item.GetError() == "" {
+				t.Fatalf("expected error for unknown")
+			}
+		} else if string(item.GetValue()) != db[item.GetKey()] {
+			t.Fatalf("expected %s=%s, got %s", item.GetKey(), db[item.GetKey()], item.GetValue())
+		}
+	}
+}
+
+// staticDetector 将固定的key视为热点
+type staticDetector map[string]bool
+
+func (d staticDetector) Record(key string) bool { return d[key] }
+func (d staticDetector) IsHot(key string) bool  { return d[key] }
+func (d staticDetector) Decay()                 {}
+func (d staticDetector) HotKeys(n int) []hotspot.HotKey {
+	return []hotspot.HotKey{{Key: "pinned"}}
+}
+
+func TestHotKeys(t *testing.T) {
+	g := NewGroup("hotkeys", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.SetHotSpotThreshold(5)
+	for i := 0; i < 10; i++ {
+		g.Get("hot")
+	}
+	g.Get("cold")
+
+	hot := g.HotKeys(1)
+	if len(hot) != 1 || hot[0].Key != "hot" || hot[0].Count != 10 {
+		t.Fatalf("HotKeys(1) = %+v", hot)
+	}
+	if !g.IsHotSpot("hot") || g.IsHotSpot("cold") {
+		t.Fatal("unexpected hot spot state")
+	}
+
+	g.SetHotSpotDetector(staticDetector{"pinned": true})
+	if !g.IsHotSpot("pinned") || g.IsHotSpot("hot") {
+		t.Fatal("custom detector is not used")
+	}
+	if hot := g.HotKeys(10); len(hot) != 1 || hot[0].Key != "pinned" {
+		t.Fatalf("HotKeys(10) = %+v", hot)
+	}
+}
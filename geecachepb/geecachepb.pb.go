@@ -4,8 +4,12 @@
 package geecachepb
 
 import (
+	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 	math "math"
 )
 
@@ -23,6 +27,12 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Request struct {
 	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
 	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	CacheOnly            bool     `protobuf:"varint,3,opt,name=cache_only,json=cacheOnly,proto3" json:"cache_only,omitempty"`
+	Write                bool     `protobuf:"varint,4,opt,name=write,proto3" json:"write,omitempty"`
+	Lease                uint64   `protobuf:"varint,5,opt,name=lease,proto3" json:"lease,omitempty"`
+	Generation           uint64   `protobuf:"varint,6,opt,name=generation,proto3" json:"generation,omitempty"`
+	Tag                  string   `protobuf:"bytes,7,opt,name=tag,proto3" json:"tag,omitempty"`
+	WantLease            bool     `protobuf:"varint,8,opt,name=want_lease,json=wantLease,proto3" json:"want_lease,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -67,8 +77,55 @@ func (m *Request) GetKey() string {
 	return ""
 }
 
+func (m *Request) GetCacheOnly() bool {
+	if m != nil {
+		return m.CacheOnly
+	}
+	return false
+}
+
+func (m *Request) GetWrite() bool {
+	if m != nil {
+		return m.Write
+	}
+	return false
+}
+
+func (m *Request) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *Request) GetGeneration() uint64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+func (m *Request) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *Request) GetWantLease() bool {
+	if m != nil {
+		return m.WantLease
+	}
+	return false
+}
+
 type Response struct {
 	Value                []byte   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Expire               int64    `protobuf:"varint,2,opt,name=expire,proto3" json:"expire,omitempty"`
+	Miss                 bool     `protobuf:"varint,3,opt,name=miss,proto3" json:"miss,omitempty"`
+	NotFound             bool     `protobuf:"varint,4,opt,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	Lease                uint64   `protobuf:"varint,5,opt,name=lease,proto3" json:"lease,omitempty"`
+	Generation           uint64   `protobuf:"varint,6,opt,name=generation,proto3" json:"generation,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -106,23 +163,742 @@ func (m *Response) GetValue() []byte {
 	return nil
 }
 
+func (m *Response) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *Response) GetMiss() bool {
+	if m != nil {
+		return m.Miss
+	}
+	return false
+}
+
+func (m *Response) GetNotFound() bool {
+	if m != nil {
+		return m.NotFound
+	}
+	return false
+}
+
+func (m *Response) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *Response) GetGeneration() uint64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+type SetRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Expire               int64    `protobuf:"varint,4,opt,name=expire,proto3" json:"expire,omitempty"`
+	Write                bool     `protobuf:"varint,5,opt,name=write,proto3" json:"write,omitempty"`
+	Lease                uint64   `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
+	Generation           uint64   `protobuf:"varint,7,opt,name=generation,proto3" json:"generation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetRequest) Reset()         { *m = SetRequest{} }
+func (m *SetRequest) String() string { return proto.CompactTextString(m) }
+func (*SetRequest) ProtoMessage()    {}
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{2}
+}
+
+func (m *SetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetRequest.Unmarshal(m, b)
+}
+func (m *SetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetRequest.Marshal(b, m, deterministic)
+}
+func (m *SetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetRequest.Merge(m, src)
+}
+func (m *SetRequest) XXX_Size() int {
+	return xxx_messageInfo_SetRequest.Size(m)
+}
+func (m *SetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetRequest proto.InternalMessageInfo
+
+func (m *SetRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *SetRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SetRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *SetRequest) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *SetRequest) GetWrite() bool {
+	if m != nil {
+		return m.Write
+	}
+	return false
+}
+
+func (m *SetRequest) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *SetRequest) GetGeneration() uint64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+type SetResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetResponse) Reset()         { *m = SetResponse{} }
+func (m *SetResponse) String() string { return proto.CompactTextString(m) }
+func (*SetResponse) ProtoMessage()    {}
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{3}
+}
+
+func (m *SetResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetResponse.Unmarshal(m, b)
+}
+func (m *SetResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetResponse.Marshal(b, m, deterministic)
+}
+func (m *SetResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetResponse.Merge(m, src)
+}
+func (m *SetResponse) XXX_Size() int {
+	return xxx_messageInfo_SetResponse.Size(m)
+}
+func (m *SetResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetResponse proto.InternalMessageInfo
+
+type DeleteResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteResponse) Reset()         { *m = DeleteResponse{} }
+func (m *DeleteResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteResponse) ProtoMessage()    {}
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{4}
+}
+
+func (m *DeleteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteResponse.Unmarshal(m, b)
+}
+func (m *DeleteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteResponse.Merge(m, src)
+}
+func (m *DeleteResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteResponse.Size(m)
+}
+func (m *DeleteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteResponse proto.InternalMessageInfo
+
+type BatchRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Keys                 []string `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	Generation           uint64   `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchRequest) Reset()         { *m = BatchRequest{} }
+func (m *BatchRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchRequest) ProtoMessage()    {}
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{5}
+}
+
+func (m *BatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchRequest.Unmarshal(m, b)
+}
+func (m *BatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchRequest.Merge(m, src)
+}
+func (m *BatchRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchRequest.Size(m)
+}
+func (m *BatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchRequest proto.InternalMessageInfo
+
+func (m *BatchRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *BatchRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *BatchRequest) GetGeneration() uint64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+type BatchItem struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Expire               int64    `protobuf:"varint,3,opt,name=expire,proto3" json:"expire,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	NotFound             bool     `protobuf:"varint,5,opt,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchItem) Reset()         { *m = BatchItem{} }
+func (m *BatchItem) String() string { return proto.CompactTextString(m) }
+func (*BatchItem) ProtoMessage()    {}
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{6}
+}
+
+func (m *BatchItem) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchItem.Unmarshal(m, b)
+}
+func (m *BatchItem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchItem.Marshal(b, m, deterministic)
+}
+func (m *BatchItem) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchItem.Merge(m, src)
+}
+func (m *BatchItem) XXX_Size() int {
+	return xxx_messageInfo_BatchItem.Size(m)
+}
+func (m *BatchItem) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchItem.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchItem proto.InternalMessageInfo
+
+func (m *BatchItem) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *BatchItem) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *BatchItem) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *BatchItem) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *BatchItem) GetNotFound() bool {
+	if m != nil {
+		return m.NotFound
+	}
+	return false
+}
+
+type BatchResponse struct {
+	Items                []*BatchItem `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *BatchResponse) Reset()         { *m = BatchResponse{} }
+func (m *BatchResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchResponse) ProtoMessage()    {}
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{7}
+}
+
+func (m *BatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchResponse.Unmarshal(m, b)
+}
+func (m *BatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchResponse.Merge(m, src)
+}
+func (m *BatchResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchResponse.Size(m)
+}
+func (m *BatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchResponse proto.InternalMessageInfo
+
+func (m *BatchResponse) GetItems() []*BatchItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type GenerationRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GenerationRequest) Reset()         { *m = GenerationRequest{} }
+func (m *GenerationRequest) String() string { return proto.CompactTextString(m) }
+func (*GenerationRequest) ProtoMessage()    {}
+func (*GenerationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{8}
+}
+
+func (m *GenerationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GenerationRequest.Unmarshal(m, b)
+}
+func (m *GenerationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GenerationRequest.Marshal(b, m, deterministic)
+}
+func (m *GenerationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GenerationRequest.Merge(m, src)
+}
+func (m *GenerationRequest) XXX_Size() int {
+	return xxx_messageInfo_GenerationRequest.Size(m)
+}
+func (m *GenerationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GenerationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GenerationRequest proto.InternalMessageInfo
+
+func (m *GenerationRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+type GenerationResponse struct {
+	Generation           uint64   `protobuf:"varint,1,opt,name=generation,proto3" json:"generation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GenerationResponse) Reset()         { *m = GenerationResponse{} }
+func (m *GenerationResponse) String() string { return proto.CompactTextString(m) }
+func (*GenerationResponse) ProtoMessage()    {}
+func (*GenerationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_889d0a4ad37a0d42, []int{9}
+}
+
+func (m *GenerationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GenerationResponse.Unmarshal(m, b)
+}
+func (m *GenerationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GenerationResponse.Marshal(b, m, deterministic)
+}
+func (m *GenerationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GenerationResponse.Merge(m, src)
+}
+func (m *GenerationResponse) XXX_Size() int {
+	return xxx_messageInfo_GenerationResponse.Size(m)
+}
+func (m *GenerationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GenerationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GenerationResponse proto.InternalMessageInfo
+
+func (m *GenerationResponse) GetGeneration() uint64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Request)(nil), "geecachepb.Request")
 	proto.RegisterType((*Response)(nil), "geecachepb.Response")
+	proto.RegisterType((*SetRequest)(nil), "geecachepb.SetRequest")
+	proto.RegisterType((*SetResponse)(nil), "geecachepb.SetResponse")
+	proto.RegisterType((*DeleteResponse)(nil), "geecachepb.DeleteResponse")
+	proto.RegisterType((*BatchRequest)(nil), "geecachepb.BatchRequest")
+	proto.RegisterType((*BatchItem)(nil), "geecachepb.BatchItem")
+	proto.RegisterType((*BatchResponse)(nil), "geecachepb.BatchResponse")
+	proto.RegisterType((*GenerationRequest)(nil), "geecachepb.GenerationRequest")
+	proto.RegisterType((*GenerationResponse)(nil), "geecachepb.GenerationResponse")
 }
 
 func init() { proto.RegisterFile("geecachepb.proto", fileDescriptor_889d0a4ad37a0d42) }
 
 var fileDescriptor_889d0a4ad37a0d42 = []byte{
-	// 148 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x48, 0x4f, 0x4d, 0x4d,
-	0x4e, 0x4c, 0xce, 0x48, 0x2d, 0x48, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x42, 0x88,
-	0x28, 0x19, 0x72, 0xb1, 0x07, 0xa5, 0x16, 0x96, 0xa6, 0x16, 0x97, 0x08, 0x89, 0x70, 0xb1, 0xa6,
-	0x17, 0xe5, 0x97, 0x16, 0x48, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x41, 0x38, 0x42, 0x02, 0x5c,
-	0xcc, 0xd9, 0xa9, 0x95, 0x12, 0x4c, 0x60, 0x31, 0x10, 0x53, 0x49, 0x81, 0x8b, 0x23, 0x28, 0xb5,
-	0xb8, 0x20, 0x3f, 0xaf, 0x38, 0x15, 0xa4, 0xa7, 0x2c, 0x31, 0xa7, 0x34, 0x15, 0xac, 0x87, 0x27,
-	0x08, 0xc2, 0x31, 0xb2, 0xe3, 0xe2, 0x72, 0x07, 0x69, 0x76, 0x06, 0x59, 0x22, 0x64, 0xc0, 0xc5,
-	0xec, 0x9e, 0x5a, 0x22, 0x24, 0xac, 0x87, 0xe4, 0x10, 0xa8, 0x9d, 0x52, 0x22, 0xa8, 0x82, 0x10,
-	0x53, 0x93, 0xd8, 0xc0, 0xee, 0x34, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x5c, 0xd5, 0xdd, 0x09,
-	0xbb, 0x00, 0x00, 0x00,
+	// 532 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xdb, 0x6e, 0xd3, 0x40,
+	0x10, 0xd5, 0x76, 0x73, 0xf3, 0xf4, 0x42, 0x58, 0x4a, 0x59, 0x82, 0x5a, 0x45, 0x7e, 0x0a, 0x42,
+	0xaa, 0x50, 0x40, 0x48, 0x48, 0x08, 0x89, 0x82, 0x88, 0x90, 0x40, 0x95, 0xb6, 0x2f, 0xbc, 0x45,
+	0x4e, 0x3a, 0xa4, 0x56, 0x93, 0x5d, 0x63, 0xaf, 0x29, 0x7e, 0xe1, 0x57, 0xf8, 0x03, 0xfe, 0x84,
+	0x8f, 0xe1, 0x0f, 0xd0, 0xee, 0xba, 0x8e, 0x1d, 0xbb, 0x95, 0xe0, 0x6d, 0xe7, 0x8c, 0x67, 0xce,
+	0x9c, 0x33, 0x93, 0x40, 0x7f, 0x81, 0x38, 0x0f, 0xe6, 0x17, 0x18, 0xcd, 0x8e, 0xa3, 0x58, 0x69,
+	0xc5, 0x60, 0x8d, 0xf8, 0xbf, 0x09, 0x74, 0x05, 0x7e, 0x4d, 0x31, 0xd1, 0x6c, 0x1f, 0xda, 0x8b,
+	0x58, 0xa5, 0x11, 0x27, 0x43, 0x32, 0xf2, 0x84, 0x0b, 0x58, 0x1f, 0xe8, 0x25, 0x66, 0x7c, 0xcb,
+	0x62, 0xe6, 0xc9, 0x0e, 0x01, 0x6c, 0xf9, 0x54, 0xc9, 0x65, 0xc6, 0xe9, 0x90, 0x8c, 0x7a, 0xc2,
+	0xb3, 0xc8, 0xa9, 0x5c, 0x66, 0xa6, 0xcd, 0x55, 0x1c, 0x6a, 0xe4, 0x2d, 0x9b, 0x71, 0x81, 0x41,
+	0x97, 0x18, 0x24, 0xc8, 0xdb, 0x43, 0x32, 0x6a, 0x09, 0x17, 0xb0, 0x23, 0x80, 0x05, 0x4a, 0x8c,
+	0x03, 0x1d, 0x2a, 0xc9, 0x3b, 0x36, 0x55, 0x42, 0x0c, 0xb9, 0x0e, 0x16, 0xbc, 0xeb, 0xc8, 0x75,
+	0xb0, 0x30, 0xe4, 0x57, 0x81, 0xd4, 0x53, 0xd7, 0xac, 0xe7, 0xc8, 0x0d, 0xf2, 0xd1, 0x00, 0xfe,
+	0x4f, 0x02, 0x3d, 0x81, 0x49, 0xa4, 0x64, 0x62, 0x39, 0xbf, 0x05, 0xcb, 0x14, 0xad, 0xa0, 0x1d,
+	0xe1, 0x02, 0x76, 0x00, 0x1d, 0xfc, 0x1e, 0x85, 0x31, 0x5a, 0x4d, 0x54, 0xe4, 0x11, 0x63, 0xd0,
+	0x5a, 0x85, 0x49, 0x92, 0x0b, 0xb2, 0x6f, 0xf6, 0x08, 0x3c, 0xa9, 0xf4, 0xf4, 0x8b, 0x4a, 0xe5,
+	0x79, 0xae, 0xa7, 0x27, 0x95, 0x7e, 0x6f, 0xe2, 0xff, 0x93, 0xe4, 0xff, 0x22, 0x00, 0x67, 0xa8,
+	0xff, 0xd5, 0xf4, 0x42, 0x0b, 0x6d, 0xd6, 0xd2, 0xaa, 0x68, 0x29, 0x76, 0xd0, 0x6e, 0xdc, 0x41,
+	0xe7, 0xe6, 0x81, 0xbb, 0xb5, 0x81, 0x77, 0x61, 0xdb, 0xce, 0xeb, 0x4c, 0xf5, 0xfb, 0xb0, 0xf7,
+	0x0e, 0x97, 0xa8, 0xb1, 0x40, 0x3e, 0xc3, 0xce, 0x49, 0xa0, 0xe7, 0x17, 0xb7, 0x4b, 0x62, 0xd0,
+	0xba, 0xc4, 0x2c, 0xe1, 0x5b, 0x43, 0x3a, 0xf2, 0x84, 0x7d, 0x6f, 0x50, 0xd3, 0x1a, 0xf5, 0x0f,
+	0xf0, 0x6c, 0xe7, 0x0f, 0x1a, 0x57, 0xd7, 0x9e, 0x90, 0x06, 0x4f, 0xb6, 0x9a, 0x3d, 0xa1, 0x9b,
+	0x9e, 0x60, 0x1c, 0xab, 0xd8, 0x5a, 0xe5, 0x09, 0x17, 0x54, 0x37, 0xdc, 0xae, 0x6e, 0xd8, 0x7f,
+	0x05, 0xbb, 0xb9, 0xb2, 0xfc, 0xa2, 0x9e, 0x40, 0x3b, 0xd4, 0xb8, 0x4a, 0x38, 0x19, 0xd2, 0xd1,
+	0xf6, 0xf8, 0xfe, 0x71, 0xe9, 0xc7, 0x55, 0x4c, 0x2a, 0xdc, 0x37, 0xfe, 0x63, 0xb8, 0x3b, 0x29,
+	0xb4, 0xdc, 0x6a, 0x8e, 0xff, 0x1c, 0x58, 0xf9, 0xd3, 0x9c, 0xad, 0x6a, 0x0f, 0xd9, 0xb4, 0x67,
+	0xfc, 0x87, 0x00, 0x4c, 0x4c, 0xfd, 0x5b, 0x33, 0x02, 0x7b, 0x0a, 0x74, 0x82, 0x9a, 0xdd, 0x2b,
+	0x0f, 0x95, 0xd3, 0x0e, 0xf6, 0xab, 0x60, 0x4e, 0xf0, 0x02, 0xe8, 0x19, 0x6a, 0x76, 0x50, 0x4e,
+	0xae, 0x6f, 0x73, 0xf0, 0xa0, 0x86, 0xe7, 0x75, 0x2f, 0xa1, 0xe3, 0x6e, 0xa0, 0x99, 0x6c, 0x50,
+	0x06, 0xab, 0xc7, 0xc2, 0x5e, 0x43, 0x77, 0x82, 0xfa, 0x53, 0x20, 0x33, 0xc6, 0x6b, 0xee, 0x5d,
+	0x37, 0x78, 0xd8, 0x90, 0x71, 0xf5, 0xe3, 0x19, 0xdc, 0x59, 0x4b, 0x7e, 0x73, 0xbe, 0x0a, 0x25,
+	0x3b, 0x85, 0xbd, 0x93, 0x74, 0x15, 0xad, 0x0d, 0x64, 0x87, 0xe5, 0xfa, 0xda, 0x0e, 0x06, 0x47,
+	0x37, 0xa5, 0x1d, 0xc7, 0xac, 0x63, 0xff, 0x27, 0x9f, 0xfd, 0x1d, 0x00, 0x1c, 0xe4, 0xb5, 0x26,
+	0x3b, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// GroupCacheClient is the client API for GroupCache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GroupCacheClient interface {
+	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error)
+	GetMany(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+}
+
+type groupCacheClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGroupCacheClient(cc *grpc.ClientConn) GroupCacheClient {
+	return &groupCacheClient{cc}
+}
+
+func (c *groupCacheClient) Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/geecachepb.GroupCache/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, "/geecachepb.GroupCache/Set", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/geecachepb.GroupCache/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) GetMany(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, "/geecachepb.GroupCache/GetMany", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GroupCacheServer is the server API for GroupCache service.
+type GroupCacheServer interface {
+	Get(context.Context, *Request) (*Response, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Delete(context.Context, *Request) (*DeleteResponse, error)
+	GetMany(context.Context, *BatchRequest) (*BatchResponse, error)
+}
+
+// UnimplementedGroupCacheServer can be embedded to have forward compatible implementations.
+type UnimplementedGroupCacheServer struct {
+}
+
+func (*UnimplementedGroupCacheServer) Get(ctx context.Context, req *Request) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedGroupCacheServer) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (*UnimplementedGroupCacheServer) Delete(ctx context.Context, req *Request) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (*UnimplementedGroupCacheServer) GetMany(ctx context.Context, req *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMany not implemented")
+}
+
+func RegisterGroupCacheServer(s *grpc.Server, srv GroupCacheServer) {
+	s.RegisterService(&_GroupCache_serviceDesc, srv)
+}
+
+func _GroupCache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/geecachepb.GroupCache/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Get(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/geecachepb.GroupCache/Set",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/geecachepb.GroupCache/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Delete(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_GetMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).GetMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/geecachepb.GroupCache/GetMany",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).GetMany(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _GroupCache_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "geecachepb.GroupCache",
+	HandlerType: (*GroupCacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _GroupCache_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _GroupCache_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _GroupCache_Delete_Handler,
+		},
+		{
+			MethodName: "GetMany",
+			Handler:    _GroupCache_GetMany_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "geecachepb.proto",
+}
+
+// GroupCacheAdminClient is the client API for GroupCacheAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GroupCacheAdminClient interface {
+	BumpGeneration(ctx context.Context, in *GenerationRequest, opts ...grpc.CallOption) (*GenerationResponse, error)
+}
+
+type groupCacheAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGroupCacheAdminClient(cc *grpc.ClientConn) GroupCacheAdminClient {
+	return &groupCacheAdminClient{cc}
+}
+
+func (c *groupCacheAdminClient) BumpGeneration(ctx context.Context, in *GenerationRequest, opts ...grpc.CallOption) (*GenerationResponse, error) {
+	out := new(GenerationResponse)
+	err := c.cc.Invoke(ctx, "/geecachepb.GroupCacheAdmin/BumpGeneration", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GroupCacheAdminServer is the server API for GroupCacheAdmin service.
+type GroupCacheAdminServer interface {
+	BumpGeneration(context.Context, *GenerationRequest) (*GenerationResponse, error)
+}
+
+// UnimplementedGroupCacheAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedGroupCacheAdminServer struct {
+}
+
+func (*UnimplementedGroupCacheAdminServer) BumpGeneration(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BumpGeneration not implemented")
+}
+
+func RegisterGroupCacheAdminServer(s *grpc.Server, srv GroupCacheAdminServer) {
+	s.RegisterService(&_GroupCacheAdmin_serviceDesc, srv)
+}
+
+func _GroupCacheAdmin_BumpGeneration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheAdminServer).BumpGeneration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/geecachepb.GroupCacheAdmin/BumpGeneration",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheAdminServer).BumpGeneration(ctx, req.(*GenerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _GroupCacheAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "geecachepb.GroupCacheAdmin",
+	HandlerType: (*GroupCacheAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BumpGeneration",
+			Handler:    _GroupCacheAdmin_BumpGeneration_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "geecachepb.proto",
 }
@@ -0,0 +1,94 @@
+package geecache
+
+import (
+	pb "geecache/geecachepb"
+	"log"
+	"sync"
+)
+
+// PeerLister is implemented by PeerPickers that can list every remote
+// peer. BumpGeneration uses it to send the new generation to the whole
+// cluster instead of waiting for it to spread with regular requests.
+type PeerLister interface {
+	ListPeers() []PeerGetter
+}
+
+// Generation 返回组当前的代数
+func (g *Group) Generation() uint64 {
+	return g.generation.Load()
+}
+
+// BumpGeneration 递增组的代数，使整个集群中本组的所有数据失效，用于批量导入等场景。
+// 代数是有效缓存键的一部分，本节点的旧数据立即不可见，并随淘汰策略逐渐移出缓存。
+// PeerPicker 实现 PeerLister 时新的代数立即发送给所有节点，否则随之后的请求传播，
+// 代数较小的节点收到后同样使旧数据失效。返回新的代数
+func (g *Group) BumpGeneration() (uint64, error) {
+	gen := g.generation.Add(1)
+	g.applyGeneration()
+	return gen, g.broadcast(&pb.Request{
+		Group:      g.name,
+		Generation: gen,
+	})
+}
+
+// observeGeneration 处理其他节点请求或响应中的代数，大于本节点的代数时采用该代数。
+// 返回 gen 是否不小于本节点的代数，较小时对方携带的数据来自旧代数
+func (g *Group) observeGeneration(gen uint64) bool {
+	for {
+		cur := g.generation.Load()
+		if gen <= cur {
+			return gen == cur
+		}
+		if g.generation.CompareAndSwap(cur, gen) {
+			log.Printf("[GeeCache] Group %s moved to generation %d", g.name, gen)
+			g.applyGeneration()
+			return true
+		}
+	}
+}
+
+// applyGeneration 将组的代数应用到缓存，删除二级缓存中的旧数据，
+// 并作废旧代数的加载持有的租约。并发调用时串行执行，最后执行的一次读取到最新的代数
+func (g *Group) applyGeneration() {
+	g.genMu.Lock()
+	defer g.genMu.Unlock()
+	gen := g.generation.Load()
+	g.mainCache.setGeneration(gen)
+	if g.negCache != nil {
+		g.negCache.setGeneration(gen)
+	}
+	g.clearL2()
+	if g.tags != nil {
+		g.tags.clear()
+	}
+	if g.leases != nil {
+		g.leases.invalidateAll()
+	}
+}
+
+// broadcast 将 key 为空的 Delete 请求(传播代数或删除tag)发送给所有节点
+func (g *Group) broadcast(req *pb.Request) error {
+	lister, ok := g.peers.(PeerLister)
+	if !ok {
+		return nil
+	}
+	peers := lister.ListPeers()
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, p PeerGetter) {
+			defer wg.Done()
+			errs[i] = p.Delete(req)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			log.Printf("[GeeCache] Failed to broadcast to peer for group %s: %v", g.name, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// listPicker 在 fakePicker 的基础上实现 PeerLister
+type listPicker struct {
+	fakePicker
+}
+
+func (p *listPicker) ListPeers() []PeerGetter {
+	peers := []PeerGetter{p.owner}
+	for _, b := range p.backups {
+		peers = append(peers, b)
+	}
+	return peers
+}
+
+func TestBumpGeneration(t *testing.T) {
+	var loads atomic.Int64
+	g := newVersionedGroup("generation-bump", &loads, 0)
+	g.Get("k")
+
+	// 递增代数后旧数据立即不可见，重新回源
+	if gen, err := g.BumpGeneration(); err != nil || gen != 1 {
+		t.Fatalf("BumpGeneration() = %d, %v", gen, err)
+	}
+	if view, _ := g.Get("k"); view.String() != "2" {
+		t.Fatalf("Get(k) = %q after the bump, want a reload", view)
+	}
+
+	// 新的代数发送给所有节点
+	picker := &listPicker{fakePicker{owner: &fakePeer{}, backups: []*fakePeer{{}}}}
+	g.RegisterPeers(picker)
+	if _, err := g.BumpGeneration(); err != nil {
+		t.Fatal(err)
+	}
+	for _, peer := range picker.ListPeers() {
+		if deleted := peer.(*fakePeer).deleted; len(deleted) != 1 || deleted[0] != "" {
+			t.Fatalf("expected the generation to be broadcast, got deletes %q", deleted)
+		}
+	}
+}
+
+func TestGenerationPropagation(t *testing.T) {
+	var loads atomic.Int64
+	g := newVersionedGroup("generation-peers", &loads, 0)
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+	g.Get("k")
+
+	// 请求携带更大的代数时接收方采用该代数，旧数据失效
+	res := &pb.Response{}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "generation-peers", Key: "k", Generation: 3}, res); err != nil {
+		t.Fatal(err)
+	}
+	if string(res.GetValue()) != "2" || res.GetGeneration() != 3 || g.Generation() != 3 {
+		t.Fatalf("got %q at generation %d, group at %d", res.GetValue(), res.GetGeneration(), g.Generation())
+	}
+
+	// 旧代数的推送被丢弃
+	getter.Set(&pb.Request{Group: "generation-peers", Key: "pushed", Generation: 2}, &pb.Response{Value: []byte("old")})
+	if _, ok := g.mainCache.get("pushed"); ok {
+		t.Fatal("push from an older generation should be rejected")
+	}
+
+	// 节点间接口不能递增代数，不带代数的删除请求被拒绝
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+defaultBasePath+"generation-peers/", nil)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || g.Generation() != 3 {
+		t.Fatalf("peer DELETE without generation returned %d, group at generation %d", resp.StatusCode, g.Generation())
+	}
+
+	// 管理接口递增代数
+	admin := httptest.NewServer(NewHTTPPool("self").AdminHandler())
+	defer admin.Close()
+	resp, err = admin.Client().Post(admin.URL+defaultBasePath+"generation-peers/_generation", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "4\n" || g.Generation() != 4 {
+		t.Fatalf("admin bump returned %q, group at generation %d", body, g.Generation())
+	}
+	// 其他节点传播的代数只更新本节点
+	if err := getter.Delete(&pb.Request{Group: "generation-peers", Generation: 2}); err != nil || g.Generation() != 4 {
+		t.Fatalf("stale generation should be ignored, group at %d: %v", g.Generation(), err)
+	}
+}
@@ -0,0 +1,335 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// 同步/删除等非读取请求的默认超时时间
+const defaultGRPCTimeout = 3 * time.Second
+
+// GRPCPool implements PeerPicker for a pool of gRPC peers.
+type GRPCPool struct {
+	// this peer's address, e.g. "10.0.0.1:8001",
+	// peers keyed by e.g. "10.0.0.2:8008"
+	peerRing[*grpcGetter]
+
+	creds    credentials.TransportCredentials // 客户端传输凭证，支持 TLS
+	serverMu sync.Mutex                       // guards server
+	server   *grpc.Server
+}
+
+// NewGRPCPool initializes a gRPC pool of peers.
+func NewGRPCPool(self string) *GRPCPool {
+	return newGRPCPool(self, insecure.NewCredentials())
+}
+
+// NewGRPCPoolWithTLS initializes a gRPC pool of peers which verifies the
+// certificates of other peers against caFile.
+func NewGRPCPoolWithTLS(self, caFile string) *GRPCPool {
+	creds, err := credentials.NewClientTLSFromFile(caFile, "")
+	if err != nil {
+		log.Printf("[GeeCache] Failed to load CA file %s: %v", caFile, err)
+		creds = credentials.NewTLS(nil)
+	}
+	return newGRPCPool(self, creds)
+}
+
+func newGRPCPool(self string, creds credentials.TransportCredentials) *GRPCPool {
+	p := &GRPCPool{creds: creds}
+	p.peerRing = newPeerRing(self, p.newGetter, p.Log)
+	return p
+}
+
+// newGetter 为节点建立 gRPC 连接
+func (p *GRPCPool) newGetter(peer string) (*grpcGetter, error) {
+	return newGRPCGetter(peer, p.creds)
+}
+
+// Log info with server name
+func (p *GRPCPool) Log(format string, v ...interface{}) {
+	log.Printf("[gRPC Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+// ListenAndServe 监听 addr 并提供 gRPC 服务，addr 为空时使用本机地址
+func (p *GRPCPool) ListenAndServe(addr string) error {
+	return p.listenAndServe(addr)
+}
+
+// ListenAndServeTLS 监听 addr 并提供基于 TLS 的 gRPC 服务
+func (p *GRPCPool) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("load server certificate: %v", err)
+	}
+	return p.listenAndServe(addr, grpc.Creds(creds))
+}
+
+func (p *GRPCPool) listenAndServe(addr string, opts ...grpc.ServerOption) error {
+	if addr == "" {
+		addr = p.self
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return p.Serve(lis, opts...)
+}
+
+// Serve 在 lis 上提供 gRPC 服务，处理所有已注册的组
+func (p *GRPCPool) Serve(lis net.Listener, opts ...grpc.ServerOption) error {
+	server := grpc.NewServer(opts...)
+	pb.RegisterGroupCacheServer(server, &grpcServer{pool: p})
+
+	p.serverMu.Lock()
+	p.server = server
+	p.serverMu.Unlock()
+
+	p.Log("serving at %s", lis.Addr())
+	return server.Serve(lis)
+}
+
+// Stop 停止 gRPC 服务并关闭所有到其他节点的连接
+func (p *GRPCPool) Stop() {
+	p.serverMu.Lock()
+	server := p.server
+	p.server = nil
+	p.serverMu.Unlock()
+
+	// 处理中的请求可能需要 PickPeer，因此先等待其结束再关闭连接
+	if server != nil {
+		server.GracefulStop()
+	}
+	p.closeAll()
+}
+
+var (
+	_ PeerPicker    = (*GRPCPool)(nil)
+	_ ReplicaPicker = (*GRPCPool)(nil)
+	_ HandoffPicker = (*GRPCPool)(nil)
+	_ PeerLister    = (*GRPCPool)(nil)
+)
+
+// RegisterAdmin 在 server 上注册管理服务 GroupCacheAdmin。server 应与 Serve 使用的节点间服务器分开，
+// 只对运维开放并通过拦截器等方式鉴权
+func (p *GRPCPool) RegisterAdmin(server *grpc.Server) {
+	pb.RegisterGroupCacheAdminServer(server, &grpcAdminServer{pool: p})
+}
+
+// grpcAdminServer 实现 GroupCacheAdmin 服务
+type grpcAdminServer struct {
+	pb.UnimplementedGroupCacheAdminServer
+	pool *GRPCPool
+}
+
+// BumpGeneration 递增组的代数，使整个集群中组内的数据失效
+func (s *grpcAdminServer) BumpGeneration(ctx context.Context, in *pb.GenerationRequest) (*pb.GenerationResponse, error) {
+	group := GetGroup(in.GetGroup())
+	if group == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group: %s", in.GetGroup())
+	}
+	gen, err := group.BumpGeneration()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.pool.Log("Bumped group=%s to generation %d", in.GetGroup(), gen)
+	return &pb.GenerationResponse{Generation: gen}, nil
+}
+
+// grpcServer 实现 GroupCache 服务，处理其他节点发来的请求
+type grpcServer struct {
+	pb.UnimplementedGroupCacheServer
+	pool *GRPCPool
+}
+
+func (s *grpcServer) group(name string) (*Group, error) {
+	group := GetGroup(name)
+	if group == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group: %s", name)
+	}
+	return group, nil
+}
+
+// Get 从指定组中获取指定值，调用方的截止时间由 gRPC 自动传递
+func (s *grpcServer) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.observeGeneration(in.GetGeneration())
+	var res *pb.Response
+	if in.GetCacheOnly() {
+		res = group.peekLocally(in.GetKey(), in.GetWantLease())
+	} else {
+		view, err := group.GetContext(ctx, in.GetKey())
+		if errors.Is(err, ErrNotFound) {
+			res = &pb.Response{NotFound: true}
+		} else if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		} else {
+			res = responseFromView(view)
+		}
+	}
+	res.Generation = group.Generation()
+	return res, nil
+}
+
+// Set 存储其他节点推送的热点数据和副本，或处理转发给拥有者的写请求
+func (s *grpcServer) Set(ctx context.Context, in *pb.SetRequest) (*pb.SetResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	current := group.observeGeneration(in.GetGeneration())
+	if in.GetWrite() {
+		// 本节点是拥有者：写入数据源并更新缓存
+		if err := group.write(in.GetKey(), in.GetValue()); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &pb.SetResponse{}, nil
+	}
+	view := viewFromResponse(&pb.Response{
+		Value:  in.GetValue(),
+		Expire: in.GetExpire(),
+	})
+	// 旧代数的推送直接丢弃
+	if !current || !group.setFromPeer(in.GetKey(), view, in.GetLease()) {
+		s.pool.Log("Rejected stale data for group=%s, key=%s", in.GetGroup(), in.GetKey())
+		return &pb.SetResponse{}, nil
+	}
+	s.pool.Log("Stored hot spot data for group=%s, key=%s", in.GetGroup(), in.GetKey())
+	return &pb.SetResponse{}, nil
+}
+
+// Delete 仅删除本地缓存，不再向其他节点转发。key 为空时删除带有 tag 的key
+// 或传播组的代数，递增代数只能通过管理服务 GroupCacheAdmin
+func (s *grpcServer) Delete(ctx context.Context, in *pb.Request) (*pb.DeleteResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if in.GetLease() != 0 {
+		// 读取方不再使用的租约
+		group.releaseLease(in.GetKey(), in.GetLease())
+		return &pb.DeleteResponse{}, nil
+	}
+	if in.GetKey() == "" && in.GetTag() != "" {
+		group.observeGeneration(in.GetGeneration())
+		if err := group.invalidateTagLocally(in.GetTag()); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		s.pool.Log("Invalidated tag=%s for group=%s", in.GetTag(), in.GetGroup())
+		return &pb.DeleteResponse{}, nil
+	}
+	if in.GetKey() == "" {
+		if in.GetGeneration() == 0 {
+			return nil, status.Error(codes.InvalidArgument, "generation is required")
+		}
+		group.observeGeneration(in.GetGeneration())
+		return &pb.DeleteResponse{}, nil
+	}
+	group.observeGeneration(in.GetGeneration())
+	group.removeLocally(in.GetKey())
+	s.pool.Log("Removed key for group=%s, key=%s", in.GetGroup(), in.GetKey())
+	return &pb.DeleteResponse{}, nil
+}
+
+// GetMany 批量获取指定组中的多个值
+func (s *grpcServer) GetMany(ctx context.Context, in *pb.BatchRequest) (*pb.BatchResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.observeGeneration(in.GetGeneration())
+	values, errs := group.GetManyContext(ctx, in.GetKeys())
+	return batchResponse(in.GetKeys(), values, errs), nil
+}
+
+// grpcGetter 持有到某个节点的长连接，所有请求在同一连接上多路复用
+type grpcGetter struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client pb.GroupCacheClient
+}
+
+func newGRPCGetter(addr string, creds credentials.TransportCredentials) (*grpcGetter, error) {
+	// grpc.Dial 不会阻塞，连接在首次请求时建立并在断开后自动重连
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	return &grpcGetter{
+		addr:   addr,
+		conn:   conn,
+		client: pb.NewGroupCacheClient(conn),
+	}, nil
+}
+
+func (g *grpcGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	res, err := g.client.Get(ctx, in)
+	if err != nil {
+		return err
+	}
+	if res.GetNotFound() {
+		return notFoundError(in.GetKey())
+	}
+	out.Value = res.GetValue()
+	out.Expire = res.GetExpire()
+	out.Miss = res.GetMiss()
+	out.Lease = res.GetLease()
+	out.Generation = res.GetGeneration()
+	return nil
+}
+
+func (g *grpcGetter) GetMany(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error {
+	res, err := g.client.GetMany(ctx, in)
+	if err != nil {
+		return err
+	}
+	out.Items = res.GetItems()
+	return nil
+}
+
+// Set stores a value for a key in remote peer
+func (g *grpcGetter) Set(in *pb.Request, out *pb.Response) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultGRPCTimeout)
+	defer cancel()
+	_, err := g.client.Set(ctx, &pb.SetRequest{
+		Group:      in.GetGroup(),
+		Key:        in.GetKey(),
+		Value:      out.GetValue(),
+		Expire:     out.GetExpire(),
+		Write:      in.GetWrite(),
+		Lease:      in.GetLease(),
+		Generation: in.GetGeneration(),
+	})
+	return err
+}
+
+// Delete removes a key from remote peer
+func (g *grpcGetter) Delete(in *pb.Request) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultGRPCTimeout)
+	defer cancel()
+	_, err := g.client.Delete(ctx, in)
+	return err
+}
+
+func (g *grpcGetter) close() {
+	if err := g.conn.Close(); err != nil {
+		log.Printf("[GeeCache] Failed to close connection to %s: %v", g.addr, err)
+	}
+}
+
+var _ PeerGetter = (*grpcGetter)(nil)
@@ -0,0 +1,35 @@
+package geecache
+
+import "geecache/registry"
+
+// GRPCPoolWithDiscovery 实现基于服务发现的gRPC节点池
+type GRPCPoolWithDiscovery struct {
+	*GRPCPool
+	*peerDiscovery
+}
+
+// NewGRPCPoolWithDiscovery 创建一个支持服务发现的gRPC节点池
+func NewGRPCPoolWithDiscovery(self string, discovery registry.Discovery, servicePrefix string) *GRPCPoolWithDiscovery {
+	grpcPool := NewGRPCPool(self)
+	return &GRPCPoolWithDiscovery{
+		GRPCPool:      grpcPool,
+		peerDiscovery: newPeerDiscovery(discovery, servicePrefix, grpcPool),
+	}
+}
+
+// NewGRPCPoolWithDiscoveryAndTLS 创建一个支持服务发现且启用 TLS 的gRPC节点池
+// caFile: CA 根证书，用于验证其他节点的 TLS 证书
+func NewGRPCPoolWithDiscoveryAndTLS(self string, discovery registry.Discovery, servicePrefix, caFile string) *GRPCPoolWithDiscovery {
+	tlsPool := NewGRPCPoolWithTLS(self, caFile)
+	return &GRPCPoolWithDiscovery{
+		GRPCPool:      tlsPool,
+		peerDiscovery: newPeerDiscovery(discovery, servicePrefix, tlsPool),
+	}
+}
+
+// Close 停止服务发现并关闭 gRPC 服务
+func (p *GRPCPoolWithDiscovery) Close() error {
+	err := p.peerDiscovery.Close()
+	p.GRPCPool.Stop()
+	return err
+}
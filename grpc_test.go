@@ -0,0 +1,116 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCPool(t *testing.T) {
+	loads := 0
+	gee := NewGroup("grpc", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewGRPCPool(lis.Addr().String())
+	go server.Serve(lis)
+	defer server.Stop()
+
+	client := NewGRPCPool("127.0.0.1:1")
+	client.Set(lis.Addr().String())
+	defer client.Stop()
+
+	peer, ok := client.PickPeer("Tom")
+	if !ok {
+		t.Fatalf("expected to pick remote peer")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res := &pb.Response{}
+	if err := peer.Get(ctx, &pb.Request{Group: "grpc", Key: "Tom"}, res); err != nil || string(res.GetValue()) != "Tom" {
+		t.Fatalf("remote get Tom failed: %v", err)
+	}
+
+	req := &pb.Request{Group: "grpc", Key: "Jack"}
+	if err := peer.Set(req, &pb.Response{Value: []byte("589")}); err != nil {
+		t.Fatalf("remote set Jack failed: %v", err)
+	}
+	if view, ok := gee.mainCache.get("Jack"); !ok || view.String() != "589" {
+		t.Fatalf("Jack should be stored by remote set")
+	}
+
+	if err := peer.Delete(req); err != nil {
+		t.Fatalf("remote delete Jack failed: %v", err)
+	}
+	if _, ok := gee.mainCache.get("Jack"); ok {
+		t.Fatalf("Jack should be removed by remote delete")
+	}
+
+	if err := peer.Get(ctx, &pb.Request{Group: "unknown", Key: "Tom"}, res); err == nil {
+		t.Fatalf("expected error for unknown group")
+	}
+	if loads != 1 {
+		t.Fatalf("expected 1 load, got %d", loads)
+	}
+}
+
+func TestGRPCAdminBumpGeneration(t *testing.T) {
+	g := NewGroup("grpc-admin", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewGRPCPool(lis.Addr().String())
+	go pool.Serve(lis)
+	defer pool.Stop()
+
+	// 节点间服务不能递增代数
+	peer := &grpcGetter{}
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	peer.client = pb.NewGroupCacheClient(conn)
+	if err := peer.Delete(&pb.Request{Group: "grpc-admin"}); status.Code(err) != codes.InvalidArgument || g.Generation() != 0 {
+		t.Fatalf("peer Delete without generation = %v, group at generation %d", err, g.Generation())
+	}
+
+	// 管理服务注册在单独的服务器上
+	adminLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := grpc.NewServer()
+	pool.RegisterAdmin(admin)
+	go admin.Serve(adminLis)
+	defer admin.Stop()
+	adminConn, err := grpc.Dial(adminLis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer adminConn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := pb.NewGroupCacheAdminClient(adminConn).BumpGeneration(ctx, &pb.GenerationRequest{Group: "grpc-admin"})
+	if err != nil || res.GetGeneration() != 1 || g.Generation() != 1 {
+		t.Fatalf("BumpGeneration() = %v, %v; group at generation %d", res, err, g.Generation())
+	}
+}
@@ -0,0 +1,97 @@
+package geecache
+
+import (
+	"context"
+	"geecache/consistenthash"
+	pb "geecache/geecachepb"
+	"log"
+	"time"
+)
+
+// 哈希环变化后，之前的拥有者仍会被查询的默认宽限期
+const defaultHandoffGrace = 30 * time.Second
+
+// HandoffPicker is implemented by PeerPickers that remember the owners of
+// keys before the last ring change. PickPreviousOwner returns the peer that
+// owned key before the change, if the key moved within the grace period.
+type HandoffPicker interface {
+	PickPreviousOwner(key string) (PeerGetter, bool)
+}
+
+// ringHandoff 记录哈希环变化时归属发生变化的区间以及变化前的节点
+type ringHandoff struct {
+	ring   *consistenthash.Map // 新的哈希环，用于计算key的哈希值
+	moves  []consistenthash.Move
+	peers  map[string]PeerGetter
+	expire time.Time
+}
+
+// newRingHandoff 比较新旧哈希环，没有区间发生变化时返回 nil
+func newRingHandoff(from, to *consistenthash.Map, peers map[string]PeerGetter, grace time.Duration) *ringHandoff {
+	moves := consistenthash.Diff(from, to)
+	if len(moves) == 0 {
+		return nil
+	}
+	return &ringHandoff{
+		ring:   to,
+		moves:  moves,
+		peers:  peers,
+		expire: time.Now().Add(grace),
+	}
+}
+
+// previousOwner 返回key在哈希环变化前的拥有者，之前的拥有者是自己时返回 false
+func (h *ringHandoff) previousOwner(key, self string) (PeerGetter, bool) {
+	if h == nil || time.Now().After(h.expire) {
+		return nil, false
+	}
+	hash := h.ring.Hash(key)
+	for _, mv := range h.moves {
+		if !mv.Contains(hash) {
+			continue
+		}
+		if mv.From == self {
+			return nil, false
+		}
+		peer, ok := h.peers[mv.From]
+		return peer, ok
+	}
+	return nil, false
+}
+
+// getFromPreviousOwner 在哈希环变化后的宽限期内，从之前的拥有者读取归属发生变化的key，
+// 避免这些key在新的拥有者上全部未命中并同时回源
+func (g *Group) getFromPreviousOwner(ctx context.Context, key string) (ByteView, bool) {
+	hp, ok := g.peers.(HandoffPicker)
+	if !ok {
+		return ByteView{}, false
+	}
+	peer, ok := hp.PickPreviousOwner(key)
+	if !ok {
+		return ByteView{}, false
+	}
+
+	req := &pb.Request{
+		Group:      g.name,
+		Key:        key,
+		CacheOnly:  true,
+		Generation: g.Generation(),
+	}
+	res := &pb.Response{}
+	if err := peer.Get(ctx, req, res); err != nil {
+		log.Println("[GeeCache] Failed to get from previous owner", err)
+		return ByteView{}, false
+	}
+	g.observeGeneration(res.GetGeneration())
+	if res.GetMiss() {
+		return ByteView{}, false
+	}
+	view := viewFromResponse(res)
+	if view.expired(time.Now()) {
+		return ByteView{}, false
+	}
+
+	log.Printf("[GeeCache] Handed off key %s from previous owner", key)
+	g.populateCache(key, view)
+	return view, true
+}
@@ -0,0 +1,102 @@
+package geecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// handoffPicker 由本节点负责所有key，并记录之前的拥有者
+type handoffPicker struct {
+	previous PeerGetter
+}
+
+func (p *handoffPicker) PickPeer(key string) (PeerGetter, bool) {
+	return nil, false
+}
+
+func (p *handoffPicker) PickPeers(key string, count int) ([]PeerGetter, bool) {
+	return nil, false
+}
+
+func (p *handoffPicker) PickPreviousOwner(key string) (PeerGetter, bool) {
+	return p.previous, p.previous != nil
+}
+
+func TestGetFromPreviousOwner(t *testing.T) {
+	loads := 0
+	g := NewGroup("handoff", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("source-" + key), nil
+	}))
+	previous := newReplicaPeer(map[string]string{"moved": "warm"})
+	g.RegisterPeers(&handoffPicker{previous: previous})
+
+	if view, err := g.Get("moved"); err != nil || view.String() != "warm" || loads != 0 {
+		t.Fatalf("Get(moved) = %q, %v with %d loads; want value from previous owner", view, err, loads)
+	}
+	if _, ok := g.mainCache.get("moved"); !ok {
+		t.Fatal("handed off value should be cached by the new owner")
+	}
+	// 之前的拥有者也未缓存时回源
+	if view, err := g.Get("cold"); err != nil || view.String() != "source-cold" || loads != 1 {
+		t.Fatalf("Get(cold) = %q, %v with %d loads", view, err, loads)
+	}
+}
+
+func TestGetManyFromPreviousOwner(t *testing.T) {
+	loads := 0
+	g := NewGroup("handoff-get-many", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("source-" + key), nil
+	}))
+	previous := newReplicaPeer(map[string]string{"moved": "warm"})
+	g.RegisterPeers(&handoffPicker{previous: previous})
+
+	// 批量读取本节点负责的key时，与 Get 一样先从之前的拥有者读取
+	values, errs := g.GetMany([]string{"moved", "cold"})
+	if len(errs) != 0 || values["moved"].String() != "warm" || values["cold"].String() != "source-cold" {
+		t.Fatalf("GetMany = %v, %v", values, errs)
+	}
+	if loads != 1 {
+		t.Fatalf("expected only the cold key to be loaded, got %d loads", loads)
+	}
+}
+
+func TestHTTPPoolPickPreviousOwner(t *testing.T) {
+	pool := NewHTTPPool("http://a")
+	pool.Set("http://a", "http://b")
+
+	// 节点 b 下线后，原本属于 b 的key归本节点所有
+	var keys []string
+	stayed := ""
+	for i := 0; len(keys) < 5 || stayed == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if _, ok := pool.PickPeer(key); ok {
+			keys = append(keys, key)
+		} else {
+			stayed = key
+		}
+	}
+	previous := pool.getters["http://b"]
+	pool.Set("http://a")
+
+	for _, key := range keys {
+		if peer, ok := pool.PickPreviousOwner(key); !ok || peer != previous {
+			t.Fatalf("PickPreviousOwner(%s) = %v, %v; want the departed peer", key, peer, ok)
+		}
+	}
+	if _, ok := pool.PickPreviousOwner(stayed); ok {
+		t.Fatalf("key %s did not move but has a previous owner", stayed)
+	}
+
+	// 节点列表未变化时保留之前的记录，宽限期结束后不再查询
+	pool.Set("http://a")
+	if _, ok := pool.PickPreviousOwner(keys[0]); !ok {
+		t.Fatal("refreshing an unchanged ring should keep the handoff")
+	}
+	pool.handoff.expire = time.Now().Add(-time.Second)
+	if _, ok := pool.PickPreviousOwner(keys[0]); ok {
+		t.Fatal("previous owner should not be used after the grace period")
+	}
+}
@@ -0,0 +1,169 @@
+// Package hotspot detects frequently accessed keys in bounded memory.
+package hotspot
+
+import (
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTopK     = 128
+	defaultInterval = 10 * time.Minute // 访问计数的衰减间隔
+	sketchWidth     = 4096
+	sketchDepth     = 4
+)
+
+// HotKey is a frequently accessed key together with its estimated access
+// rate.
+type HotKey struct {
+	Key   string
+	Count uint64  // 衰减后的估计访问次数
+	Rate  float64 // 估计的每秒访问次数
+}
+
+// Detector decides which keys are hot. Implementations must be safe for
+// concurrent use, Record is called on every cache read.
+type Detector interface {
+	// Record records an access to key and reports whether key is hot.
+	Record(key string) bool
+	// IsHot reports whether key is hot without recording an access.
+	IsHot(key string) bool
+	// HotKeys returns at most n hottest keys, hottest first.
+	HotKeys(n int) []HotKey
+	// Decay ages the access statistics so that old accesses count less.
+	Decay()
+}
+
+// SketchDetector counts accesses with a decaying count-min sketch and keeps
+// the heaviest hitters in a space-saving top-K. Its memory use does not
+// depend on the number of distinct keys.
+type SketchDetector struct {
+	sketch   *sketch
+	top      *topK
+	interval time.Duration
+
+	threshold atomic.Uint32
+	start     time.Time
+	lastDecay atomic.Int64 // 上次衰减时间(Unix纳秒)
+	decayed   atomic.Bool  // 是否衰减过，用于估算访问速率的时间窗口
+}
+
+// NewSketchDetector creates a SketchDetector. A key is hot once its decayed
+// access count reaches threshold. k is the number of heavy hitters tracked
+// for HotKeys and interval is how often counts are halved; zero values use
+// the defaults.
+func NewSketchDetector(threshold, k int, interval time.Duration) *SketchDetector {
+	if k <= 0 {
+		k = defaultTopK
+	}
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	d := &SketchDetector{
+		sketch:   newSketch(sketchWidth, sketchDepth),
+		top:      newTopK(k),
+		interval: interval,
+		start:    time.Now(),
+	}
+	d.SetThreshold(threshold)
+	d.lastDecay.Store(d.start.UnixNano())
+	return d
+}
+
+// SetThreshold sets the decayed access count at which a key becomes hot.
+func (d *SketchDetector) SetThreshold(threshold int) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	d.threshold.Store(uint32(threshold))
+}
+
+// Record implements Detector.
+func (d *SketchDetector) Record(key string) bool {
+	d.maybeDecay()
+
+	est := d.sketch.increment(key)
+	// 计数不超过 top-K 最小值的key无需加锁
+	if est > d.top.floor.Load() {
+		d.top.offer(key, est)
+	}
+
+	threshold := d.threshold.Load()
+	if est == threshold {
+		log.Printf("[GeeCache] Key %s becomes hot spot data", key)
+	}
+	return est >= threshold
+}
+
+// IsHot implements Detector.
+func (d *SketchDetector) IsHot(key string) bool {
+	return d.sketch.estimate(key) >= d.threshold.Load()
+}
+
+// HotKeys implements Detector.
+func (d *SketchDetector) HotKeys(n int) []HotKey {
+	items := d.top.snapshot()
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return items[i].key < items[j].key
+	})
+	if n >= 0 && len(items) > n {
+		items = items[:n]
+	}
+
+	window := d.window().Seconds()
+	hot := make([]HotKey, len(items))
+	for i, it := range items {
+		hot[i] = HotKey{
+			Key:   it.key,
+			Count: uint64(it.count),
+			Rate:  float64(it.count) / window,
+		}
+	}
+	return hot
+}
+
+// window 返回估算访问速率的时间窗口。每个间隔计数减半，稳定速率 r 下
+// 衰减后 t 时刻的计数约为 r*(interval+t)
+func (d *SketchDetector) window() time.Duration {
+	w := time.Since(d.start)
+	if d.decayed.Load() {
+		w = d.interval + time.Since(time.Unix(0, d.lastDecay.Load()))
+	}
+	if w < time.Second {
+		w = time.Second
+	}
+	return w
+}
+
+// Decay implements Detector.
+func (d *SketchDetector) Decay() {
+	d.lastDecay.Store(time.Now().UnixNano())
+	d.decayed.Store(true)
+	d.sketch.halve()
+	d.top.halve()
+}
+
+// maybeDecay 每隔一段时间异步衰减访问计数，只有一个调用方能触发
+func (d *SketchDetector) maybeDecay() {
+	last := d.lastDecay.Load()
+	if time.Since(time.Unix(0, last)) <= d.interval ||
+		!d.lastDecay.CompareAndSwap(last, time.Now().UnixNano()) {
+		return
+	}
+	go func() {
+		// 使用defer捕获可能的panic，确保异步操作的可靠性
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[GeeCache] Panic in hot spot decay: %v", r)
+			}
+		}()
+		d.Decay()
+	}()
+}
+
+var _ Detector = (*SketchDetector)(nil)
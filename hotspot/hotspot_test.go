@@ -0,0 +1,93 @@
+package hotspot
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRecordThreshold(t *testing.T) {
+	d := NewSketchDetector(3, 0, 0)
+	for i := 1; i <= 3; i++ {
+		hot := d.Record("key")
+		if hot != (i == 3) {
+			t.Fatalf("access %d: hot = %v", i, hot)
+		}
+	}
+	if !d.IsHot("key") || d.IsHot("other") {
+		t.Fatal("IsHot does not match recorded accesses")
+	}
+
+	d.SetThreshold(10)
+	if d.IsHot("key") {
+		t.Fatal("key should not be hot after raising threshold")
+	}
+}
+
+func TestHotKeys(t *testing.T) {
+	d := NewSketchDetector(100, 8, 0)
+	// 少量热点key夹杂在大量只访问一次的key之中
+	for i := 0; i < 20000; i++ {
+		d.Record(fmt.Sprintf("cold-%d", i))
+		if i%10 == 0 {
+			d.Record("hot-a")
+		}
+		if i%20 == 0 {
+			d.Record("hot-b")
+		}
+	}
+
+	hot := d.HotKeys(2)
+	if len(hot) != 2 || hot[0].Key != "hot-a" || hot[1].Key != "hot-b" {
+		t.Fatalf("HotKeys(2) = %+v", hot)
+	}
+	if hot[0].Count < 2000 || hot[0].Rate <= 0 {
+		t.Fatalf("unexpected estimate for hot-a: %+v", hot[0])
+	}
+	if n := len(d.HotKeys(100)); n > 8 {
+		t.Fatalf("top-K holds %d keys, want at most 8", n)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	d := NewSketchDetector(4, 0, 0)
+	for i := 0; i < 6; i++ {
+		d.Record("key")
+	}
+	d.Decay()
+	if d.IsHot("key") {
+		t.Fatal("key should not be hot after decay")
+	}
+	if hot := d.HotKeys(1); len(hot) != 1 || hot[0].Count != 3 {
+		t.Fatalf("HotKeys(1) after decay = %+v", hot)
+	}
+
+	d.Decay()
+	d.Decay()
+	if hot := d.HotKeys(1); len(hot) != 0 {
+		t.Fatalf("expected decayed keys to be dropped, got %+v", hot)
+	}
+}
+
+func TestConcurrentRecord(t *testing.T) {
+	d := NewSketchDetector(1000, 16, 0)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				d.Record("shared")
+				d.Record(fmt.Sprintf("key-%d-%d", w, i))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if !d.IsHot("shared") {
+		t.Fatal("shared key should be hot")
+	}
+	if hot := d.HotKeys(1); hot[0].Key != "shared" || hot[0].Count < 8000 {
+		t.Fatalf("HotKeys(1) = %+v", hot)
+	}
+}
@@ -0,0 +1,75 @@
+package hotspot
+
+import "sync/atomic"
+
+// sketch 是可并发访问的 count-min sketch，计数器使用原子操作，
+// 记录访问时不需要加锁
+type sketch struct {
+	depth int
+	mask  uint64
+	cells []atomic.Uint32 // depth 行计数器连续存放
+}
+
+func newSketch(width, depth int) *sketch {
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	return &sketch{
+		depth: depth,
+		mask:  uint64(w - 1),
+		cells: make([]atomic.Uint32, w*depth),
+	}
+}
+
+// index 使用双重哈希计算第 i 行的下标
+func (s *sketch) index(h1, h2 uint64, i int) int {
+	return i*int(s.mask+1) + int((h1+uint64(i)*h2)&s.mask)
+}
+
+// increment 将key的计数加一并返回新的估计值
+func (s *sketch) increment(key string) uint32 {
+	h1, h2 := hash(key)
+	est := ^uint32(0)
+	for i := 0; i < s.depth; i++ {
+		if v := s.cells[s.index(h1, h2, i)].Add(1); v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// estimate 返回key的估计计数，不低于真实计数
+func (s *sketch) estimate(key string) uint32 {
+	h1, h2 := hash(key)
+	est := ^uint32(0)
+	for i := 0; i < s.depth; i++ {
+		if v := s.cells[s.index(h1, h2, i)].Load(); v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// halve 将所有计数减半，使历史访问逐渐衰减
+func (s *sketch) halve() {
+	for i := range s.cells {
+		c := &s.cells[i]
+		for {
+			v := c.Load()
+			if c.CompareAndSwap(v, v>>1) {
+				break
+			}
+		}
+	}
+}
+
+// hash 计算key的 FNV-1a 哈希，不分配内存
+func hash(key string) (h1, h2 uint64) {
+	sum := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		sum ^= uint64(key[i])
+		sum *= 1099511628211
+	}
+	return sum, sum>>32 | 1
+}
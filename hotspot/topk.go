@@ -0,0 +1,123 @@
+package hotspot
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+)
+
+// topK 使用 space-saving 算法在固定空间内维护访问最频繁的 k 个key：
+// 容量已满时，新出现的key替换计数最小的条目，并将其计数记为误差。
+// 计数取自 sketch 的估计值，因此同一个key多次提交时取较大者。
+type topK struct {
+	mu    sync.Mutex
+	k     int
+	items map[string]*item
+	heap  itemHeap
+
+	// 已满时为最小计数，否则为0。计数不超过 floor 的key不可能进入 top-K，
+	// 记录访问时可以无锁地跳过
+	floor atomic.Uint32
+}
+
+type item struct {
+	key   string
+	count uint32
+	err   uint32 // 被替换条目的计数，count-err 是真实计数的下界
+	index int
+}
+
+func newTopK(k int) *topK {
+	return &topK{
+		k:     k,
+		items: make(map[string]*item, k),
+		heap:  make(itemHeap, 0, k),
+	}
+}
+
+// offer 提交key的最新计数
+func (t *topK) offer(key string, count uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if it, ok := t.items[key]; ok {
+		if count > it.count {
+			it.count = count
+			heap.Fix(&t.heap, it.index)
+		}
+	} else if len(t.heap) < t.k {
+		it := &item{key: key, count: count}
+		t.items[key] = it
+		heap.Push(&t.heap, it)
+	} else if min := t.heap[0]; count > min.count {
+		delete(t.items, min.key)
+		min.key, min.err, min.count = key, min.count, count
+		t.items[key] = min
+		heap.Fix(&t.heap, 0)
+	}
+	t.updateFloor()
+}
+
+// halve 将所有计数减半，计数归零的条目被移除
+func (t *topK) halve() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kept := t.heap[:0]
+	for _, it := range t.heap {
+		it.count >>= 1
+		it.err >>= 1
+		if it.count == 0 {
+			delete(t.items, it.key)
+			continue
+		}
+		it.index = len(kept)
+		kept = append(kept, it)
+	}
+	t.heap = kept
+	heap.Init(&t.heap)
+	t.updateFloor()
+}
+
+// snapshot 返回所有条目的副本
+func (t *topK) snapshot() []item {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	items := make([]item, 0, len(t.heap))
+	for _, it := range t.heap {
+		items = append(items, *it)
+	}
+	return items
+}
+
+func (t *topK) updateFloor() {
+	if len(t.heap) < t.k {
+		t.floor.Store(0)
+		return
+	}
+	t.floor.Store(t.heap[0].count)
+}
+
+// itemHeap 是按计数排序的小顶堆
+type itemHeap []*item
+
+func (h itemHeap) Len() int           { return len(h) }
+func (h itemHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -1,18 +1,21 @@
 package geecache
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"geecache/consistenthash"
 	pb "geecache/geecachepb"
 	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
-	"sync"
-	"crypto/tls"
-	"crypto/x509"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -20,47 +23,55 @@ import (
 const (
 	defaultBasePath = "/_geecache/" // 默认路径
 	defaultReplicas = 50
+	// 调用方剩余的超时时间(毫秒)，转发剩余时长而非绝对时间点以避免节点间时钟偏差
+	timeoutHeader = "X-Geecache-Timeout"
 )
 
 // HTTPPool implements PeerPicker for a pool of HTTP peers.
 type HTTPPool struct {
-	// this peer's base URL, e.g. "https://example.net:8000"
-	self        string
-	basePath    string
-	mu          sync.Mutex // guards peers and httpGetters
-	peers       *consistenthash.Map
-	httpGetters map[string]*httpGetter // keyed by e.g. "http://10.0.0.2:8008"
-
-	client      *http.Client    // 支持自定义 TLS Client
+	// this peer's base URL, e.g. "https://example.net:8000",
+	// peers keyed by e.g. "http://10.0.0.2:8008"
+	peerRing[*httpGetter]
+	basePath string
+
+	client *http.Client // 支持自定义 TLS Client
 }
 
 // NewHTTPPool initializes an HTTP pool of peers.
 func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
-		self:     self,            // 本机地址
-		basePath: defaultBasePath, // 默认路径
-	}
+	return newHTTPPool(self, http.DefaultClient) // 默认不启用 TLS
 }
 
 func NewHTTPPoolWithTLS(self, caFile string) *HTTPPool {
-    client := newTLSClient(caFile)
-    return &HTTPPool{self: self, basePath: defaultBasePath, client: client}
+	return newHTTPPool(self, newTLSClient(caFile))
 }
 
-func newTLSClient(caFile string) *http.Client {
-    pool := x509.NewCertPool()
-    pem, err := ioutil.ReadFile(caFile)
-    pool.AppendCertsFromPEM(pem)
-    tr := &http.Transport{
-        TLSClientConfig: &tls.Config{
-            RootCAs:    pool,
-            MinVersion: tls.VersionTLS12,
-        },
-    }
-    return &http.Client{Transport: tr}
+func newHTTPPool(self string, client *http.Client) *HTTPPool {
+	p := &HTTPPool{basePath: defaultBasePath, client: client}
+	p.peerRing = newPeerRing(self, p.newGetter, p.Log)
+	return p
 }
 
+// newGetter 为节点创建 HTTP 客户端，地址:http://10.0.0.2:8008/_geecache/
+func (p *HTTPPool) newGetter(peer string) (*httpGetter, error) {
+	return &httpGetter{baseURL: peer + p.basePath, client: p.client}, nil
+}
 
+func newTLSClient(caFile string) *http.Client {
+	pool := x509.NewCertPool()
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		log.Printf("[GeeCache] Failed to read CA file %s: %v", caFile, err)
+	}
+	pool.AppendCertsFromPEM(pem)
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	return &http.Client{Transport: tr}
+}
 
 // Log info with server name
 func (p *HTTPPool) Log(format string, v ...interface{}) {
@@ -93,14 +104,42 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// 处理GET请求，获取缓存数据
-		view, err := group.Get(key) // 从指定组中获取指定值
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		ctx := r.Context()
+		if timeout := r.Header.Get(timeoutHeader); timeout != "" {
+			ms, err := strconv.ParseInt(timeout, 10, 64)
+			if err != nil {
+				http.Error(w, "bad timeout: "+timeout, http.StatusBadRequest)
+				return
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+			defer cancel()
+		}
+		group.observeGeneration(generationParam(r))
+		var res *pb.Response
+		if r.URL.Query().Get("cache_only") == "true" {
+			// 副本读取：只查本地缓存
+			res = group.peekLocally(key, r.URL.Query().Get("want_lease") == "true")
+		} else {
+			view, err := group.GetContext(ctx, key) // 从指定组中获取指定值
+			if errors.Is(err, ErrNotFound) {
+				// 数据源中不存在该key，返回 404 和 not_found 标记，调用方可缓存该结果
+				body, _ := proto.Marshal(&pb.Response{NotFound: true, Generation: group.Generation()})
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(body)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			res = responseFromView(view)
 		}
 
+		res.Generation = group.Generation()
 		// Write the value to the response body as a proto message.
-		body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+		body, err := proto.Marshal(res)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -110,7 +149,7 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Write(body)
 
 	case http.MethodPut:
-		// 处理PUT请求，存储热点数据
+		// 处理PUT请求，存储热点数据和副本，write=true 时作为拥有者写入数据源
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("reading request body: %v", err), http.StatusBadRequest)
@@ -125,120 +164,148 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// 将数据添加到本地缓存
-		value := ByteView{b: cloneBytes(res.Value)}
-		group.mainCache.add(key, value)
+		current := group.observeGeneration(generationParam(r))
+		if r.URL.Query().Get("write") == "true" {
+			// 本节点是拥有者：写入数据源并更新缓存
+			if err := group.write(key, res.GetValue()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// 将数据添加到本地缓存，启用租约时检查推送携带的租约
+		// 旧代数的推送直接丢弃
+		lease, _ := strconv.ParseUint(r.URL.Query().Get("lease"), 10, 64)
+		if !current || !group.setFromPeer(key, viewFromResponse(res), lease) {
+			p.Log("Rejected stale data for group=%s, key=%s", groupName, key)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 
 		p.Log("Stored hot spot data for group=%s, key=%s", groupName, key)
 		w.WriteHeader(http.StatusOK)
 
-	default:
-		w.Header().Set("Allow", "GET, PUT")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// Set updates the pool's list of peers.
-func (p *HTTPPool) Set(peers ...string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.peers = consistenthash.New(defaultReplicas, nil) // 创建一个一致性哈希
-	p.peers.Add(peers...)                              // 添加节点到一致性哈希
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
-	for _, peer := range peers {
-		// 为每个节点创建一个 HTTP 客户端，地址:http://10.0.0.2:8008/_geecache/
-		// p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath, client: p.client}
-	}
-}
+	case http.MethodPost:
+		// 处理POST请求，批量获取缓存数据: POST /<basepath>/<groupname>/
+		if key != "" {
+			http.Error(w, "batch request must not contain a key", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
 
-// PickPeer picks a peer according to key
-func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+		req := &pb.BatchRequest{}
+		if err = proto.Unmarshal(body, req); err != nil {
+			http.Error(w, fmt.Sprintf("decoding request body: %v", err), http.StatusBadRequest)
+			return
+		}
 
-	// 确保p.peers已经初始化
-	if p.peers == nil {
-		log.Println("PickPeer() called but peers not properly initialized")
-		return nil, false
-	}
+		group.observeGeneration(req.GetGeneration())
+		values, errs := group.GetManyContext(r.Context(), req.GetKeys())
+		body, err = proto.Marshal(batchResponse(req.GetKeys(), values, errs))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		// 通过一致性哈希(节点负荷平衡)找到该值(应该)存储的节点
-		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
-	} else if peer == p.self {
-		p.Log("Pick self %s", peer)
-		return nil, false
-	}
-	return nil, false
-}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
 
-// PickPeers picks multiple peers for hot spot data backup
-func (p *HTTPPool) PickPeers(key string, count int) ([]PeerGetter, bool) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	case http.MethodDelete:
+		if lease, _ := strconv.ParseUint(r.URL.Query().Get("lease"), 10, 64); lease != 0 {
+			// 读取方不再使用的租约，只释放租约不删除数据
+			group.releaseLease(key, lease)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if tag := r.URL.Query().Get("tag"); key == "" && tag != "" {
+			// 其他节点的 InvalidateTag：删除本节点索引中带有该tag的key
+			group.observeGeneration(generationParam(r))
+			if err := group.invalidateTagLocally(tag); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			p.Log("Invalidated tag=%s for group=%s", tag, groupName)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if key == "" {
+			// 其他节点传播的代数，只更新本节点的代数。递增代数只能通过 AdminHandler
+			gen := generationParam(r)
+			if gen == 0 {
+				http.Error(w, "generation is required", http.StatusBadRequest)
+				return
+			}
+			group.observeGeneration(gen)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// 处理DELETE请求，仅删除本地缓存，不再向其他节点转发
+		group.observeGeneration(generationParam(r))
+		group.removeLocally(key)
+		p.Log("Removed key for group=%s, key=%s", groupName, key)
+		w.WriteHeader(http.StatusOK)
 
-	// 确保p.peers已经初始化
-	if p.peers == nil {
-		log.Println("PickPeers() called but peers not properly initialized")
-		return nil, false
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
+}
 
-	// 获取主节点
-	mainPeer := p.peers.Get(key)
-	if mainPeer == "" || mainPeer == p.self {
-		return nil, false
-	}
+// generationPath 是管理接口中递增代数的路径后缀
+const generationPath = "_generation"
 
-	// 收集所有可用的节点（除了自己）
-	var availablePeers []string
-	for peer := range p.httpGetters {
-		if peer != p.self {
-			availablePeers = append(availablePeers, peer)
+// AdminHandler 返回管理接口，与节点间的接口分开，应在只对运维开放的地址上提供服务并自行鉴权。
+// POST /<basepath>/<groupname>/_generation 递增组的代数，使整个集群中组内的数据失效，返回新的代数
+func (p *HTTPPool) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, p.basePath), "/", 2)
+		if !strings.HasPrefix(r.URL.Path, p.basePath) || len(parts) != 2 || parts[1] != generationPath {
+			http.NotFound(w, r)
+			return
 		}
-	}
-
-	// 如果可用节点数量不足，返回所有可用节点
-	if len(availablePeers) <= count {
-		peers := make([]PeerGetter, 0, len(availablePeers))
-		for _, peer := range availablePeers {
-			peers = append(peers, p.httpGetters[peer])
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
-		p.Log("Pick all %d available peers for hot spot data", len(peers))
-		return peers, len(peers) > 0
-	}
-
-	// 否则，选择指定数量的节点（包括主节点）
-	peers := make([]PeerGetter, 0, count)
-	// 首先添加主节点
-	peers = append(peers, p.httpGetters[mainPeer])
-
-	// 随机选择其他节点
-	for i := 0; i < count-1 && len(availablePeers) > 0; i++ {
-		// 简单随机选择一个节点
-		index := rand.Intn(len(availablePeers))
-		peer := availablePeers[index]
-		// 如果不是主节点，添加到结果中
-		if peer != mainPeer {
-			peers = append(peers, p.httpGetters[peer])
+		group := GetGroup(parts[0])
+		if group == nil {
+			http.Error(w, "no such group: "+parts[0], http.StatusNotFound)
+			return
 		}
-		// 从可用节点中移除已选择的节点
-		availablePeers = append(availablePeers[:index], availablePeers[index+1:]...)
-	}
-
-	p.Log("Pick %d peers for hot spot data", len(peers))
-	return peers, true
+		gen, err := group.BumpGeneration()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.Log("Bumped group=%s to generation %d", parts[0], gen)
+		fmt.Fprintf(w, "%d\n", gen)
+	})
 }
 
-var _ PeerPicker = (*HTTPPool)(nil)
+var (
+	_ PeerPicker    = (*HTTPPool)(nil)
+	_ ReplicaPicker = (*HTTPPool)(nil)
+	_ HandoffPicker = (*HTTPPool)(nil)
+	_ PeerLister    = (*HTTPPool)(nil)
+)
 
 type httpGetter struct {
 	baseURL string
 	client  *http.Client
 }
 
-func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+// close HTTP 客户端由所有节点共享，无需单独释放
+func (h *httpGetter) close() {}
+
+func (h *httpGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
 	// 构建请求的 URL:http://10.0.0.2:8008/_geecache/<groupname>/<key>
 	u := fmt.Sprintf(
 		"%v%v/%v",
@@ -247,14 +314,47 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 		url.QueryEscape(in.GetKey()),
 	)
 
-	// res, err := http.Get(u) 
+	query := url.Values{}
+	if in.GetCacheOnly() {
+		query.Set("cache_only", "true")
+	}
+	if in.GetWantLease() {
+		query.Set("want_lease", "true")
+	}
+	setGenerationParam(query, in.GetGeneration())
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+	// 将调用方的截止时间转发给远程节点
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline).Milliseconds()
+		if remaining <= 0 {
+			return context.DeadlineExceeded
+		}
+		req.Header.Set(timeoutHeader, strconv.FormatInt(remaining, 10))
+	}
+
+	// res, err := http.Get(u)
 	// 发送 HTTP 请求给该地址的 HTTP 服务端，由ServeHttp来处理
-	res, err := h.client.Get(u)
+	res, err := h.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotFound {
+		// 区分数据源中不存在的key和不存在的组
+		bytes, _ := io.ReadAll(res.Body)
+		if proto.Unmarshal(bytes, out) == nil && out.GetNotFound() {
+			return notFoundError(in.GetKey())
+		}
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
 	if res.StatusCode != http.StatusOK {
 		return fmt.Errorf("server returned: %v", res.Status)
 	}
@@ -280,6 +380,17 @@ func (h *httpGetter) Set(in *pb.Request, out *pb.Response) error {
 		url.QueryEscape(in.GetGroup()),
 		url.QueryEscape(in.GetKey()),
 	)
+	query := url.Values{}
+	if in.GetWrite() {
+		query.Set("write", "true")
+	}
+	if in.GetLease() != 0 {
+		query.Set("lease", strconv.FormatUint(in.GetLease(), 10))
+	}
+	setGenerationParam(query, in.GetGeneration())
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
 
 	// 将响应数据序列化为protobuf
 	body, err := proto.Marshal(out)
@@ -311,4 +422,100 @@ func (h *httpGetter) Set(in *pb.Request, out *pb.Response) error {
 	return nil
 }
 
+// Delete sends a DELETE request to remove a key from remote peer
+func (h *httpGetter) Delete(in *pb.Request) error {
+	// 构建请求的 URL:http://10.0.0.2:8008/_geecache/<groupname>/<key>
+	u := fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.QueryEscape(in.GetGroup()),
+		url.QueryEscape(in.GetKey()),
+	)
+	query := url.Values{}
+	setGenerationParam(query, in.GetGeneration())
+	if in.GetKey() == "" {
+		// 传播代数的请求总是携带参数，否则会被当作管理接口的递增请求
+		query.Set("generation", strconv.FormatUint(in.GetGeneration(), 10))
+	}
+	if in.GetTag() != "" {
+		query.Set("tag", in.GetTag())
+	}
+	if in.GetLease() != 0 {
+		query.Set("lease", strconv.FormatUint(in.GetLease(), 10))
+	}
+	u += "?" + query.Encode()
+
+	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+
+	return nil
+}
+
+// GetMany sends a POST request to load multiple keys from remote peer
+func (h *httpGetter) GetMany(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error {
+	// 构建请求的 URL:http://10.0.0.2:8008/_geecache/<groupname>/
+	u := fmt.Sprintf(
+		"%v%v/",
+		h.baseURL,
+		url.QueryEscape(in.GetGroup()),
+	)
+
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %v", err)
+	}
+
+	if err = proto.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding response body: %v", err)
+	}
+
+	return nil
+}
+
+// generationParam 读取请求携带的组代数，未携带时为 0
+func generationParam(r *http.Request) uint64 {
+	gen, _ := strconv.ParseUint(r.URL.Query().Get("generation"), 10, 64)
+	return gen
+}
+
+// setGenerationParam 将组代数加入查询参数，0 表示不携带
+func setGenerationParam(query url.Values, gen uint64) {
+	if gen != 0 {
+		query.Set("generation", strconv.FormatUint(gen, 10))
+	}
+}
+
 var _ PeerGetter = (*httpGetter)(nil)
@@ -6,118 +6,195 @@ import (
 	"log"
 	"sync"
 	"time"
-	"crypto/tls"           // 新增：TLS 支持
-	"crypto/x509"          // 新增：根证书池
-	"io/ioutil"            // 新增：读取 CA 文件
 )
 
 // HTTPPoolWithDiscovery 实现基于服务发现的HTTP节点池
 type HTTPPoolWithDiscovery struct {
 	*HTTPPool
-	discovery       registry.Discovery // 服务发现客户端
-	servicePrefix   string             // 服务前缀
-	refreshInterval time.Duration      // 刷新间隔
-	stopSignal      chan struct{}      // 停止信号
-	mu              sync.Mutex         // 互斥锁
+	*peerDiscovery
 }
 
 // NewHTTPPoolWithDiscovery 创建一个支持服务发现的HTTP节点池
 func NewHTTPPoolWithDiscovery(self string, discovery registry.Discovery, servicePrefix string) *HTTPPoolWithDiscovery {
-	if servicePrefix == "" {
-		servicePrefix = registry.DefaultServicePrefix
+	httpPool := NewHTTPPool(self)
+	return &HTTPPoolWithDiscovery{
+		HTTPPool:      httpPool,
+		peerDiscovery: newPeerDiscovery(discovery, servicePrefix, httpPool),
 	}
+}
 
-	pool := &HTTPPoolWithDiscovery{
-		HTTPPool:        NewHTTPPool(self),
-		discovery:       discovery,
-		servicePrefix:   servicePrefix,
-		refreshInterval: 10 * time.Second,
-		stopSignal:      make(chan struct{}),
+// NewHTTPPoolWithDiscoveryAndTLS 创建一个支持服务发现的 HTTPS 节点池
+// caFile: CA 根证书，用于验证其他节点的 TLS 证书
+func NewHTTPPoolWithDiscoveryAndTLS(self string, discovery registry.Discovery, servicePrefix, caFile string) *HTTPPoolWithDiscovery {
+	// 创建带 TLS 配置的 HTTPPool
+	tlsPool := NewHTTPPoolWithTLS(self, caFile) // HTTPPool.client = newTLSClient(caFile)
+	return &HTTPPoolWithDiscovery{
+		HTTPPool:      tlsPool,
+		peerDiscovery: newPeerDiscovery(discovery, servicePrefix, tlsPool),
 	}
+}
 
-	// 初始化节点列表
-	pool.refreshPeers()
+// 订阅服务变化后，定期刷新只用于补偿可能遗漏的事件
+const defaultPeerRefreshInterval = time.Minute
 
-	// 启动定期刷新节点的goroutine
-	go pool.refreshPeersLoop()
+// peerSetter 是服务发现更新节点池时用到的操作，HTTPPool 和 GRPCPool 实现了该接口
+type peerSetter interface {
+	AddPeers(peers ...string)
+	RemovePeers(peers ...string)
+}
 
-	return pool
+// peerDiscovery 根据服务发现的结果维护节点池的节点列表，HTTP 和 gRPC 节点池共用。
+// 订阅服务变化并增量更新哈希环，同时定期与服务列表对账
+type peerDiscovery struct {
+	discovery       registry.Discovery // 服务发现客户端
+	servicePrefix   string             // 服务前缀
+	refreshInterval time.Duration      // 刷新间隔
+	stopSignal      chan struct{}      // 停止信号
+	mu              sync.Mutex         // 互斥锁
+	pool            peerSetter         // 节点池
+	members         map[string]string  // 服务键 -> 节点地址
+	cancel          func()             // 取消订阅
 }
 
-// NewHTTPPoolWithDiscoveryAndTLS 创建一个支持服务发现的 HTTPS 节点池
-// caFile: CA 根证书，用于验证其他节点的 TLS 证书
-func NewHTTPPoolWithDiscoveryAndTLS(self string, discovery registry.Discovery, servicePrefix, caFile string) *HTTPPoolWithDiscovery {
+// newPeerDiscovery 初始化节点列表，订阅服务变化并启动定期刷新
+func newPeerDiscovery(discovery registry.Discovery, servicePrefix string, pool peerSetter) *peerDiscovery {
 	if servicePrefix == "" {
 		servicePrefix = registry.DefaultServicePrefix
 	}
 
-	// 创建带 TLS 配置的 HTTPPool
-	tlsPool := NewHTTPPoolWithTLS(self, caFile)
-	pool := &HTTPPoolWithDiscovery{
-		HTTPPool:        tlsPool,  // HTTPPool.client = newTLSClient(caFile)
+	d := &peerDiscovery{
 		discovery:       discovery,
 		servicePrefix:   servicePrefix,
-		refreshInterval: 10 * time.Second,
+		refreshInterval: defaultPeerRefreshInterval,
 		stopSignal:      make(chan struct{}),
+		pool:            pool,
+		members:         make(map[string]string),
 	}
 
 	// 初始化节点列表
-	pool.refreshPeers()
+	d.refreshPeers()
+
+	// 订阅服务变化，已存在的服务会重新投递，不会重复添加
+	d.cancel = discovery.Subscribe(servicePrefix, d.handleEvent)
 
 	// 启动定期刷新节点的goroutine
-	go pool.refreshPeersLoop()
+	go d.refreshPeersLoop()
+
+	return d
+}
+
+// handleEvent 将服务变化应用到节点池
+func (p *peerDiscovery) handleEvent(ev registry.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.Type {
+	case registry.EventPut:
+		old, ok := p.members[ev.Key]
+		if ok && old == ev.Service.Addr {
+			return
+		}
+		p.members[ev.Key] = ev.Service.Addr
+		if ok {
+			p.removeIfUnused(old)
+		}
+		p.pool.AddPeers(ev.Service.Addr)
+	case registry.EventDelete:
+		addr, ok := p.members[ev.Key]
+		if !ok {
+			return
+		}
+		delete(p.members, ev.Key)
+		p.removeIfUnused(addr)
+	}
+}
 
-	return pool
+// removeIfUnused 没有其他服务使用该地址时将其移出节点池，调用方需持有锁
+func (p *peerDiscovery) removeIfUnused(addr string) {
+	if !containsAddr(p.members, addr) {
+		p.pool.RemovePeers(addr)
+	}
 }
 
-// 刷新节点列表
-func (p *HTTPPoolWithDiscovery) refreshPeers() {
+// 刷新节点列表，只应用与当前节点列表的差异
+func (p *peerDiscovery) refreshPeers() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	services := p.discovery.GetServicesByPrefix(p.servicePrefix)
 	if len(services) == 0 {
+		// 服务列表为空通常是注册中心暂时不可用，保留现有节点
 		log.Println("No services found with prefix:", p.servicePrefix)
 		return
 	}
 
-	var peers []string
-	for _, service := range services {
-		peers = append(peers, service.Addr)
+	members := make(map[string]string, len(services))
+	addrs := make(map[string]bool, len(services))
+	for key, service := range services {
+		members[key] = service.Addr
+		addrs[service.Addr] = true
+	}
+
+	var added, removed []string
+	for addr := range addrs {
+		if !containsAddr(p.members, addr) {
+			added = append(added, addr)
+		}
+	}
+	for _, addr := range p.members {
+		if !addrs[addr] {
+			removed = append(removed, addr)
+			addrs[addr] = true // 多个服务使用同一地址时只移除一次
+		}
+	}
+	p.members = members
+	if len(added) == 0 && len(removed) == 0 {
+		return
 	}
 
 	// 更新节点列表
-	p.HTTPPool.Set(peers...)
-	log.Printf("Refreshed peers: %v\n", peers)
+	p.pool.RemovePeers(removed...)
+	p.pool.AddPeers(added...)
+	log.Printf("Refreshed peers: added %v, removed %v\n", added, removed)
 }
 
-// 定期刷新节点列表
-func (p *HTTPPoolWithDiscovery) refreshPeersLoop() {
-	// 创建一个定时器，固定时间间隔内周期性触发，并向 ticker.C 这个通道发送信号
-	ticker := time.NewTicker(p.refreshInterval)
-	defer ticker.Stop()
+func containsAddr(members map[string]string, addr string) bool {
+	for _, a := range members {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
 
+// 定期刷新节点列表
+func (p *peerDiscovery) refreshPeersLoop() {
 	for {
+		p.mu.Lock()
+		interval := p.refreshInterval
+		p.mu.Unlock()
+
 		select {
 		case <-p.stopSignal:
 			// 监听 stopSignal，收到信号则退出循环，终止刷新任务
 			return
-		case <-ticker.C:
+		case <-time.After(interval):
 			// 每隔一段时间（refreshInterval），刷新节点列表
 			p.refreshPeers()
 		}
 	}
 }
 
-// SetRefreshInterval 设置刷新间隔
-func (p *HTTPPoolWithDiscovery) SetRefreshInterval(interval time.Duration) {
+// SetRefreshInterval 设置刷新间隔，在下一次刷新后生效
+func (p *peerDiscovery) SetRefreshInterval(interval time.Duration) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.refreshInterval = interval
 }
 
-// Close 关闭服务发现
-func (p *HTTPPoolWithDiscovery) Close() error {
+// Close 取消订阅并停止刷新
+func (p *peerDiscovery) Close() error {
+	p.cancel()
 	close(p.stopSignal)
 	return nil
 }
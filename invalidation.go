@@ -0,0 +1,116 @@
+package geecache
+
+import (
+	"context"
+	"geecache/invalidation"
+	"log"
+	"strings"
+)
+
+// SetInvalidationBus 订阅集群的失效消息总线：收到本组的消息时删除本节点缓存中的
+// 副本、热点备份和近端缓存，Remove 和 Invalidate 将删除广播给所有节点。
+// 消息至少投递一次，错过消息时清空整个组。应在使用组之前调用
+func (g *Group) SetInvalidationBus(bus invalidation.Bus) error {
+	cancel, err := bus.Subscribe(0, g.applyInvalidation)
+	if err != nil {
+		return err
+	}
+	if g.busCancel != nil {
+		g.busCancel()
+	}
+	g.bus = bus
+	g.busCancel = cancel
+	return nil
+}
+
+// Invalidate 在数据源中的key变更后调用，删除所有节点缓存中的key，
+// 不指定key时清空整个组。未配置总线时通过 Remove 通知key的拥有者、副本和备份节点，
+// 清空整个组时通过 BumpGeneration 广播新的代数
+func (g *Group) Invalidate(ctx context.Context, keys ...string) error {
+	if g.bus == nil {
+		return g.invalidateWithoutBus(keys...)
+	}
+	if len(keys) == 0 {
+		g.flushLocally()
+	}
+	for _, key := range keys {
+		g.removeLocally(key)
+	}
+	return g.publishInvalidation(ctx, keys...)
+}
+
+// invalidateWithoutBus 在未配置总线时直接通知其他节点
+func (g *Group) invalidateWithoutBus(keys ...string) error {
+	if len(keys) == 0 {
+		_, err := g.BumpGeneration()
+		return err
+	}
+	var firstErr error
+	for _, key := range keys {
+		if err := g.Remove(key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// publishInvalidation 将删除发布到总线
+func (g *Group) publishInvalidation(ctx context.Context, keys ...string) error {
+	if g.bus == nil {
+		return nil
+	}
+	if _, err := g.bus.Publish(ctx, g.name, keys...); err != nil {
+		log.Printf("[GeeCache] Failed to publish invalidation for %s: %v", g.name, err)
+		return err
+	}
+	return nil
+}
+
+// applyInvalidation 处理总线上的消息，同一消息可能被投递多次
+func (g *Group) applyInvalidation(msg invalidation.Message) {
+	if msg.Group != "" && msg.Group != g.name {
+		return
+	}
+	g.stats.invalidations.Add(1)
+	if msg.Flush() {
+		g.flushLocally()
+		return
+	}
+	for _, key := range msg.Keys {
+		g.removeLocally(key)
+	}
+}
+
+// flushLocally 清空本节点缓存中本组的所有数据，并作废未完成的租约
+func (g *Group) flushLocally() {
+	g.stats.flushes.Add(1)
+	g.mainCache.clear()
+	if g.negCache != nil {
+		g.negCache.clear()
+	}
+	g.clearL2()
+	if g.tags != nil {
+		g.tags.clear()
+	}
+	if g.leases != nil {
+		g.leases.invalidateAll()
+	}
+}
+
+// clearL2 删除二级缓存中本组的所有数据
+func (g *Group) clearL2() {
+	if g.l2 == nil {
+		return
+	}
+	keys, err := g.l2.store.Keys()
+	if err != nil {
+		log.Printf("[GeeCache] Failed to list L2 keys: %v", err)
+		return
+	}
+	prefix := g.l2Key("")
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			g.l2.store.Delete(key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package invalidation 实现集群范围的缓存失效消息总线。
+// 数据源变更时发布失效消息，所有节点删除本地的副本、热点备份和近端缓存。
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed 在总线关闭后发布或订阅时返回
+var ErrClosed = errors.New("invalidation: bus closed")
+
+// Message 是一条失效消息
+type Message struct {
+	// Seq 是消息在总线上的序号，从 1 开始连续递增
+	Seq uint64 `json:"seq"`
+	// Group 为空表示所有组
+	Group string `json:"group,omitempty"`
+	// Keys 为空表示组内的所有key
+	Keys []string `json:"keys,omitempty"`
+}
+
+// Flush 判断消息是否要求清空整个组
+func (m Message) Flush() bool {
+	return len(m.Keys) == 0
+}
+
+// Bus 是失效消息总线。消息至少投递一次，订阅方需要能够重复处理同一条消息；
+// 订阅方错过消息(如断线期间消息已被清理)时收到一条 Group 和 Keys 都为空的消息，
+// 表示应清空所有组
+type Bus interface {
+	// Publish 发布失效消息，返回分配的序号
+	Publish(ctx context.Context, group string, keys ...string) (uint64, error)
+	// Subscribe 订阅序号大于 after 的消息，after 为 0 表示只接收订阅之后的消息。
+	// 同一订阅的 fn 按序号顺序调用，不会并发执行
+	Subscribe(after uint64, fn func(Message)) (cancel func(), err error)
+	// Close 关闭总线并结束所有订阅
+	Close() error
+}
+
+// sequencer 按序号过滤投递给订阅方的消息：丢弃重复的消息，
+// 发现序号不连续时先投递一条清空所有组的消息
+type sequencer struct {
+	mu   sync.Mutex
+	last uint64
+	fn   func(Message)
+}
+
+func newSequencer(last uint64, fn func(Message)) *sequencer {
+	return &sequencer{last: last, fn: fn}
+}
+
+// deliver 投递一条消息，返回是否发现了缺失的消息
+func (s *sequencer) deliver(msg Message) (gap bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg.Seq <= s.last {
+		return false
+	}
+	if msg.Seq > s.last+1 {
+		gap = true
+		s.fn(Message{Seq: msg.Seq - 1})
+	}
+	s.last = msg.Seq
+	s.fn(msg)
+	return gap
+}
+
+// lost 表示无法确定错过了哪些消息，投递一条清空所有组的消息，之后从 seq 继续
+func (s *sequencer) lost(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq < s.last {
+		seq = s.last
+	}
+	s.last = seq
+	s.fn(Message{Seq: seq})
+}
+
+func (s *sequencer) position() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
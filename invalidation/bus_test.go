@@ -0,0 +1,102 @@
+package invalidation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// recorder 记录收到的消息
+type recorder struct {
+	msgs []Message
+}
+
+func (r *recorder) fn(msg Message) {
+	r.msgs = append(r.msgs, msg)
+}
+
+func TestLocalBusDelivery(t *testing.T) {
+	ctx := context.Background()
+	bus := NewLocalBus(0)
+	defer bus.Close()
+
+	bus.Publish(ctx, "scores", "before")
+	var r recorder
+	cancel, err := bus.Subscribe(0, r.fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, _ := bus.Publish(ctx, "scores", "a", "b")
+	bus.Publish(ctx, "scores")
+
+	// 只收到订阅之后的消息，序号连续
+	want := []Message{
+		{Seq: seq, Group: "scores", Keys: []string{"a", "b"}},
+		{Seq: seq + 1, Group: "scores"},
+	}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Fatalf("got %+v, want %+v", r.msgs, want)
+	}
+	if !r.msgs[1].Flush() {
+		t.Fatal("message without keys should flush the group")
+	}
+
+	cancel()
+	bus.Publish(ctx, "scores", "c")
+	if len(r.msgs) != 2 {
+		t.Fatal("cancelled subscription should not receive messages")
+	}
+}
+
+func TestLocalBusCatchUp(t *testing.T) {
+	ctx := context.Background()
+	bus := NewLocalBus(2)
+	defer bus.Close()
+	for _, key := range []string{"a", "b", "c"} {
+		bus.Publish(ctx, "scores", key)
+	}
+
+	// 落后的消息仍在历史中时补发
+	var r recorder
+	bus.Subscribe(1, r.fn)
+	if len(r.msgs) != 2 || r.msgs[0].Keys[0] != "b" || r.msgs[1].Keys[0] != "c" {
+		t.Fatalf("expected messages 2 and 3 to be replayed, got %+v", r.msgs)
+	}
+
+	// 需要的消息已被清理时收到清空所有组的消息
+	var lost recorder
+	bus.Subscribe(0, func(Message) {})
+	bus.Subscribe(1, func(Message) {})
+	bus.Publish(ctx, "scores", "d")
+	bus.Subscribe(1, lost.fn)
+	if len(lost.msgs) != 1 || lost.msgs[0].Group != "" || !lost.msgs[0].Flush() || lost.msgs[0].Seq != 4 {
+		t.Fatalf("expected a single flush-all message, got %+v", lost.msgs)
+	}
+}
+
+func TestSequencer(t *testing.T) {
+	var r recorder
+	s := newSequencer(3, r.fn)
+
+	// 重复的消息被丢弃
+	if s.deliver(Message{Seq: 3, Keys: []string{"dup"}}) || len(r.msgs) != 0 {
+		t.Fatal("duplicate message should be dropped")
+	}
+	s.deliver(Message{Seq: 4, Keys: []string{"a"}})
+
+	// 序号不连续时先清空所有组
+	if !s.deliver(Message{Seq: 7, Keys: []string{"b"}}) {
+		t.Fatal("expected a gap")
+	}
+	want := []Message{
+		{Seq: 4, Keys: []string{"a"}},
+		{Seq: 6},
+		{Seq: 7, Keys: []string{"b"}},
+	}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Fatalf("got %+v, want %+v", r.msgs, want)
+	}
+	if s.position() != 7 {
+		t.Fatalf("position = %d, want 7", s.position())
+	}
+}
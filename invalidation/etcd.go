@@ -0,0 +1,204 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdBus 是基于 etcd 的失效消息总线。序号保存在 prefix+"seq" 中，
+// 每条消息保存在 prefix+"msg/<序号>" 中，只保留最近的 retain 条；
+// 订阅方先读取落后的消息，再从读取时的版本开始 watch，断线或版本被压缩后重新读取
+type EtcdBus struct {
+	client *clientv3.Client
+	prefix string
+	retain uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEtcdBus 使用已有的 etcd 客户端(如 registry.ServiceDiscovery.Client())创建总线，
+// retain 为保留的历史消息数量，0 表示使用默认值。关闭总线不会关闭客户端
+func NewEtcdBus(client *clientv3.Client, prefix string, retain int) *EtcdBus {
+	if retain <= 0 {
+		retain = defaultRetain
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EtcdBus{
+		client: client,
+		prefix: prefix,
+		retain: uint64(retain),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (b *EtcdBus) seqKey() string {
+	return b.prefix + "seq"
+}
+
+func (b *EtcdBus) msgPrefix() string {
+	return b.prefix + "msg/"
+}
+
+// msgKey 序号补齐为定长，使键的顺序与序号的顺序一致
+func (b *EtcdBus) msgKey(seq uint64) string {
+	return fmt.Sprintf("%s%020d", b.msgPrefix(), seq)
+}
+
+// currentSeq 读取最新的序号和序号键的修改版本
+func (b *EtcdBus) currentSeq(ctx context.Context, opts ...clientv3.OpOption) (uint64, int64, error) {
+	resp, err := b.client.Get(ctx, b.seqKey(), opts...)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, 0, nil
+	}
+	seq, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sequence %q: %v", resp.Kvs[0].Value, err)
+	}
+	return seq, resp.Kvs[0].ModRevision, nil
+}
+
+// Publish 实现 Bus 接口，通过比较序号键的版本分配连续的序号，
+// 序号递增、写入消息和清理旧消息在同一个事务中完成
+func (b *EtcdBus) Publish(ctx context.Context, group string, keys ...string) (uint64, error) {
+	if b.ctx.Err() != nil {
+		return 0, ErrClosed
+	}
+	for {
+		cur, rev, err := b.currentSeq(ctx)
+		if err != nil {
+			return 0, err
+		}
+		next := cur + 1
+		data, err := json.Marshal(Message{Seq: next, Group: group, Keys: keys})
+		if err != nil {
+			return 0, err
+		}
+
+		ops := []clientv3.Op{
+			clientv3.OpPut(b.seqKey(), strconv.FormatUint(next, 10)),
+			clientv3.OpPut(b.msgKey(next), string(data)),
+		}
+		if next > b.retain {
+			ops = append(ops, clientv3.OpDelete(b.msgKey(0), clientv3.WithRange(b.msgKey(next-b.retain))))
+		}
+		resp, err := b.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(b.seqKey()), "=", rev)).
+			Then(ops...).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if resp.Succeeded {
+			return next, nil
+		}
+		// 其他节点同时发布，重新读取序号
+	}
+}
+
+// Subscribe 实现 Bus 接口，消息在后台 goroutine 中投递
+func (b *EtcdBus) Subscribe(after uint64, fn func(Message)) (func(), error) {
+	if b.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	if after == 0 {
+		cur, _, err := b.currentSeq(b.ctx)
+		if err != nil {
+			return nil, err
+		}
+		after = cur
+	}
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	s := newSequencer(after, fn)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.watch(ctx, s)
+	}()
+	return cancel, nil
+}
+
+// watch 补发落后的消息后持续监听新消息，出错时重新补发
+func (b *EtcdBus) watch(ctx context.Context, s *sequencer) {
+	for ctx.Err() == nil {
+		rev, err := b.catchUp(ctx, s)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[Invalidation] Failed to read messages: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		wch := b.client.Watch(ctx, b.msgPrefix(), clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		for wresp := range wch {
+			if err := wresp.Err(); err != nil {
+				log.Printf("[Invalidation] Watch interrupted: %v", err)
+				break
+			}
+			for _, ev := range wresp.Events {
+				if ev.Type != clientv3.EventTypePut {
+					continue
+				}
+				var msg Message
+				if err := json.Unmarshal(ev.Kv.Value, &msg); err != nil {
+					log.Printf("[Invalidation] Invalid message %s: %v", ev.Kv.Key, err)
+					continue
+				}
+				s.deliver(msg)
+			}
+		}
+	}
+}
+
+// catchUp 投递序号大于订阅位置的消息，返回读取时的版本。
+// 需要的消息已被清理时投递清空所有组的消息
+func (b *EtcdBus) catchUp(ctx context.Context, s *sequencer) (int64, error) {
+	resp, err := b.client.Get(ctx, b.msgKey(s.position()+1),
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(b.msgPrefix())))
+	if err != nil {
+		return 0, err
+	}
+	rev := resp.Header.Revision
+	for _, kv := range resp.Kvs {
+		var msg Message
+		if err := json.Unmarshal(kv.Value, &msg); err != nil {
+			log.Printf("[Invalidation] Invalid message %s: %v", kv.Key, err)
+			continue
+		}
+		s.deliver(msg)
+	}
+
+	// 序号之后的消息都已被清理
+	cur, _, err := b.currentSeq(ctx, clientv3.WithRev(rev))
+	if err != nil {
+		return 0, err
+	}
+	if cur > s.position() {
+		s.lost(cur)
+	}
+	return rev, nil
+}
+
+// Close 实现 Bus 接口，结束所有订阅
+func (b *EtcdBus) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
@@ -0,0 +1,97 @@
+package invalidation
+
+import (
+	"context"
+	"sync"
+)
+
+// 默认保留的历史消息数量，订阅方落后超过该数量时需要清空所有组
+const defaultRetain = 1024
+
+// LocalBus 是进程内的失效消息总线，用于测试和单进程部署。
+// 消息在 Publish 中同步投递，订阅方的回调不能再调用 Publish
+type LocalBus struct {
+	mu      sync.Mutex
+	retain  int
+	seq     uint64
+	history []Message // 最近 retain 条消息，用于补发
+	subs    map[int]*sequencer
+	nextID  int
+	closed  bool
+}
+
+// NewLocalBus 创建进程内总线，retain 为保留的历史消息数量，0 表示使用默认值
+func NewLocalBus(retain int) *LocalBus {
+	if retain <= 0 {
+		retain = defaultRetain
+	}
+	return &LocalBus{
+		retain: retain,
+		subs:   make(map[int]*sequencer),
+	}
+}
+
+// Publish 实现 Bus 接口
+func (b *LocalBus) Publish(ctx context.Context, group string, keys ...string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+
+	b.seq++
+	msg := Message{Seq: b.seq, Group: group, Keys: append([]string(nil), keys...)}
+	b.history = append(b.history, msg)
+	if len(b.history) > b.retain {
+		b.history = b.history[len(b.history)-b.retain:]
+	}
+	for _, s := range b.subs {
+		s.deliver(msg)
+	}
+	return msg.Seq, nil
+}
+
+// Subscribe 实现 Bus 接口，after 之后仍保留在历史中的消息在返回之前补发
+func (b *LocalBus) Subscribe(after uint64, fn func(Message)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if after == 0 || after > b.seq {
+		after = b.seq
+	}
+
+	s := newSequencer(after, fn)
+	if after < b.seq {
+		// 需要的消息已不在历史中
+		if len(b.history) == 0 || b.history[0].Seq > after+1 {
+			s.lost(b.seq)
+		} else {
+			for _, msg := range b.history {
+				s.deliver(msg)
+			}
+		}
+	}
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = s
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}, nil
+}
+
+// Close 实现 Bus 接口
+func (b *LocalBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.subs = make(map[int]*sequencer)
+	return nil
+}
@@ -0,0 +1,86 @@
+package geecache
+
+import (
+	"context"
+	"geecache/invalidation"
+	"testing"
+)
+
+func TestInvalidationBus(t *testing.T) {
+	ctx := context.Background()
+	bus := invalidation.NewLocalBus(0)
+	defer bus.Close()
+	g := NewGroup("invalidation-bus", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	}))
+	if err := g.SetInvalidationBus(bus); err != nil {
+		t.Fatal(err)
+	}
+	g.Get("a")
+	g.Get("b")
+
+	// 其他节点发布的消息删除本节点的副本，其他组的消息被忽略
+	bus.Publish(ctx, "other-group", "a")
+	if _, ok := g.mainCache.get("a"); !ok {
+		t.Fatal("message for another group should be ignored")
+	}
+	bus.Publish(ctx, "invalidation-bus", "a")
+	if _, ok := g.mainCache.get("a"); ok {
+		t.Fatal("a should be invalidated")
+	}
+
+	// Remove 将删除广播给其他节点
+	var got []invalidation.Message
+	bus.Subscribe(0, func(msg invalidation.Message) { got = append(got, msg) })
+	if err := g.Remove("b"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Group != "invalidation-bus" || got[0].Keys[0] != "b" {
+		t.Fatalf("expected Remove to publish b, got %+v", got)
+	}
+}
+
+func TestInvalidationGapFlushes(t *testing.T) {
+	g := NewGroup("invalidation-gap", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	}))
+	for _, key := range []string{"a", "b", "c"} {
+		g.Get(key)
+	}
+
+	// 错过消息时总线投递不带组和key的消息，清空整个组
+	g.applyInvalidation(invalidation.Message{Seq: 7})
+	if g.mainCache.size() != 0 {
+		t.Fatal("a gap should flush the whole group")
+	}
+	if stats := g.GetStats(); stats.Flushes != 1 || stats.Invalidations != 1 {
+		t.Fatalf("expected 1 flush, got %+v", stats)
+	}
+	if view, _ := g.Get("a"); view.String() != "v-a" {
+		t.Fatalf("Get(a) = %q after the flush", view)
+	}
+}
+
+func TestInvalidateWithoutBus(t *testing.T) {
+	g := NewGroup("invalidation-no-bus", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	}))
+	picker := &listPicker{fakePicker{owner: &fakePeer{}}}
+	g.RegisterPeers(picker)
+
+	// 未配置总线时删除请求直接发送给key的拥有者
+	if err := g.Invalidate(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := picker.owner.deleted; len(deleted) != 1 || deleted[0] != "a" {
+		t.Fatalf("owner deletes = %q, want [a]", deleted)
+	}
+
+	// 清空整个组时广播新的代数
+	if err := g.Invalidate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if g.Generation() != 1 || len(picker.owner.deleted) != 2 {
+		t.Fatalf("generation = %d, owner deletes = %q; want the generation broadcast", g.Generation(), picker.owner.deleted)
+	}
+}
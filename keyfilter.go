@@ -0,0 +1,106 @@
+package geecache
+
+import (
+	"geecache/bloom"
+	"log"
+)
+
+// A KeySource lists all valid keys of a group, calling add for each key.
+type KeySource func(add func(key string)) error
+
+// SetKeyFilter 启用已知key的布隆过滤器：回源之前检查过滤器，判定不存在的key直接返回
+// ErrNotFound，防止随机key穿透到数据源。过滤器在回源的节点(通常是拥有者)上检查，
+// 非拥有者照常转发请求，不会因为未学到其他节点新增的key而拒绝。过滤器按 expectedKeys 个key和
+// 误判率 fpRate 分配空间，回源成功和 Set 的key会加入过滤器。
+// 启用后需调用 RebuildKeyFilter 从数据源导入已有的key，之后新增到数据源的key
+// 在下一次重建之前会被拒绝，应定期重建。所有节点应使用相同的参数以便合并，
+// 应在使用组之前调用
+func (g *Group) SetKeyFilter(expectedKeys int, fpRate float64) {
+	g.keyFilterLearning.Store(false)
+	g.keyFilter.Store(bloom.New(expectedKeys, fpRate))
+}
+
+// LearnKeyFilter 启用学习模式的布隆过滤器：在第一次 RebuildKeyFilter 或 EnforceKeyFilter
+// 之前放行所有key，只记录回源成功和 Set 的key，用于无法列出所有key的数据源。
+// 各节点只学到自己回源的key，拥有者不可用时接替回源的节点可通过 ExportKeyFilter/MergeKeyFilter
+// 获得其他节点学到的key。参数要求与 SetKeyFilter 相同，应在使用组之前调用
+func (g *Group) LearnKeyFilter(expectedKeys int, fpRate float64) {
+	g.keyFilterLearning.Store(true)
+	g.keyFilter.Store(bloom.New(expectedKeys, fpRate))
+}
+
+// EnforceKeyFilter 结束学习阶段，之后过滤器判定不存在的key被拒绝
+func (g *Group) EnforceKeyFilter() {
+	g.keyFilterLearning.Store(false)
+}
+
+// RebuildKeyFilter 从 source 列出的key构建新的过滤器并原子替换，同时结束学习阶段。
+// 构建期间仍使用原来的过滤器，期间回源成功和 Set 的key同时加入新的过滤器
+func (g *Group) RebuildKeyFilter(source KeySource) error {
+	old := g.keyFilter.Load()
+	if old == nil {
+		return nil
+	}
+	f := old.EmptyCopy()
+	g.nextKeyFilter.Store(f)
+	defer g.nextKeyFilter.CompareAndSwap(f, nil)
+	n := 0
+	if err := source(func(key string) {
+		f.Add(key)
+		n++
+	}); err != nil {
+		return err
+	}
+	g.keyFilter.Store(f)
+	g.keyFilterLearning.Store(false)
+	log.Printf("[GeeCache] Rebuilt key filter of group %s with %d keys", g.name, n)
+	return nil
+}
+
+// ExportKeyFilter 序列化过滤器，发送给其他节点合并
+func (g *Group) ExportKeyFilter() ([]byte, error) {
+	f := g.keyFilter.Load()
+	if f == nil {
+		return nil, nil
+	}
+	return f.MarshalBinary()
+}
+
+// MergeKeyFilter 合并其他节点导出的过滤器，本节点随后也接受对方已知的key
+func (g *Group) MergeKeyFilter(data []byte) error {
+	f := g.keyFilter.Load()
+	if f == nil {
+		return nil
+	}
+	var other bloom.Filter
+	if err := other.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	return f.Merge(&other)
+}
+
+// keyMayExist 判断key是否可能存在，未启用过滤器时总是返回 true
+func (g *Group) keyMayExist(key string) bool {
+	f := g.keyFilter.Load()
+	if f == nil || g.keyFilterLearning.Load() || f.MayContain(key) {
+		return true
+	}
+	g.stats.filterRejects.Add(1)
+	return false
+}
+
+// rememberKey 将确认存在的key加入过滤器，重建期间同时加入新的过滤器
+func (g *Group) rememberKey(key string) {
+	f := g.keyFilter.Load()
+	if f == nil {
+		return
+	}
+	f.Add(key)
+	if next := g.nextKeyFilter.Load(); next != nil {
+		next.Add(key)
+	}
+	// 读取 nextKeyFilter 之前重建已完成替换
+	if cur := g.keyFilter.Load(); cur != f {
+		cur.Add(key)
+	}
+}
@@ -0,0 +1,154 @@
+package geecache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeyFilter(t *testing.T) {
+	db := newFakeDB()
+	db.values["Tom"] = "630"
+	loads := 0
+	g := NewGroup("key-filter", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return db.Get(key)
+	}))
+	g.SetWriteThrough(db)
+	g.SetKeyFilter(1000, 0.01)
+
+	err := g.RebuildKeyFilter(func(add func(string)) error {
+		for key := range db.values {
+			add(key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 过滤器中不存在的key不回源
+	if _, err := g.Get("random-key"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(random-key) error = %v, want ErrNotFound", err)
+	}
+	if view, err := g.Get("Tom"); err != nil || view.String() != "630" {
+		t.Fatalf("Get(Tom) = %q, %v", view, err)
+	}
+	if loads != 1 || g.GetStats().FilterRejects != 1 {
+		t.Fatalf("expected 1 load and 1 rejection, got %d loads and %+v", loads, g.GetStats())
+	}
+
+	// Set 写入的key加入过滤器
+	g.Set("Jack", []byte("589"))
+	g.mainCache.remove("Jack")
+	if view, err := g.Get("Jack"); err != nil || view.String() != "589" {
+		t.Fatalf("Get(Jack) = %q, %v", view, err)
+	}
+}
+
+func TestLearnKeyFilter(t *testing.T) {
+	loads := 0
+	g := NewGroup("key-filter-learn", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	g.LearnKeyFilter(1000, 0.01)
+
+	// 学习阶段放行所有key，回源成功的key加入过滤器
+	if view, err := g.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("Get(Tom) while learning = %q, %v", view, err)
+	}
+	g.Get("missing")
+	if loads != 2 || g.GetStats().FilterRejects != 0 {
+		t.Fatalf("learning filter should pass keys through, got %d loads and %+v", loads, g.GetStats())
+	}
+
+	g.EnforceKeyFilter()
+	g.mainCache.remove("Tom")
+	if view, err := g.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("Get(Tom) after learning = %q, %v", view, err)
+	}
+	if _, err := g.Get("missing"); !errors.Is(err, ErrNotFound) || loads != 3 {
+		t.Fatalf("Get(missing) = %v after %d loads, want rejection without loading", err, loads)
+	}
+}
+
+func TestRebuildKeyFilterKeepsConcurrentAdds(t *testing.T) {
+	g := NewGroup("key-filter-rebuild", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.LearnKeyFilter(1000, 0.01)
+
+	// 重建期间回源的key不在 source 的列表中，仍需加入新的过滤器
+	err := g.RebuildKeyFilter(func(add func(string)) error {
+		add("listed")
+		if _, err := g.Get("loaded-during-rebuild"); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.mainCache.remove("loaded-during-rebuild")
+	for _, key := range []string{"listed", "loaded-during-rebuild"} {
+		if _, err := g.Get(key); err != nil {
+			t.Fatalf("Get(%s) after rebuild: %v", key, err)
+		}
+	}
+	if _, err := g.Get("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("rebuild should end the learning phase, Get(unknown) error = %v", err)
+	}
+}
+
+func TestKeyFilterOnNonOwner(t *testing.T) {
+	db := newFakeDB()
+	g := NewGroup("key-filter-peer", 2<<10, db)
+	g.SetWriteThrough(db)
+	g.SetKeyFilter(1000, 0.01)
+	owner := &writePeer{writes: make(map[string]string)}
+	g.RegisterPeers(replicaSet{owner})
+
+	// 非拥有者写入的key由拥有者保存，之后在本节点读取时转发给拥有者，不被本节点的过滤器拒绝
+	if err := g.Set("new", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if view, err := g.Get("new"); err != nil || view.String() != "v" {
+		t.Fatalf("Get(new) through the owner = %q, %v", view, err)
+	}
+	if g.GetStats().FilterRejects != 0 {
+		t.Fatalf("non-owner should not filter keys, got %+v", g.GetStats())
+	}
+}
+
+func TestMergeKeyFilter(t *testing.T) {
+	g := NewGroup("key-filter-merge", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.SetKeyFilter(1000, 0.01)
+	if _, err := g.Get("remote-key"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(remote-key) error = %v, want ErrNotFound", err)
+	}
+
+	// 其他节点导出的过滤器
+	other := NewGroup("key-filter-other", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	other.SetKeyFilter(1000, 0.01)
+	other.RebuildKeyFilter(func(add func(string)) error {
+		add("remote-key")
+		return nil
+	})
+	data, err := other.ExportKeyFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MergeKeyFilter(data); err != nil {
+		t.Fatal(err)
+	}
+	if view, err := g.Get("remote-key"); err != nil || view.String() != "remote-key" {
+		t.Fatalf("Get(remote-key) after merge = %q, %v", view, err)
+	}
+}
@@ -0,0 +1,206 @@
+package geecache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 租约表超过该大小时清理过期的租约和删除标记
+const leaseSweepThreshold = 1024
+
+// lease 是写入一个key的许可，一个key同时只有一个有效的租约
+type lease struct {
+	token   uint64
+	expires time.Time
+	done    chan struct{} // 租约结束(写入、作废或过期)时关闭
+}
+
+// leaseTable 实现 memcache 风格的租约：缓存未命中时向第一个调用方发放租约，
+// 只有持有有效租约的调用方可以写入该key，删除会作废未完成的租约，
+// 避免删除之前开始的加载在删除之后写入旧值
+type leaseTable struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	next       uint64
+	leases     map[string]*lease
+	tombstones map[string]time.Time // key最近一次被删除的时间
+	flushed    time.Time            // 整个组最近一次被清空的时间
+}
+
+func newLeaseTable(ttl time.Duration) *leaseTable {
+	return &leaseTable{
+		ttl:        ttl,
+		leases:     make(map[string]*lease),
+		tombstones: make(map[string]time.Time),
+	}
+}
+
+// acquire 为key发放租约。其他调用方持有有效租约时 token 为 0，
+// 返回该租约结束时关闭的通道和过期时间
+func (t *leaseTable) acquire(key string) (token uint64, wait <-chan struct{}, expires time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if l, ok := t.leases[key]; ok {
+		if now.Before(l.expires) {
+			return 0, l.done, l.expires
+		}
+		t.endLocked(key, l)
+	}
+	if len(t.leases) > leaseSweepThreshold {
+		t.sweepLocked(now)
+	}
+	t.next++
+	t.leases[key] = &lease{token: t.next, expires: now.Add(t.ttl), done: make(chan struct{})}
+	return t.next, nil, time.Time{}
+}
+
+// fill 判断持有 token 的调用方能否写入key，成功时租约结束
+func (t *leaseTable) fill(key string, token uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.leases[key]
+	if !ok || l.token != token || time.Now().After(l.expires) {
+		return false
+	}
+	t.endLocked(key, l)
+	return true
+}
+
+// release 释放持有 token 的调用方不再使用的租约，等待的调用方自行回源
+func (t *leaseTable) release(key string, token uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.leases[key]; ok && l.token == token {
+		t.endLocked(key, l)
+	}
+}
+
+// allowUnleased 判断不带租约的写入(如热点备份和副本推送)能否写入key：
+// 删除后 ttl 内拒绝，避免删除之前发出的推送写入旧值。写入后未完成的租约结束
+func (t *leaseTable) allowUnleased(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.flushed) < t.ttl {
+		return false
+	}
+	if deleted, ok := t.tombstones[key]; ok {
+		if time.Since(deleted) < t.ttl {
+			return false
+		}
+		delete(t.tombstones, key)
+	}
+	if l, ok := t.leases[key]; ok {
+		t.endLocked(key, l)
+	}
+	return true
+}
+
+// invalidate 作废key未完成的租约，并记录删除时间
+func (t *leaseTable) invalidate(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.leases[key]; ok {
+		t.endLocked(key, l)
+	}
+	now := time.Now()
+	if len(t.tombstones) > leaseSweepThreshold {
+		t.sweepLocked(now)
+	}
+	t.tombstones[key] = now
+}
+
+// invalidateAll 作废所有未完成的租约，并记录清空时间
+func (t *leaseTable) invalidateAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, l := range t.leases {
+		t.endLocked(key, l)
+	}
+	t.tombstones = make(map[string]time.Time)
+	t.flushed = time.Now()
+}
+
+func (t *leaseTable) endLocked(key string, l *lease) {
+	close(l.done)
+	delete(t.leases, key)
+}
+
+// sweepLocked 清理过期的租约和删除标记
+func (t *leaseTable) sweepLocked(now time.Time) {
+	for key, l := range t.leases {
+		if now.After(l.expires) {
+			t.endLocked(key, l)
+		}
+	}
+	for key, deleted := range t.tombstones {
+		if now.Sub(deleted) >= t.ttl {
+			delete(t.tombstones, key)
+		}
+	}
+}
+
+// SetLeases 启用租约，ttl 为租约的有效期和删除后拒绝不带租约写入的时间。
+// 缓存未命中时只有获得租约的调用方回源并写入缓存，其他调用方等待其写入；
+// 删除会作废未完成的租约，删除之前开始的加载不会在删除之后写入旧值。
+// 0 表示不启用，应在使用组之前调用
+func (g *Group) SetLeases(ttl time.Duration) {
+	if ttl <= 0 {
+		g.leases = nil
+		return
+	}
+	g.leases = newLeaseTable(ttl)
+}
+
+// acquireLease 获取本节点写入key的租约，其他调用方持有租约时等待其写入缓存。
+// 等到数据时 ok 为 true；否则返回获得的租约，token 为 0 表示加载的数据不能写入缓存
+func (g *Group) acquireLease(ctx context.Context, key string) (token uint64, view ByteView, ok bool) {
+	token, wait, expires := g.leases.acquire(key)
+	if token != 0 {
+		return token, ByteView{}, false
+	}
+	g.stats.leaseWaits.Add(1)
+	timer := time.NewTimer(time.Until(expires))
+	defer timer.Stop()
+	select {
+	case <-wait:
+	case <-timer.C:
+	case <-ctx.Done():
+		return 0, ByteView{}, false
+	}
+	if view, ok := g.mainCache.get(key); ok {
+		return 0, view, true
+	}
+	// 持有者加载失败、租约被作废或过期，尝试自己获取租约
+	token, _, _ = g.leases.acquire(key)
+	return token, ByteView{}, false
+}
+
+// fillLease 判断持有 token 的加载结果能否写入缓存
+func (g *Group) fillLease(key string, token uint64) bool {
+	if g.leases == nil {
+		return true
+	}
+	if token != 0 && g.leases.fill(key, token) {
+		return true
+	}
+	g.stats.rejectedSets.Add(1)
+	return false
+}
+
+// acceptPeerSet 判断其他节点推送的数据能否写入缓存，lease 为 0 表示推送不带租约
+func (g *Group) acceptPeerSet(key string, lease uint64) bool {
+	if g.leases == nil {
+		return true
+	}
+	if lease != 0 {
+		return g.fillLease(key, lease)
+	}
+	if g.leases.allowUnleased(key) {
+		return true
+	}
+	g.stats.rejectedSets.Add(1)
+	return false
+}
@@ -0,0 +1,130 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLeaseRejectsStaleSet(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGroup("lease-stale-set", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		<-release
+		return []byte("old"), nil
+	}))
+	g.SetLeases(time.Second)
+
+	done := make(chan ByteView)
+	go func() {
+		view, _ := g.Get("k")
+		done <- view
+	}()
+	// 加载期间数据源被更新并删除缓存，之前开始的加载不能写入旧值
+	time.Sleep(20 * time.Millisecond)
+	g.Remove("k")
+	close(release)
+
+	if view := <-done; view.String() != "old" {
+		t.Fatalf("Get(k) = %q, want the loaded value", view)
+	}
+	if _, ok := g.mainCache.get("k"); ok {
+		t.Fatal("load started before the delete should not fill the cache")
+	}
+	if g.GetStats().RejectedSets != 1 {
+		t.Fatalf("expected 1 rejected set, got %+v", g.GetStats())
+	}
+
+	// 删除后的一段时间内拒绝不带租约的推送
+	if g.setFromPeer("k", ByteView{b: []byte("old")}, 0) {
+		t.Fatal("unleased set right after a delete should be rejected")
+	}
+}
+
+func TestLeaseWaitsForHolder(t *testing.T) {
+	loads := 0
+	g := NewGroup("lease-wait", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("source"), nil
+	}))
+	g.SetLeases(time.Second)
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	// 其他节点的副本读取未命中，获得租约
+	res := &pb.Response{}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "lease-wait", Key: "k", CacheOnly: true, WantLease: true}, res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.GetMiss() || res.GetLease() == 0 {
+		t.Fatalf("expected a miss with a lease, got %v", res)
+	}
+	second := &pb.Response{}
+	getter.Get(context.Background(), &pb.Request{Group: "lease-wait", Key: "k", CacheOnly: true, WantLease: true}, second)
+	if second.GetLease() != 0 {
+		t.Fatal("only one caller should get the lease")
+	}
+
+	// 本节点未命中时等待租约持有者写入，而不是回源
+	done := make(chan ByteView)
+	go func() {
+		view, _ := g.Get("k")
+		done <- view
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// 错误的租约不能写入
+	getter.Set(&pb.Request{Group: "lease-wait", Key: "k", Lease: res.GetLease() + 1}, &pb.Response{Value: []byte("bogus")})
+	if _, ok := g.mainCache.get("k"); ok {
+		t.Fatal("set with a wrong lease should be rejected")
+	}
+	req := &pb.Request{Group: "lease-wait", Key: "k", Lease: res.GetLease()}
+	if err := getter.Set(req, &pb.Response{Value: []byte("repaired")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if view := <-done; view.String() != "repaired" || loads != 0 {
+		t.Fatalf("Get(k) = %q with %d loads, want the lease holder's value", view, loads)
+	}
+	if g.GetStats().LeaseWaits != 1 {
+		t.Fatalf("expected 1 lease wait, got %+v", g.GetStats())
+	}
+}
+
+func TestUnusedLeaseReleased(t *testing.T) {
+	g := NewGroup("lease-release", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	}))
+	g.SetLeases(time.Second)
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	// 不修复副本的读取(如哈希环变化后的交接)不获得租约，本节点的加载不被阻塞
+	res := &pb.Response{}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "lease-release", Key: "a", CacheOnly: true}, res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.GetMiss() || res.GetLease() != 0 {
+		t.Fatalf("expected a miss without a lease, got %v", res)
+	}
+	start := time.Now()
+	if view, err := g.Get("a"); err != nil || view.String() != "source" || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Get(a) = %q, %v after %v", view, err, time.Since(start))
+	}
+
+	// 读取方释放不使用的租约后，本节点立即回源
+	res = &pb.Response{}
+	getter.Get(context.Background(), &pb.Request{Group: "lease-release", Key: "b", CacheOnly: true, WantLease: true}, res)
+	if res.GetLease() == 0 {
+		t.Fatal("expected a lease")
+	}
+	if err := getter.Delete(&pb.Request{Group: "lease-release", Key: "b", Lease: res.GetLease()}); err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if view, err := g.Get("b"); err != nil || view.String() != "source" || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Get(b) = %q, %v after %v", view, err, time.Since(start))
+	}
+}
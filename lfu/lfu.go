@@ -0,0 +1,142 @@
+package lfu
+
+import (
+	"container/list"
+	"geecache/lru"
+)
+
+// Cache is a LFU cache, entries with the same frequency are evicted in LRU
+// order. It is not safe for concurrent access.
+type Cache struct {
+	maxBytes int64
+	nbytes   int64
+	cache    map[string]*list.Element
+	freqs    map[int]*list.List // 访问频率 -> 该频率下的条目，表头为最近访问
+	minFreq  int
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value Value)
+}
+
+type entry struct {
+	key   string
+	value Value
+	freq  int
+}
+
+// Value use Len to count how many bytes it takes
+type Value = lru.Value
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		cache:     make(map[string]*list.Element),
+		freqs:     make(map[int]*list.List),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Add adds a value to the cache.
+func (c *Cache) Add(key string, value Value) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		kv.value = value
+		c.touch(ele)
+	} else {
+		kv := &entry{key: key, value: value, freq: 1}
+		c.cache[key] = c.list(1).PushFront(kv)
+		c.minFreq = 1
+		c.nbytes += int64(len(key)) + int64(value.Len())
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+// Get look ups a key's value
+func (c *Cache) Get(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		c.touch(ele)
+		return ele.Value.(*entry).value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache.
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+	}
+}
+
+// RemoveOldest removes the least frequently used item
+func (c *Cache) RemoveOldest() {
+	if len(c.cache) == 0 {
+		return
+	}
+	if l, ok := c.freqs[c.minFreq]; !ok || l.Len() == 0 {
+		c.resetMinFreq()
+	}
+	if ele := c.freqs[c.minFreq].Back(); ele != nil {
+		c.removeElement(ele)
+	}
+}
+
+// Len the number of cache entries
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Size returns the number of bytes used by the cache
+func (c *Cache) Size() int64 {
+	return c.nbytes
+}
+
+// touch 将条目的访问频率加一，并移动到新频率链表的表头
+func (c *Cache) touch(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	old := c.freqs[kv.freq]
+	old.Remove(ele)
+	if old.Len() == 0 {
+		delete(c.freqs, kv.freq)
+		if c.minFreq == kv.freq {
+			c.minFreq++
+		}
+	}
+	kv.freq++
+	c.cache[kv.key] = c.list(kv.freq).PushFront(kv)
+}
+
+func (c *Cache) list(freq int) *list.List {
+	l, ok := c.freqs[freq]
+	if !ok {
+		l = list.New()
+		c.freqs[freq] = l
+	}
+	return l
+}
+
+// resetMinFreq 在最小频率链表被清空后重新计算最小频率
+func (c *Cache) resetMinFreq() {
+	c.minFreq = 0
+	for freq := range c.freqs {
+		if c.minFreq == 0 || freq < c.minFreq {
+			c.minFreq = freq
+		}
+	}
+}
+
+func (c *Cache) removeElement(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	l := c.freqs[kv.freq]
+	l.Remove(ele)
+	if l.Len() == 0 {
+		delete(c.freqs, kv.freq)
+	}
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
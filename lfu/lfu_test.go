@@ -0,0 +1,72 @@
+package lfu
+
+import (
+	"reflect"
+	"testing"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	lfu := New(int64(0), nil)
+	lfu.Add("key1", String("1234"))
+	if v, ok := lfu.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := lfu.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestRemoveLeastFrequent(t *testing.T) {
+	k1, k2, k3 := "key1", "key2", "k3"
+	v1, v2, v3 := "value1", "value2", "v3"
+	cap := len(k1 + k2 + v1 + v2)
+	lfu := New(int64(cap), nil)
+	lfu.Add(k1, String(v1))
+	lfu.Add(k2, String(v2))
+	// key1 被访问过，频率更高，应淘汰 key2
+	lfu.Get(k1)
+	lfu.Add(k3, String(v3))
+
+	if _, ok := lfu.Get("key2"); ok || lfu.Len() != 2 {
+		t.Fatalf("RemoveOldest key2 failed")
+	}
+	if _, ok := lfu.Get("key1"); !ok {
+		t.Fatalf("frequent key1 should not be evicted")
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	keys := make([]string, 0)
+	callback := func(key string, value Value) {
+		keys = append(keys, key)
+	}
+	lfu := New(int64(10), callback)
+	lfu.Add("key1", String("123456"))
+	lfu.Add("k2", String("k2"))
+	lfu.Add("k3", String("k3"))
+	lfu.Add("k4", String("k4"))
+
+	expect := []string{"key1", "k2"}
+
+	if !reflect.DeepEqual(expect, keys) {
+		t.Fatalf("Call OnEvicted failed, expect keys equals to %s, got %s", expect, keys)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	lfu := New(int64(0), nil)
+	lfu.Add("key1", String("1234"))
+	lfu.Add("key1", String("123"))
+	lfu.Remove("key1")
+
+	if lfu.Len() != 0 || lfu.Size() != 0 {
+		t.Fatalf("Remove key1 failed, len=%d size=%d", lfu.Len(), lfu.Size())
+	}
+	lfu.RemoveOldest()
+}
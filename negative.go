@@ -0,0 +1,58 @@
+package geecache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned by a Getter when the key does not exist in the
+// source of truth. Getters should return it, or an error wrapping it, so
+// that the miss can be cached with Group.SetNegativeTTL and reported to
+// remote peers as a not-found rather than a failure.
+var ErrNotFound = errors.New("geecache: key not found")
+
+// 负缓存占组缓存容量的比例(1/8)，与正常数据分开存放，避免挤占正常数据
+const negativeCacheRatio = 8
+
+func notFoundError(key string) error {
+	return fmt.Errorf("key %s: %w", key, ErrNotFound)
+}
+
+// SetNegativeTTL 缓存数据源中不存在的key，ttl 内再次读取时直接返回 ErrNotFound，
+// 不再回源也不转发给其他节点，防止大量请求不存在的key穿透到数据源。
+// 0 表示不缓存，应在使用组之前调用
+func (g *Group) SetNegativeTTL(ttl time.Duration) {
+	g.negativeTTL = ttl
+	if ttl > 0 && g.negCache == nil {
+		g.negCache = newCache(g.mainCache.cacheBytes/negativeCacheRatio, EvictionLRU)
+		g.negCache.setGeneration(g.Generation())
+	}
+}
+
+// negativeHit 判断key是否在负缓存中
+func (g *Group) negativeHit(key string) bool {
+	if g.negCache == nil {
+		return false
+	}
+	if _, ok := g.negCache.get(key); ok {
+		g.stats.negativeHits.Add(1)
+		return true
+	}
+	return false
+}
+
+// cacheNotFound 在 err 表示key不存在时将其写入负缓存
+func (g *Group) cacheNotFound(key string, err error) {
+	if g.negativeTTL <= 0 || !errors.Is(err, ErrNotFound) {
+		return
+	}
+	g.negCache.add(key, ByteView{e: time.Now().Add(g.negativeTTL)})
+}
+
+// forgetNotFound 在key被写入或删除时清除负缓存
+func (g *Group) forgetNotFound(key string) {
+	if g.negCache != nil {
+		g.negCache.remove(key)
+	}
+}
@@ -0,0 +1,110 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	loads := 0
+	g := NewGroup("negative", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		switch key {
+		case "missing":
+			return nil, fmt.Errorf("%s not exist: %w", key, ErrNotFound)
+		case "broken":
+			return nil, errors.New("db unavailable")
+		}
+		return []byte(key), nil
+	}))
+	g.SetNegativeTTL(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if _, err := g.Get("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+		}
+	}
+	if loads != 1 || g.GetStats().NegativeHits != 2 {
+		t.Fatalf("expected 1 load and 2 negative hits, got %d loads and %+v", loads, g.GetStats())
+	}
+
+	// 其他错误不缓存
+	g.Get("broken")
+	g.Get("broken")
+	if loads != 3 {
+		t.Fatalf("errors other than ErrNotFound should not be cached, loads = %d", loads)
+	}
+
+	// 过期或删除后重新回源
+	time.Sleep(60 * time.Millisecond)
+	g.Get("missing")
+	g.Remove("missing")
+	g.Get("missing")
+	if loads != 5 {
+		t.Fatalf("expected reload after expiry and removal, loads = %d", loads)
+	}
+}
+
+// notFoundPeer 模拟数据源中不存在key的拥有者
+type notFoundPeer struct {
+	fakePeer
+	gets int
+}
+
+func (p *notFoundPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.gets++
+	return notFoundError(in.GetKey())
+}
+
+func TestNegativeCacheFromPeer(t *testing.T) {
+	loads := 0
+	g := NewGroup("negative-peer", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}))
+	g.SetNegativeTTL(time.Minute)
+	owner := &notFoundPeer{}
+	g.RegisterPeers(replicaSet{owner})
+
+	// 拥有者确认不存在时不回源，并在本节点缓存该结果
+	for i := 0; i < 2; i++ {
+		if _, err := g.Get("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+		}
+	}
+	if loads != 0 || owner.gets != 1 {
+		t.Fatalf("expected one request to the owner and no load, got %d gets and %d loads", owner.gets, loads)
+	}
+}
+
+func TestHTTPGetterNotFound(t *testing.T) {
+	NewGroup("negative-http", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	err := getter.Get(context.Background(), &pb.Request{Group: "negative-http", Key: "k"}, &pb.Response{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get error = %v, want ErrNotFound", err)
+	}
+	// 不存在的组不是 ErrNotFound
+	err = getter.Get(context.Background(), &pb.Request{Group: "no-such-group", Key: "k"}, &pb.Response{})
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get from missing group error = %v, want a non not-found error", err)
+	}
+
+	res := &pb.BatchResponse{}
+	if err := getter.GetMany(context.Background(), &pb.BatchRequest{Group: "negative-http", Keys: []string{"k"}}, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || !res.Items[0].GetNotFound() {
+		t.Fatalf("batch item should be marked not found, got %v", res.Items)
+	}
+}
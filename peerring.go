@@ -0,0 +1,273 @@
+package geecache
+
+import (
+	"geecache/consistenthash"
+	"log"
+	"sync"
+	"time"
+)
+
+// ringGetter 是哈希环上远程节点的客户端，close 释放到该节点的连接
+type ringGetter interface {
+	PeerGetter
+	close()
+}
+
+// peerRing 维护一致性哈希环、各节点的客户端以及最近一次哈希环变化，
+// HTTPPool 和 GRPCPool 通过嵌入它实现节点选择，只需提供创建客户端的方式
+type peerRing[G ringGetter] struct {
+	// this peer's address
+	self    string
+	mu      sync.Mutex // guards peers, getters and handoff
+	peers   *consistenthash.Map
+	getters map[string]G // keyed by peer address
+
+	handoff      *ringHandoff  // 最近一次哈希环变化
+	handoffGrace time.Duration // 哈希环变化后查询之前拥有者的宽限期
+
+	dial func(addr string) (G, error)          // 为新节点创建客户端
+	logf func(format string, v ...interface{}) // 带节点地址前缀的日志
+}
+
+func newPeerRing[G ringGetter](self string, dial func(addr string) (G, error), logf func(format string, v ...interface{})) peerRing[G] {
+	return peerRing[G]{
+		self:         self,
+		handoffGrace: defaultHandoffGrace,
+		dial:         dial,
+		logf:         logf,
+	}
+}
+
+// Set updates the pool's list of peers.
+// 已存在节点的客户端会被复用，被移除节点的客户端在宽限期结束后关闭，
+// 归属发生变化的key在宽限期内未命中时，会先从之前的拥有者读取。
+func (r *peerRing[G]) Set(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oldPeers := r.peers
+	r.peers = consistenthash.New(defaultReplicas, nil)
+	r.peers.Add(peers...)
+
+	getters := make(map[string]G, len(peers))
+	for _, peer := range peers {
+		if getter, ok := r.getters[peer]; ok {
+			getters[peer] = getter
+			continue
+		}
+		getter, err := r.dial(peer)
+		if err != nil {
+			r.logf("Failed to dial peer %s: %v", peer, err)
+			continue
+		}
+		getters[peer] = getter
+	}
+	r.recordHandoff(oldPeers, r.getters)
+	for peer, getter := range r.getters {
+		if _, ok := getters[peer]; !ok {
+			r.closeLater(getter)
+		}
+	}
+	r.getters = getters
+}
+
+// AddPeers adds peers to the pool. 只为新节点创建客户端，已有节点的客户端保持不变
+func (r *peerRing[G]) AddPeers(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.peers == nil {
+		r.peers = consistenthash.New(defaultReplicas, nil)
+	}
+	if r.getters == nil {
+		r.getters = make(map[string]G, len(peers))
+	}
+
+	var added []string
+	for _, peer := range peers {
+		if _, ok := r.getters[peer]; ok {
+			continue
+		}
+		getter, err := r.dial(peer)
+		if err != nil {
+			r.logf("Failed to dial peer %s: %v", peer, err)
+			continue
+		}
+		r.getters[peer] = getter
+		added = append(added, peer)
+	}
+	if len(added) == 0 {
+		return
+	}
+	oldPeers := r.cloneRing()
+	r.peers.Add(added...)
+	r.recordHandoff(oldPeers, r.getters)
+	r.logf("Added peers %v", added)
+}
+
+// RemovePeers removes peers from the pool. 被移除节点的客户端在宽限期结束后关闭
+func (r *peerRing[G]) RemovePeers(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed []string
+	for _, peer := range peers {
+		if _, ok := r.getters[peer]; ok {
+			removed = append(removed, peer)
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	oldPeers := r.cloneRing()
+	r.peers.Remove(removed...)
+	// 宽限期内仍会从被移除的节点读取，handoff 中保留了它们的客户端
+	r.recordHandoff(oldPeers, r.getters)
+	for _, peer := range removed {
+		r.closeLater(r.getters[peer])
+		delete(r.getters, peer)
+	}
+	r.logf("Removed peers %v", removed)
+}
+
+// closeLater 宽限期内仍可能从被移除的节点读取，结束后再关闭客户端。调用方需持有锁
+func (r *peerRing[G]) closeLater(getter G) {
+	if r.handoffGrace > 0 {
+		time.AfterFunc(r.handoffGrace, getter.close)
+	} else {
+		getter.close()
+	}
+}
+
+// closeAll 清空所有节点并立即关闭它们的客户端
+func (r *peerRing[G]) closeAll() {
+	r.mu.Lock()
+	getters := r.getters
+	r.getters = nil
+	r.mu.Unlock()
+
+	for _, getter := range getters {
+		getter.close()
+	}
+}
+
+// cloneRing 复制当前哈希环用于记录变化，不需要记录时返回 nil。调用方需持有锁
+func (r *peerRing[G]) cloneRing() *consistenthash.Map {
+	if r.peers == nil || r.handoffGrace <= 0 {
+		return nil
+	}
+	return r.peers.Clone()
+}
+
+// recordHandoff 记录哈希环从 oldPeers 变为当前哈希环时归属发生变化的区间，调用方需持有锁
+func (r *peerRing[G]) recordHandoff(oldPeers *consistenthash.Map, oldGetters map[string]G) {
+	if oldPeers == nil || r.handoffGrace <= 0 {
+		return
+	}
+	prev := make(map[string]PeerGetter, len(oldGetters))
+	for addr, getter := range oldGetters {
+		prev[addr] = getter
+	}
+	// 节点列表未变化时保留上一次变化的记录
+	if handoff := newRingHandoff(oldPeers, r.peers, prev, r.handoffGrace); handoff != nil {
+		r.handoff = handoff
+		r.logf("Peer ring changed, %d ranges moved", len(handoff.moves))
+	}
+}
+
+// SetHandoffGrace 设置哈希环变化后查询之前拥有者的宽限期，0 表示不查询
+func (r *peerRing[G]) SetHandoffGrace(grace time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handoffGrace = grace
+}
+
+// PickPreviousOwner picks the peer which owned key before the last ring change
+func (r *peerRing[G]) PickPreviousOwner(key string) (PeerGetter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.handoff.previousOwner(key, r.self)
+}
+
+// PickPeer picks a peer according to key
+func (r *peerRing[G]) PickPeer(key string) (PeerGetter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 确保r.peers已经初始化
+	if r.peers == nil {
+		log.Println("PickPeer() called but peers not properly initialized")
+		return nil, false
+	}
+
+	// 通过一致性哈希(节点负荷平衡)找到该值(应该)存储的节点，是自己时由本节点负责
+	if peer := r.peers.Get(key); peer != "" && peer != r.self {
+		if getter, ok := r.getters[peer]; ok {
+			r.logf("Pick peer %s", peer)
+			return getter, true
+		}
+	}
+	return nil, false
+}
+
+// PickPeers picks multiple peers for hot spot data backup.
+// 主节点优先，其余节点按哈希环顺时针选取，同一key总是选中相同的节点
+func (r *peerRing[G]) PickPeers(key string, count int) ([]PeerGetter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 确保r.peers已经初始化
+	if r.peers == nil {
+		log.Println("PickPeers() called but peers not properly initialized")
+		return nil, false
+	}
+
+	// 主节点是自己时由本节点负责，无需备份
+	nodes := r.peers.GetN(key, count+1)
+	if len(nodes) == 0 || nodes[0] == r.self {
+		return nil, false
+	}
+
+	peers := make([]PeerGetter, 0, count)
+	for _, node := range nodes {
+		if getter, ok := r.getters[node]; ok && node != r.self && len(peers) < count {
+			peers = append(peers, getter)
+		}
+	}
+
+	r.logf("Pick %d peers for hot spot data", len(peers))
+	return peers, len(peers) > 0
+}
+
+// ListPeers returns all remote peers
+func (r *peerRing[G]) ListPeers() []PeerGetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peers := make([]PeerGetter, 0, len(r.getters))
+	for node, getter := range r.getters {
+		if node != r.self {
+			peers = append(peers, getter)
+		}
+	}
+	return peers
+}
+
+// PickReplicas picks the n nodes responsible for key clockwise on the ring,
+// this node is represented by nil
+func (r *peerRing[G]) PickReplicas(key string, n int) []PeerGetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.peers == nil {
+		log.Println("PickReplicas() called but peers not properly initialized")
+		return nil
+	}
+
+	var replicas []PeerGetter
+	for _, node := range r.peers.GetN(key, n) {
+		if node == r.self {
+			replicas = append(replicas, nil)
+		} else if getter, ok := r.getters[node]; ok {
+			replicas = append(replicas, getter)
+		}
+	}
+	return replicas
+}
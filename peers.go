@@ -1,6 +1,9 @@
 package geecache
 
-import pb "geecache/geecachepb"
+import (
+	"context"
+	pb "geecache/geecachepb"
+)
 
 // PeerPicker is the interface that must be implemented to locate
 // the peer that owns a specific key.
@@ -10,9 +13,22 @@ type PeerPicker interface {
 	PickPeers(key string, count int) ([]PeerGetter, bool)
 }
 
+// ReplicaPicker is implemented by PeerPickers that can place a key on
+// multiple nodes. PickReplicas returns the n nodes responsible for key in
+// ring order, this node is represented by a nil PeerGetter.
+type ReplicaPicker interface {
+	PickReplicas(key string, n int) []PeerGetter
+}
+
 // PeerGetter is the interface that must be implemented by a peer.
 type PeerGetter interface {
-	Get(in *pb.Request, out *pb.Response) error
+	// Get loads a value for a key from remote peer, the deadline and
+	// cancellation of ctx are propagated to the remote request
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
 	// Set stores a value for a key in remote peer
 	Set(in *pb.Request, out *pb.Response) error
+	// Delete removes a key from remote peer
+	Delete(in *pb.Request) error
+	// GetMany loads values for multiple keys from remote peer in one request
+	GetMany(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error
 }
@@ -0,0 +1,88 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// SetStaleWhileRevalidate 设置过期数据的宽限期：过期不超过 grace 的数据直接返回给调用方，
+// 同时在后台通过 singleflight 刷新，调用方不必等待回源。0 表示不启用，应在使用组之前调用
+func (g *Group) SetStaleWhileRevalidate(grace time.Duration) {
+	g.staleGrace = grace
+}
+
+// SetEarlyRefresh 启用概率提前刷新(XFetch)：命中的数据临近过期时，以随剩余时间减少而
+// 增大的概率在后台提前刷新，避免热点key过期时集中回源。beta 越大越早刷新，通常为 1，
+// 0 表示不启用。回源耗时取最近加载耗时的滑动平均，应在使用组之前调用
+func (g *Group) SetEarlyRefresh(beta float64) {
+	g.earlyBeta = beta
+}
+
+// lookupCache 查询一级缓存，处理宽限期内的过期数据和提前刷新
+func (g *Group) lookupCache(key string) (ByteView, bool) {
+	view, stale, ok := g.mainCache.getStale(key, g.staleGrace)
+	if !ok {
+		return ByteView{}, false
+	}
+	if stale {
+		g.stats.staleHits.Add(1)
+		g.refreshInBackground(key)
+	} else if g.shouldRefreshEarly(view) {
+		g.stats.earlyRefreshes.Add(1)
+		g.refreshInBackground(key)
+	}
+	return view, true
+}
+
+// shouldRefreshEarly 按 XFetch 算法判断是否提前刷新：
+// now - delta * beta * ln(rand) >= expire，其中 delta 为回源耗时
+func (g *Group) shouldRefreshEarly(view ByteView) bool {
+	if g.earlyBeta <= 0 || view.e.IsZero() {
+		return false
+	}
+	delta := float64(g.loadNanos.Load())
+	if delta == 0 {
+		return false
+	}
+	gap := time.Duration(-delta * g.earlyBeta * math.Log(rand.Float64()))
+	return !time.Now().Add(gap).Before(view.e)
+}
+
+// refreshInBackground 在后台重新加载key，同一key同时只有一个刷新，
+// 与未命中的调用方共用 singleflight
+func (g *Group) refreshInBackground(key string) {
+	if _, loaded := g.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	gen := g.Generation()
+	go func() {
+		defer g.refreshing.Delete(key)
+		value, err := g.load(context.Background(), key)
+		switch {
+		case g.Generation() != gen:
+			// 刷新期间组的代数发生变化，缓存中已没有旧值
+		case err == nil:
+			// 从其他节点获取的数据也更新到本节点缓存中的旧值
+			g.setFromPeer(key, value, 0)
+		case errors.Is(err, ErrNotFound):
+			g.mainCache.remove(key)
+		default:
+			log.Printf("[GeeCache] Failed to refresh %s: %v", key, err)
+		}
+	}()
+}
+
+// observeLoad 记录一次加载耗时，用滑动平均估计回源耗时
+func (g *Group) observeLoad(start time.Time) {
+	d := int64(time.Since(start))
+	old := g.loadNanos.Load()
+	if old == 0 {
+		g.loadNanos.Store(d)
+		return
+	}
+	g.loadNanos.Store(old + (d-old)/8)
+}
@@ -0,0 +1,93 @@
+package geecache
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newVersionedGroup 每次回源返回递增的版本号
+func newVersionedGroup(name string, loads *atomic.Int64, delay time.Duration) *Group {
+	return NewGroup(name, 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		time.Sleep(delay)
+		return []byte(strconv.FormatInt(loads.Add(1), 10)), nil
+	}))
+}
+
+// waitCached 等待后台刷新写入缓存
+func waitCached(t *testing.T, g *Group, key, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if view, ok := g.mainCache.get(key); ok && view.String() == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s=%s in cache", key, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var loads atomic.Int64
+	g := newVersionedGroup("stale-while-revalidate", &loads, 50*time.Millisecond)
+	g.SetDefaultTTL(20 * time.Millisecond)
+	g.SetStaleWhileRevalidate(time.Second)
+
+	g.Get("k")
+	time.Sleep(30 * time.Millisecond)
+
+	// 宽限期内的过期数据立即返回，并发的调用方只触发一次刷新
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if view, err := g.Get("k"); err != nil || view.String() != "1" {
+				t.Errorf("Get(k) = %q, %v, want the stale value", view, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("stale reads blocked for %v", elapsed)
+	}
+	waitCached(t, g, "k", "2")
+	if loads.Load() != 2 || g.GetStats().StaleHits != 10 {
+		t.Fatalf("expected 2 loads and 10 stale hits, got %d loads and %+v", loads.Load(), g.GetStats())
+	}
+
+	// 超过宽限期后同步回源
+	g.SetStaleWhileRevalidate(time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if view, _ := g.Get("k"); view.String() != "3" {
+		t.Fatalf("Get(k) = %q after the grace period, want a fresh value", view)
+	}
+}
+
+func TestEarlyRefresh(t *testing.T) {
+	var loads atomic.Int64
+	g := newVersionedGroup("early-refresh", &loads, 0)
+	g.SetDefaultTTL(time.Minute)
+
+	g.Get("k")
+	// 回源耗时远大于剩余时间时几乎必然提前刷新
+	g.loadNanos.Store(int64(time.Hour))
+	g.Get("k")
+	if loads.Load() != 1 {
+		t.Fatal("early refresh should be disabled by default")
+	}
+
+	g.SetEarlyRefresh(1)
+	if view, _ := g.Get("k"); view.String() != "1" {
+		t.Fatalf("Get(k) = %q, want the cached value while refreshing", view)
+	}
+	waitCached(t, g, "k", "2")
+	if g.GetStats().EarlyRefreshes == 0 {
+		t.Fatalf("expected an early refresh, got %+v", g.GetStats())
+	}
+}
@@ -0,0 +1,470 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// consulServiceName 所有缓存节点在 Consul 中注册为同一个服务，通过元数据区分
+	consulServiceName = "geecache"
+	// 元数据中保存服务键和地址，服务键和地址可能包含 Consul 不允许的字符
+	consulMetaKey  = "geecache_key"
+	consulMetaAddr = "geecache_addr"
+	// Consul 允许的最小自动注销时间
+	consulDeregisterAfter = "1m"
+	// 阻塞查询的最长等待时间
+	consulWatchWait = 30 * time.Second
+)
+
+// consulClient 是 Consul HTTP API 的简单客户端，请求失败时依次尝试其他地址
+type consulClient struct {
+	endpoints []string
+	http      *http.Client
+}
+
+func newConsulClient(endpoints []string) (*consulClient, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no consul endpoints")
+	}
+	c := &consulClient{http: &http.Client{}}
+	for _, ep := range endpoints {
+		if !strings.HasPrefix(ep, "http://") && !strings.HasPrefix(ep, "https://") {
+			ep = "http://" + ep
+		}
+		c.endpoints = append(c.endpoints, strings.TrimSuffix(ep, "/"))
+	}
+	return c, nil
+}
+
+// do 发送请求并解析 JSON 响应，返回响应头中的 X-Consul-Index
+func (c *consulClient) do(ctx context.Context, method, path string, in, out interface{}) (uint64, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return 0, err
+		}
+	}
+
+	var lastErr error
+	for _, ep := range c.endpoints {
+		req, err := http.NewRequestWithContext(ctx, method, ep+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, &consulError{status: resp.StatusCode, msg: strings.TrimSpace(string(data))}
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				return 0, fmt.Errorf("decode consul response: %v", err)
+			}
+		}
+		index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+		return index, nil
+	}
+	return 0, lastErr
+}
+
+// consulError 是 Consul 返回的非 200 响应
+type consulError struct {
+	status int
+	msg    string
+}
+
+func (e *consulError) Error() string {
+	return fmt.Sprintf("consul returned %d: %s", e.status, e.msg)
+}
+
+// consulServiceID 将服务键转换为 Consul 服务ID，只包含字母、数字和连字符。
+// 其余字节转义为 "-" 加两位十六进制，不同的服务键对应不同的ID
+func consulServiceID(serviceKey string) string {
+	var b strings.Builder
+	b.WriteString(consulServiceName)
+	for i := 0; i < len(serviceKey); i++ {
+		c := serviceKey[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "-%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// consulService 对应 Consul 注册接口的请求体
+type consulService struct {
+	ID      string
+	Name    string
+	Address string            `json:",omitempty"`
+	Port    int               `json:",omitempty"`
+	Meta    map[string]string `json:",omitempty"`
+	Check   *consulCheck      `json:",omitempty"`
+}
+
+type consulCheck struct {
+	CheckID                        string
+	TTL                            string
+	Status                         string
+	DeregisterCriticalServiceAfter string
+}
+
+// consulHealthEntry 对应健康查询接口返回的条目
+type consulHealthEntry struct {
+	Service consulService
+}
+
+// ConsulRegistry 实现基于 Consul 的服务注册，通过 TTL 健康检查保持服务存活
+type ConsulRegistry struct {
+	client     *consulClient
+	serviceTTL int64          // 健康检查的存活时间(秒)
+	serviceKey string         // 服务键名
+	service    *consulService // 注册的服务
+	stopSignal chan struct{}  // 停止信号
+	registered bool           // 是否已注册
+	mu         sync.Mutex     // 互斥锁
+}
+
+// NewConsulRegistry 创建一个新的 Consul 注册中心客户端，endpoints 为 Consul agent 的地址
+func NewConsulRegistry(endpoints []string, serviceTTL int64) (*ConsulRegistry, error) {
+	client, err := newConsulClient(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if serviceTTL <= 0 {
+		serviceTTL = 10
+	}
+	return &ConsulRegistry{
+		client:     client,
+		serviceTTL: serviceTTL,
+	}, nil
+}
+
+// Register 注册服务到 Consul
+func (c *ConsulRegistry) Register(serviceKey string, info ServiceInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.registered {
+		return fmt.Errorf("service already registered")
+	}
+
+	meta := make(map[string]string, len(info.Metadata)+2)
+	maps.Copy(meta, info.Metadata)
+	meta[consulMetaKey] = serviceKey
+	meta[consulMetaAddr] = info.Addr
+
+	id := consulServiceID(serviceKey)
+	service := &consulService{
+		ID:   id,
+		Name: consulServiceName,
+		Meta: meta,
+		Check: &consulCheck{
+			CheckID:                        "service:" + id,
+			TTL:                            fmt.Sprintf("%ds", c.serviceTTL),
+			Status:                         "passing",
+			DeregisterCriticalServiceAfter: consulDeregisterAfter,
+		},
+	}
+	service.Address, service.Port = splitHostPort(info.Addr)
+
+	if err := c.register(service); err != nil {
+		return err
+	}
+
+	c.serviceKey = serviceKey
+	c.service = service
+	c.stopSignal = make(chan struct{})
+	c.registered = true
+
+	// 启动定期上报健康状态的goroutine
+	go c.keepAlive(c.stopSignal)
+
+	log.Printf("Service registered: %s -> %s\n", serviceKey, info.Addr)
+	return nil
+}
+
+func (c *ConsulRegistry) register(service *consulService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.client.do(ctx, http.MethodPut, "/v1/agent/service/register", service, nil); err != nil {
+		return fmt.Errorf("register service error: %v", err)
+	}
+	return nil
+}
+
+// keepAlive 在 TTL 内定期上报健康状态。Consul agent 重启后会丢失服务，此时重新注册
+func (c *ConsulRegistry) keepAlive(stop chan struct{}) {
+	ticker := time.NewTicker(time.Duration(c.serviceTTL) * time.Second / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			service := c.service
+			c.mu.Unlock()
+			if service == nil {
+				return
+			}
+
+			err := c.pass(service.Check.CheckID)
+			if cerr, ok := err.(*consulError); ok && cerr.status == http.StatusNotFound {
+				log.Println("Health check not found, trying to re-register...")
+				err = c.register(service)
+			}
+			if err != nil {
+				log.Printf("Update health check failed: %v\n", err)
+			}
+		}
+	}
+}
+
+func (c *ConsulRegistry) pass(checkID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := c.client.do(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape(checkID), nil, nil)
+	return err
+}
+
+// Deregister 从 Consul 注销服务
+func (c *ConsulRegistry) Deregister() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.registered {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.client.do(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(c.service.ID), nil, nil); err != nil {
+		// 服务仍然注册，保持健康检查上报，之后可以重试注销
+		return fmt.Errorf("deregister service error: %v", err)
+	}
+
+	close(c.stopSignal)
+	c.registered = false
+	c.service = nil
+	log.Printf("Service deregistered: %s\n", c.serviceKey)
+	return nil
+}
+
+// Close 注销服务并关闭客户端
+func (c *ConsulRegistry) Close() error {
+	return c.Deregister()
+}
+
+// ConsulDiscovery 实现基于 Consul 的服务发现，通过阻塞查询监听健康服务的变化
+type ConsulDiscovery struct {
+	client   *consulClient
+	services map[string]ServiceInfo // 服务缓存
+	index    uint64                 // 最近一次查询的 X-Consul-Index
+	prefix   string                 // 服务前缀，用于过滤服务
+	mu       sync.RWMutex           // 读写锁
+	cancel   context.CancelFunc     // 停止监听
+	subs     subscribers            // 服务变化的订阅者
+}
+
+// NewConsulDiscovery 创建一个新的 Consul 服务发现客户端
+func NewConsulDiscovery(endpoints []string, prefix string) (*ConsulDiscovery, error) {
+	client, err := newConsulClient(endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sd := &ConsulDiscovery{
+		client:   client,
+		services: make(map[string]ServiceInfo),
+		prefix:   prefix,
+		cancel:   cancel,
+	}
+
+	// 初始化服务列表
+	initCtx, initCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer initCancel()
+	if err := sd.fetchServices(initCtx, 0); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go sd.watchServices(ctx)
+	return sd, nil
+}
+
+// fetchServices 查询健康的服务，index 非0时为阻塞查询，直到服务变化或等待超时才返回
+func (sd *ConsulDiscovery) fetchServices(ctx context.Context, index uint64) error {
+	path := "/v1/health/service/" + consulServiceName + "?passing=true"
+	if index > 0 {
+		path += fmt.Sprintf("&index=%d&wait=%ds", index, int(consulWatchWait.Seconds()))
+	}
+
+	var entries []consulHealthEntry
+	newIndex, err := sd.client.do(ctx, http.MethodGet, path, nil, &entries)
+	if err != nil {
+		return err
+	}
+
+	services := make(map[string]ServiceInfo, len(entries))
+	for _, entry := range entries {
+		key := entry.Service.Meta[consulMetaKey]
+		if key == "" || !strings.HasPrefix(key, sd.prefix) {
+			continue
+		}
+		meta := make(map[string]string, len(entry.Service.Meta))
+		for k, v := range entry.Service.Meta {
+			if k != consulMetaKey && k != consulMetaAddr {
+				meta[k] = v
+			}
+		}
+		services[key] = ServiceInfo{
+			Addr:     entry.Service.Meta[consulMetaAddr],
+			Metadata: meta,
+		}
+	}
+
+	sd.mu.Lock()
+	for key := range sd.services {
+		if _, ok := services[key]; !ok {
+			log.Printf("Service removed: %s\n", key)
+		}
+	}
+	for key, service := range services {
+		if _, ok := sd.services[key]; !ok {
+			log.Printf("Service added/updated: %s -> %s\n", key, service.Addr)
+		}
+	}
+	sd.subs.diffServices(sd.services, services)
+	sd.services = services
+	// 索引回退时(例如 Consul 重启)从头开始阻塞查询
+	if newIndex < sd.index {
+		newIndex = 0
+	}
+	sd.index = newIndex
+	sd.mu.Unlock()
+	return nil
+}
+
+// watchServices 使用阻塞查询持续监听服务变化
+func (sd *ConsulDiscovery) watchServices(ctx context.Context) {
+	for {
+		sd.mu.RLock()
+		index := sd.index
+		sd.mu.RUnlock()
+		if index == 0 {
+			index = 1
+		}
+
+		err := sd.fetchServices(ctx, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Watch consul services error: %v\n", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// GetServices 获取所有服务
+func (sd *ConsulDiscovery) GetServices() map[string]ServiceInfo {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	services := make(map[string]ServiceInfo, len(sd.services))
+	maps.Copy(services, sd.services)
+	return services
+}
+
+// GetService 获取指定服务
+func (sd *ConsulDiscovery) GetService(serviceKey string) (ServiceInfo, bool) {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	service, ok := sd.services[serviceKey]
+	return service, ok
+}
+
+// GetServicesByPrefix 获取指定前缀的服务
+func (sd *ConsulDiscovery) GetServicesByPrefix(prefix string) map[string]ServiceInfo {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	services := make(map[string]ServiceInfo)
+	for k, v := range sd.services {
+		if strings.HasPrefix(k, prefix) {
+			services[k] = v
+		}
+	}
+	return services
+}
+
+// Subscribe 订阅指定前缀的服务变化
+func (sd *ConsulDiscovery) Subscribe(prefix string, fn func(Event)) func() {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	snapshot := make(map[string]ServiceInfo)
+	for k, v := range sd.services {
+		if strings.HasPrefix(k, prefix) {
+			snapshot[k] = v
+		}
+	}
+	return sd.subs.add(prefix, fn, snapshot)
+}
+
+// Close 停止监听服务变化
+func (sd *ConsulDiscovery) Close() error {
+	sd.cancel()
+	sd.subs.closeAll()
+	return nil
+}
+
+// splitHostPort 尽量从服务地址(可能带协议)中解析出主机和端口，仅用于在 Consul 中展示
+func splitHostPort(addr string) (string, int) {
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		addr = u.Host
+	}
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return addr, 0
+	}
+	port, err := strconv.Atoi(addr[i+1:])
+	if err != nil {
+		return addr, 0
+	}
+	return addr[:i], port
+}
@@ -0,0 +1,284 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul 模拟 Consul agent 的服务注册、TTL 健康检查和阻塞查询接口
+type fakeConsul struct {
+	mu       sync.Mutex
+	index    uint64
+	changed  chan struct{} // 每次变化时关闭并替换，用于唤醒阻塞查询
+	services map[string]consulService
+	passing  map[string]bool // checkID -> 是否健康
+	passes   int
+
+	failDeregister int // 之后的若干次注销请求返回错误
+}
+
+func newFakeConsul() (*fakeConsul, *httptest.Server) {
+	f := &fakeConsul{
+		index:    1,
+		changed:  make(chan struct{}),
+		services: make(map[string]consulService),
+		passing:  make(map[string]bool),
+	}
+	return f, httptest.NewServer(f)
+}
+
+// bump 递增索引并唤醒阻塞查询，调用方需持有锁
+func (f *fakeConsul) bump() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// restart 模拟 agent 重启后丢失所有本地注册的服务
+func (f *fakeConsul) restart() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.services = make(map[string]consulService)
+	f.passing = make(map[string]bool)
+	f.bump()
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPut && path == "/v1/agent/service/register":
+		var s consulService
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.services[s.ID] = s
+		f.passing[s.Check.CheckID] = s.Check.Status == "passing"
+		f.bump()
+		f.mu.Unlock()
+
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/v1/agent/service/deregister/"):
+		id := strings.TrimPrefix(path, "/v1/agent/service/deregister/")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.failDeregister > 0 {
+			f.failDeregister--
+			http.Error(w, "agent unavailable", http.StatusInternalServerError)
+			return
+		}
+		s, ok := f.services[id]
+		if !ok {
+			http.Error(w, "unknown service", http.StatusNotFound)
+			return
+		}
+		delete(f.services, id)
+		delete(f.passing, s.Check.CheckID)
+		f.bump()
+
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/v1/agent/check/pass/"):
+		id := strings.TrimPrefix(path, "/v1/agent/check/pass/")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		passing, ok := f.passing[id]
+		if !ok {
+			http.Error(w, "unknown check", http.StatusNotFound)
+			return
+		}
+		f.passes++
+		if !passing {
+			f.passing[id] = true
+			f.bump()
+		}
+
+	case r.Method == http.MethodGet && path == "/v1/health/service/"+consulServiceName:
+		index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		f.mu.Lock()
+		if index > 0 && index == f.index {
+			// 阻塞直到服务变化或等待超时
+			changed := f.changed
+			f.mu.Unlock()
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+			f.mu.Lock()
+		}
+		var entries []consulHealthEntry
+		for _, s := range f.services {
+			if r.URL.Query().Get("passing") == "true" && !f.passing[s.Check.CheckID] {
+				continue
+			}
+			entries = append(entries, consulHealthEntry{Service: s})
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(entries)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// waitFor 等待异步的服务变化
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConsulRegistryAndDiscovery(t *testing.T) {
+	_, srv := newFakeConsul()
+	defer srv.Close()
+	endpoints := []string{srv.URL}
+
+	reg, err := NewRegistry(RegistryTypeConsul, endpoints, testTTL)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	defer reg.Close()
+	metadata := map[string]string{"weight": "10"}
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr, Metadata: metadata}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	disc, err := NewDiscovery(RegistryTypeConsul, endpoints, testPrefix)
+	if err != nil {
+		t.Fatalf("Failed to create discovery: %v", err)
+	}
+	defer disc.Close()
+
+	service, ok := disc.GetService(testKey)
+	if !ok || service.Addr != testAddr || service.Metadata["weight"] != "10" || len(service.Metadata) != 1 {
+		t.Fatalf("GetService(%s) = %+v, %v", testKey, service, ok)
+	}
+
+	// 通过阻塞查询发现新注册的服务
+	otherKey := testPrefix + "other-service"
+	other, _ := NewConsulRegistry(endpoints, testTTL)
+	if err := other.Register(otherKey, ServiceInfo{Addr: "http://localhost:8002"}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	waitFor(t, "new service", func() bool {
+		return len(disc.GetServicesByPrefix(testPrefix)) == 2
+	})
+
+	// 其他前缀的服务不会被发现
+	foreign, _ := NewConsulRegistry(endpoints, testTTL)
+	if err := foreign.Register("/services/other/node", ServiceInfo{Addr: "x"}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	defer foreign.Close()
+
+	if err := other.Deregister(); err != nil {
+		t.Fatalf("Failed to deregister service: %v", err)
+	}
+	waitFor(t, "service removal", func() bool {
+		_, ok := disc.GetService(otherKey)
+		return !ok
+	})
+	if services := disc.GetServices(); len(services) != 1 {
+		t.Fatalf("expected 1 service, got %v", services)
+	}
+}
+
+func TestConsulReRegister(t *testing.T) {
+	fake, srv := newFakeConsul()
+	defer srv.Close()
+
+	reg, err := NewConsulRegistry([]string{srv.URL}, 1)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	defer reg.Close()
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	// TTL 内定期上报健康状态
+	waitFor(t, "health check pass", func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return fake.passes > 0
+	})
+
+	// agent 丢失服务后重新注册
+	fake.restart()
+	waitFor(t, "re-registration", func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(fake.services) == 1
+	})
+}
+
+func TestConsulDeregisterRetry(t *testing.T) {
+	fake, srv := newFakeConsul()
+	defer srv.Close()
+
+	reg, err := NewConsulRegistry([]string{srv.URL}, testTTL)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	// 注销失败时服务仍然注册，重试注销和关闭不会 panic
+	fake.mu.Lock()
+	fake.failDeregister = 1
+	fake.mu.Unlock()
+	if err := reg.Deregister(); err == nil {
+		t.Fatal("expected the first deregister to fail")
+	}
+	if err := reg.Deregister(); err != nil {
+		t.Fatalf("Failed to retry deregister: %v", err)
+	}
+	if err := reg.Close(); err != nil {
+		t.Fatalf("Close after deregister: %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.services) != 0 {
+		t.Fatalf("service should be deregistered, got %v", fake.services)
+	}
+}
+
+func TestConsulServiceID(t *testing.T) {
+	// 只有分隔符不同的服务键不能对应同一个ID
+	a := consulServiceID(testPrefix + "10.0.0.1:80")
+	b := consulServiceID(testPrefix + "10-0-0-1:80")
+	if a == b {
+		t.Fatalf("distinct keys map to the same service ID %s", a)
+	}
+	for _, r := range a + b {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			t.Fatalf("service ID contains %q", r)
+		}
+	}
+}
+
+func TestNewRegistryType(t *testing.T) {
+	if _, err := NewRegistry("unknown", testEndpoints, testTTL); err == nil {
+		t.Fatal("expected error for unknown registry type")
+	}
+	if _, err := NewDiscovery("unknown", testEndpoints, testPrefix); err == nil {
+		t.Fatal("expected error for unknown discovery type")
+	}
+	if _, err := NewRegistry(RegistryTypeConsul, nil, testTTL); err == nil {
+		t.Fatal("expected error without consul endpoints")
+	}
+}
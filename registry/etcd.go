@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -157,6 +158,11 @@ func (e *EtcdRegistry) Deregister() error {
 	return nil
 }
 
+// Client 返回注册中心使用的ETCD客户端，可供失效消息总线等组件共用
+func (e *EtcdRegistry) Client() *clientv3.Client {
+	return e.client
+}
+
 // Close 关闭ETCD客户端
 func (e *EtcdRegistry) Close() error {
 	if e.registered {
@@ -175,6 +181,7 @@ type ServiceDiscovery struct {
 	watchChan clientv3.WatchChan     // 监听通道
 	mu        sync.RWMutex           // 读写锁
 	prefix    string                 // 服务前缀，用于过滤服务
+	subs      subscribers            // 服务变化的订阅者
 }
 
 // NewServiceDiscovery 创建一个新的服务发现客户端
@@ -255,12 +262,16 @@ func (sd *ServiceDiscovery) watchServices() {
 				}
 				sd.mu.Lock()
 				sd.services[string(ev.Kv.Key)] = service
+				sd.subs.publish(Event{Type: EventPut, Key: string(ev.Kv.Key), Service: service})
 				sd.mu.Unlock()
 				log.Printf("Service added/updated: %s -> %s\n", ev.Kv.Key, service.Addr)
 			case clientv3.EventTypeDelete:
 				// 当某个服务节点 从 ETCD 里删除 时，表示该服务下线
 				sd.mu.Lock()
-				delete(sd.services, string(ev.Kv.Key))
+				if service, ok := sd.services[string(ev.Kv.Key)]; ok {
+					delete(sd.services, string(ev.Kv.Key))
+					sd.subs.publish(Event{Type: EventDelete, Key: string(ev.Kv.Key), Service: service})
+				}
 				sd.mu.Unlock()
 				log.Printf("Service removed: %s\n", ev.Kv.Key)
 			}
@@ -301,7 +312,27 @@ func (sd *ServiceDiscovery) GetServicesByPrefix(prefix string) map[string]Servic
 	return services
 }
 
+// Subscribe 订阅指定前缀的服务变化
+func (sd *ServiceDiscovery) Subscribe(prefix string, fn func(Event)) func() {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	snapshot := make(map[string]ServiceInfo)
+	for k, v := range sd.services {
+		if strings.HasPrefix(k, prefix) {
+			snapshot[k] = v
+		}
+	}
+	return sd.subs.add(prefix, fn, snapshot)
+}
+
+// Client 返回服务发现使用的ETCD客户端，可供失效消息总线等组件共用
+func (sd *ServiceDiscovery) Client() *clientv3.Client {
+	return sd.client
+}
+
 // Close 关闭服务发现客户端
 func (sd *ServiceDiscovery) Close() error {
+	sd.subs.closeAll()
 	return sd.client.Close()
 }
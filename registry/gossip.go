@@ -0,0 +1,937 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 基于 SWIM 协议的去中心化成员管理，不依赖外部注册中心。
+// 每个节点周期性地探测一个成员：直接 ping 超时后请其他成员间接探测，
+// 仍失败则标记为疑似故障，疑似状态超时后确认下线。成员状态和服务信息
+// 附带在探测消息中传播，被怀疑的节点通过递增 incarnation 反驳。
+
+// gossipMessageType 消息类型
+type gossipMessageType string
+
+const (
+	gossipPing    gossipMessageType = "ping"
+	gossipPingReq gossipMessageType = "ping-req" // 请求其他节点代为探测
+	gossipAck     gossipMessageType = "ack"
+	gossipSync    gossipMessageType = "sync"   // 交换全部成员状态，用于加入集群和反熵
+	gossipUpdate  gossipMessageType = "update" // 只携带状态更新，用于主动离开
+)
+
+// memberState 成员状态
+type memberState string
+
+const (
+	memberAlive   memberState = "alive"
+	memberSuspect memberState = "suspect"
+	memberDead    memberState = "dead"
+	memberLeft    memberState = "left" // 主动离开
+)
+
+const (
+	// 单条消息最多附带的状态更新数
+	gossipMaxPiggyback = 8
+	// 下线成员保留的时间，期间收到的旧状态不会让其复活
+	gossipDeadRetention = time.Minute
+	// UDP 消息的最大长度，sync 消息携带全部成员，适用于数百个节点以内的集群
+	gossipMaxPacket = 64 << 10
+)
+
+// DefaultGossipAddr 是工厂创建 gossip 节点时默认监听的地址
+const DefaultGossipAddr = ":7946"
+
+// GossipConfig 配置 gossip 节点
+type GossipConfig struct {
+	BindAddr      string   // 监听的 UDP 地址，如 "0.0.0.0:7946"
+	AdvertiseAddr string   // 其他节点访问本节点的地址，为空时根据监听地址和种子节点推断
+	Seeds         []string // 种子节点地址，加入集群时与其交换成员状态
+	Prefix        string   // 只发现该前缀的服务，为空时发现所有服务
+
+	ProbeInterval    time.Duration // 探测间隔，默认 1s
+	ProbeTimeout     time.Duration // 等待 ack 的超时时间，默认 500ms
+	IndirectChecks   int           // 直接探测失败后间接探测的节点数，默认 3
+	SuspicionTimeout time.Duration // 疑似状态持续该时间后确认下线，默认 5s
+	PushPullInterval time.Duration // 与随机成员交换全部状态的间隔，默认 30s
+	RetransmitMult   int           // 每条状态更新的转发次数为 RetransmitMult*log10(n+1)，默认 4
+}
+
+func (c *GossipConfig) setDefaults() {
+	if c.ProbeInterval <= 0 {
+		c.ProbeInterval = time.Second
+	}
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = 500 * time.Millisecond
+	}
+	if c.IndirectChecks <= 0 {
+		c.IndirectChecks = 3
+	}
+	if c.SuspicionTimeout <= 0 {
+		c.SuspicionTimeout = 5 * time.Second
+	}
+	if c.PushPullInterval <= 0 {
+		c.PushPullInterval = 30 * time.Second
+	}
+	if c.RetransmitMult <= 0 {
+		c.RetransmitMult = 4
+	}
+}
+
+// memberUpdate 是成员状态，也是在节点间传播的状态更新
+type memberUpdate struct {
+	Name        string // 节点的 gossip 地址，作为成员的唯一标识
+	Incarnation uint64 // 由成员自己递增，较大的值覆盖较小的值
+	State       memberState
+	Key         string      `json:",omitempty"` // 服务键，未注册服务时为空
+	Info        ServiceInfo // 服务信息
+}
+
+// service 返回成员当前提供的服务，疑似故障的成员仍视为在线
+func (m *memberUpdate) service() (string, ServiceInfo, bool) {
+	if m.Key == "" || m.State == memberDead || m.State == memberLeft {
+		return "", ServiceInfo{}, false
+	}
+	return m.Key, m.Info, true
+}
+
+type member struct {
+	memberUpdate
+	changed time.Time // 最近一次状态变化的时间
+}
+
+type gossipMessage struct {
+	Type    gossipMessageType
+	Seq     uint64         `json:",omitempty"`
+	From    string         // 发送者的 gossip 地址
+	Target  string         `json:",omitempty"` // ping-req 的探测目标
+	Reply   bool           `json:",omitempty"` // sync 的回复，接收方不再回复
+	Updates []memberUpdate `json:",omitempty"`
+}
+
+// broadcast 等待附带在消息中传播的状态更新
+type broadcast struct {
+	update    memberUpdate
+	transmits int
+}
+
+// Gossip 是一个 SWIM 成员节点，同时实现了 Registry 和 Discovery 接口。
+// 注册的服务作为本节点的元数据传播到整个集群
+type Gossip struct {
+	cfg  GossipConfig
+	conn net.PacketConn
+	self string
+
+	mu         sync.RWMutex
+	members    map[string]*member
+	subs       subscribers // 服务变化的订阅者
+	probeOrder []string    // 本轮的探测顺序
+	probeIndex int
+
+	numMembers atomic.Int64 // 未下线的成员数，用于计算转发次数
+	seq        atomic.Uint64
+	ackMu      sync.Mutex
+	acks       map[uint64]chan struct{}
+	bmu        sync.Mutex
+	broadcasts []*broadcast
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewGossip 启动一个 gossip 节点并通过种子节点加入集群
+func NewGossip(cfg GossipConfig) (*Gossip, error) {
+	conn, err := net.ListenPacket("udp", cfg.BindAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen gossip address error: %v", err)
+	}
+	g, err := newGossip(cfg, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// GossipMember 是同一进程中注册中心和服务发现共用的 gossip 节点，
+// 避免同一进程以两个身份加入集群。节点在第一次使用时启动，所有使用者关闭后离开集群
+type GossipMember struct {
+	cfg GossipConfig
+
+	mu   sync.Mutex
+	node *Gossip
+	refs int
+}
+
+// NewGossipMember 创建共享的 gossip 节点，此时还不会加入集群。使用者的种子节点追加到
+// cfg.Seeds，BindAddr 为空时监听 DefaultGossipAddr，Prefix 被忽略
+func NewGossipMember(cfg GossipConfig) *GossipMember {
+	cfg.Seeds = append([]string(nil), cfg.Seeds...)
+	cfg.Prefix = ""
+	if cfg.BindAddr == "" {
+		cfg.BindAddr = DefaultGossipAddr
+	}
+	return &GossipMember{cfg: cfg}
+}
+
+// acquire 返回共享节点，不存在时以 seeds 为附加的种子节点创建
+func (m *GossipMember) acquire(seeds []string) (*Gossip, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.node != nil {
+		m.refs++
+		return m.node, nil
+	}
+	cfg := m.cfg
+	cfg.Seeds = append(append([]string(nil), cfg.Seeds...), seeds...)
+	node, err := NewGossip(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.node, m.refs = node, 1
+	return node, nil
+}
+
+// release 释放引用，最后一个引用释放时节点离开集群，之后再使用会重新加入
+func (m *GossipMember) release() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refs--
+	if m.refs > 0 {
+		return nil
+	}
+	node := m.node
+	m.node = nil
+	return node.Close()
+}
+
+// gossipRegistry 是工厂创建的注册中心，与同一进程的服务发现共用节点
+type gossipRegistry struct {
+	member    *GossipMember
+	node      *Gossip
+	closeOnce sync.Once
+}
+
+// NewGossipRegistry 通过 member 和种子节点加入集群，seeds 和 member 的种子都为空时作为第一个节点启动。
+// 同一进程的服务发现应使用同一个 member，member 为 nil 时使用默认配置的独立节点
+func NewGossipRegistry(member *GossipMember, seeds []string) (Registry, error) {
+	if member == nil {
+		member = NewGossipMember(GossipConfig{})
+	}
+	node, err := member.acquire(seeds)
+	if err != nil {
+		return nil, err
+	}
+	return &gossipRegistry{member: member, node: node}, nil
+}
+
+// Register 将服务作为共享节点的元数据传播到集群
+func (r *gossipRegistry) Register(serviceKey string, info ServiceInfo) error {
+	return r.node.Register(serviceKey, info)
+}
+
+// Deregister 注销服务
+func (r *gossipRegistry) Deregister() error {
+	return r.node.Deregister()
+}
+
+// Close 注销服务并释放共享节点
+func (r *gossipRegistry) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		if err = r.Deregister(); err != nil {
+			return
+		}
+		err = r.member.release()
+	})
+	return err
+}
+
+// gossipDiscovery 是工厂创建的服务发现，只发现 prefix 前缀的服务，与同一进程的注册中心共用节点
+type gossipDiscovery struct {
+	member *GossipMember
+	node   *Gossip
+	prefix string
+
+	mu      sync.Mutex
+	cancels map[int]func()
+	nextID  int
+	closed  bool
+}
+
+// NewGossipDiscovery 创建只发现 prefix 前缀服务的服务发现，member 和 seeds 同 NewGossipRegistry
+func NewGossipDiscovery(member *GossipMember, seeds []string, prefix string) (Discovery, error) {
+	if member == nil {
+		member = NewGossipMember(GossipConfig{})
+	}
+	node, err := member.acquire(seeds)
+	if err != nil {
+		return nil, err
+	}
+	return &gossipDiscovery{member: member, node: node, prefix: prefix, cancels: make(map[int]func())}, nil
+}
+
+// narrow 返回同时满足 prefix 和服务发现前缀的前缀，两者不相交时返回 false
+func (d *gossipDiscovery) narrow(prefix string) (string, bool) {
+	switch {
+	case strings.HasPrefix(prefix, d.prefix):
+		return prefix, true
+	case strings.HasPrefix(d.prefix, prefix):
+		return d.prefix, true
+	default:
+		return "", false
+	}
+}
+
+// GetServices 获取前缀下的所有服务
+func (d *gossipDiscovery) GetServices() map[string]ServiceInfo {
+	return d.GetServicesByPrefix("")
+}
+
+// GetService 获取指定服务
+func (d *gossipDiscovery) GetService(serviceKey string) (ServiceInfo, bool) {
+	if !strings.HasPrefix(serviceKey, d.prefix) {
+		return ServiceInfo{}, false
+	}
+	return d.node.GetService(serviceKey)
+}
+
+// GetServicesByPrefix 获取指定前缀的服务
+func (d *gossipDiscovery) GetServicesByPrefix(prefix string) map[string]ServiceInfo {
+	prefix, ok := d.narrow(prefix)
+	if !ok {
+		return make(map[string]ServiceInfo)
+	}
+	return d.node.GetServicesByPrefix(prefix)
+}
+
+// Subscribe 订阅指定前缀的服务变化，关闭服务发现时取消
+func (d *gossipDiscovery) Subscribe(prefix string, fn func(Event)) func() {
+	prefix, ok := d.narrow(prefix)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !ok || d.closed {
+		return func() {}
+	}
+	cancel := d.node.Subscribe(prefix, fn)
+	id := d.nextID
+	d.nextID++
+	d.cancels[id] = cancel
+	return func() {
+		d.mu.Lock()
+		delete(d.cancels, id)
+		d.mu.Unlock()
+		cancel()
+	}
+}
+
+// Close 取消所有订阅并释放共享节点
+func (d *gossipDiscovery) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	cancels := d.cancels
+	d.cancels = nil
+	d.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return d.member.release()
+}
+
+func newGossip(cfg GossipConfig, conn net.PacketConn) (*Gossip, error) {
+	cfg.setDefaults()
+	self, err := advertiseAddr(cfg, conn.LocalAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gossip{
+		cfg:     cfg,
+		conn:    conn,
+		self:    self,
+		members: make(map[string]*member),
+		acks:    make(map[uint64]chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	g.members[self] = &member{
+		memberUpdate: memberUpdate{Name: self, State: memberAlive},
+		changed:      time.Now(),
+	}
+	g.numMembers.Store(1)
+
+	g.wg.Add(3)
+	go g.receiveLoop()
+	go g.probeLoop()
+	go g.pushPullLoop()
+
+	// 向种子节点发送全部状态，种子节点回复它所知道的成员
+	g.join()
+	return g, nil
+}
+
+// advertiseAddr 推断其他节点访问本节点的地址。监听地址未指定IP时，
+// 使用访问第一个种子节点时的本地IP，没有种子节点时使用本机的第一个非回环IP
+func advertiseAddr(cfg GossipConfig, local net.Addr) (string, error) {
+	if cfg.AdvertiseAddr != "" {
+		return cfg.AdvertiseAddr, nil
+	}
+	addr, ok := local.(*net.UDPAddr)
+	if !ok {
+		return local.String(), nil
+	}
+	if !addr.IP.IsUnspecified() {
+		return addr.String(), nil
+	}
+	if len(cfg.Seeds) == 0 {
+		ip, err := interfaceIP()
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(ip.String(), fmt.Sprint(addr.Port)), nil
+	}
+	c, err := net.Dial("udp", cfg.Seeds[0])
+	if err != nil {
+		return "", fmt.Errorf("gossip: resolve advertise address error: %v", err)
+	}
+	defer c.Close()
+	ip := c.LocalAddr().(*net.UDPAddr).IP
+	return net.JoinHostPort(ip.String(), fmt.Sprint(addr.Port)), nil
+}
+
+// interfaceIP 返回本机的第一个非回环IPv4地址，没有时返回回环地址
+func interfaceIP() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("gossip: list interface addresses error: %v", err)
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.IP.IsGlobalUnicast() {
+			return ipnet.IP, nil
+		}
+	}
+	return net.IPv4(127, 0, 0, 1), nil
+}
+
+// LocalAddr 返回本节点的 gossip 地址，可作为其他节点的种子
+func (g *Gossip) LocalAddr() string {
+	return g.self
+}
+
+func (g *Gossip) join() {
+	for _, seed := range g.cfg.Seeds {
+		if seed != g.self {
+			g.send(seed, &gossipMessage{Type: gossipSync, Updates: g.snapshot()})
+		}
+	}
+}
+
+// send 发送消息，ping/ack 等消息会附带待传播的状态更新
+func (g *Gossip) send(to string, msg *gossipMessage) {
+	msg.From = g.self
+	if msg.Type != gossipSync && msg.Type != gossipUpdate {
+		msg.Updates = g.piggyback()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Marshal gossip message error: %v\n", err)
+		return
+	}
+	addr, err := net.ResolveUDPAddr("udp", to)
+	if err != nil {
+		log.Printf("Resolve gossip address %s error: %v\n", to, err)
+		return
+	}
+	if _, err := g.conn.WriteTo(data, addr); err != nil {
+		select {
+		case <-g.stop:
+		default:
+			log.Printf("Send gossip message to %s error: %v\n", to, err)
+		}
+	}
+}
+
+func (g *Gossip) receiveLoop() {
+	defer g.wg.Done()
+	buf := make([]byte, gossipMaxPacket)
+	for {
+		n, _, err := g.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-g.stop:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Read gossip message error: %v\n", err)
+			continue
+		}
+		var msg gossipMessage
+		if err := json.Unmarshal(buf[:n], &msg); err != nil {
+			log.Printf("Unmarshal gossip message error: %v\n", err)
+			continue
+		}
+		g.handle(&msg)
+	}
+}
+
+func (g *Gossip) handle(msg *gossipMessage) {
+	for _, u := range msg.Updates {
+		g.applyUpdate(u)
+	}
+
+	switch msg.Type {
+	case gossipPing:
+		g.send(msg.From, &gossipMessage{Type: gossipAck, Seq: msg.Seq})
+	case gossipPingReq:
+		// 代为探测，收到目标的 ack 后以原序号转发给请求者
+		go func() {
+			if g.ping(msg.Target, g.cfg.ProbeTimeout) {
+				g.send(msg.From, &gossipMessage{Type: gossipAck, Seq: msg.Seq})
+			}
+		}()
+	case gossipAck:
+		g.ackMu.Lock()
+		if ch, ok := g.acks[msg.Seq]; ok {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		g.ackMu.Unlock()
+	case gossipSync:
+		if !msg.Reply {
+			g.send(msg.From, &gossipMessage{Type: gossipSync, Reply: true, Updates: g.snapshot()})
+		}
+	}
+}
+
+// expectAck 注册等待序号 seq 的 ack
+func (g *Gossip) expectAck(seq uint64) chan struct{} {
+	ch := make(chan struct{}, 1)
+	g.ackMu.Lock()
+	g.acks[seq] = ch
+	g.ackMu.Unlock()
+	return ch
+}
+
+func (g *Gossip) cancelAck(seq uint64) {
+	g.ackMu.Lock()
+	delete(g.acks, seq)
+	g.ackMu.Unlock()
+}
+
+// waitAck 等待 ack，超时或节点关闭时返回 false
+func (g *Gossip) waitAck(ch chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	case <-g.stop:
+		return false
+	}
+}
+
+// ping 直接探测目标
+func (g *Gossip) ping(target string, timeout time.Duration) bool {
+	seq := g.seq.Add(1)
+	ch := g.expectAck(seq)
+	defer g.cancelAck(seq)
+	g.send(target, &gossipMessage{Type: gossipPing, Seq: seq})
+	return g.waitAck(ch, timeout)
+}
+
+func (g *Gossip) probeLoop() {
+	defer g.wg.Done()
+	ticker := time.NewTicker(g.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		g.expireSuspects()
+		if target := g.nextProbeTarget(); target != "" {
+			g.probe(target)
+		}
+	}
+}
+
+// probe 探测一个成员：直接探测失败后请 IndirectChecks 个成员间接探测，仍失败则标记为疑似故障
+func (g *Gossip) probe(target string) {
+	seq := g.seq.Add(1)
+	ch := g.expectAck(seq)
+	defer g.cancelAck(seq)
+
+	g.send(target, &gossipMessage{Type: gossipPing, Seq: seq})
+	if g.waitAck(ch, g.cfg.ProbeTimeout) {
+		return
+	}
+	for _, peer := range g.randomMembers(g.cfg.IndirectChecks, target) {
+		g.send(peer, &gossipMessage{Type: gossipPingReq, Seq: seq, Target: target})
+	}
+	// 间接探测需要经过两跳
+	if g.waitAck(ch, 2*g.cfg.ProbeTimeout) {
+		return
+	}
+
+	select {
+	case <-g.stop:
+		return
+	default:
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if m, ok := g.members[target]; ok && m.State == memberAlive {
+		log.Printf("Gossip member %s suspected\n", target)
+		g.setState(m, memberUpdate{Name: target, Incarnation: m.Incarnation, State: memberSuspect})
+	}
+}
+
+// nextProbeTarget 按随机顺序轮流探测所有成员
+func (g *Gossip) nextProbeTarget() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for {
+		if g.probeIndex >= len(g.probeOrder) {
+			g.probeOrder = g.probeOrder[:0]
+			for name, m := range g.members {
+				if name != g.self && (m.State == memberAlive || m.State == memberSuspect) {
+					g.probeOrder = append(g.probeOrder, name)
+				}
+			}
+			if len(g.probeOrder) == 0 {
+				return ""
+			}
+			rand.Shuffle(len(g.probeOrder), func(i, j int) {
+				g.probeOrder[i], g.probeOrder[j] = g.probeOrder[j], g.probeOrder[i]
+			})
+			g.probeIndex = 0
+		}
+		name := g.probeOrder[g.probeIndex]
+		g.probeIndex++
+		// 跳过本轮中已下线的成员
+		if m, ok := g.members[name]; ok && (m.State == memberAlive || m.State == memberSuspect) {
+			return name
+		}
+	}
+}
+
+// randomMembers 随机选取最多 n 个在线成员，不包括自己和 exclude
+func (g *Gossip) randomMembers(n int, exclude string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var names []string
+	for name, m := range g.members {
+		if name != g.self && name != exclude && m.State == memberAlive {
+			names = append(names, name)
+		}
+	}
+	rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// expireSuspects 确认疑似状态超时的成员下线，并清理下线已久的成员
+func (g *Gossip) expireSuspects() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for name, m := range g.members {
+		switch {
+		case m.State == memberSuspect && now.Sub(m.changed) >= g.cfg.SuspicionTimeout:
+			log.Printf("Gossip member %s confirmed dead\n", name)
+			g.setState(m, memberUpdate{Name: name, Incarnation: m.Incarnation, State: memberDead})
+		case (m.State == memberDead || m.State == memberLeft) && now.Sub(m.changed) >= gossipDeadRetention:
+			delete(g.members, name)
+		}
+	}
+}
+
+func (g *Gossip) pushPullLoop() {
+	defer g.wg.Done()
+	ticker := time.NewTicker(g.cfg.PushPullInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		// 没有已知成员时重新联系种子节点，用于种子节点晚于本节点启动或网络分区恢复
+		if peers := g.randomMembers(1, ""); len(peers) > 0 {
+			g.send(peers[0], &gossipMessage{Type: gossipSync, Updates: g.snapshot()})
+		} else {
+			g.join()
+		}
+	}
+}
+
+// snapshot 返回所有成员的状态
+func (g *Gossip) snapshot() []memberUpdate {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	updates := make([]memberUpdate, 0, len(g.members))
+	for _, m := range g.members {
+		updates = append(updates, m.memberUpdate)
+	}
+	return updates
+}
+
+// applyUpdate 按 SWIM 的规则合并其他节点传来的状态
+func (g *Gossip) applyUpdate(u memberUpdate) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if u.Name == g.self {
+		g.refute(u)
+		return
+	}
+
+	m, ok := g.members[u.Name]
+	if !ok {
+		switch u.State {
+		case memberAlive:
+			m = &member{memberUpdate: memberUpdate{Name: u.Name, State: memberDead}}
+			g.members[u.Name] = m
+			g.setState(m, u)
+			log.Printf("Gossip member %s joined\n", u.Name)
+		case memberDead, memberLeft:
+			// 未知成员的离线状态也要记录，避免随后收到的旧状态让其复活
+			g.members[u.Name] = &member{memberUpdate: u, changed: time.Now()}
+		}
+		return
+	}
+
+	down := m.State == memberDead || m.State == memberLeft
+	switch u.State {
+	case memberAlive:
+		if u.Incarnation <= m.Incarnation {
+			return
+		}
+		if down {
+			log.Printf("Gossip member %s rejoined\n", u.Name)
+		}
+	case memberSuspect:
+		// 在线成员被同一或更新的 incarnation 怀疑，已疑似的成员只接受更新的怀疑
+		accept := m.State == memberAlive && u.Incarnation >= m.Incarnation ||
+			m.State == memberSuspect && u.Incarnation > m.Incarnation
+		if !accept {
+			return
+		}
+	case memberDead, memberLeft:
+		if u.Incarnation < m.Incarnation || down && u.Incarnation == m.Incarnation {
+			return
+		}
+		log.Printf("Gossip member %s %s\n", u.Name, u.State)
+	default:
+		return
+	}
+	// 状态更新不一定携带服务信息
+	if u.State != memberAlive {
+		u.Key, u.Info = m.Key, m.Info
+	}
+	g.setState(m, u)
+}
+
+// refute 其他节点认为本节点故障时递增 incarnation 并广播在线状态，调用方需持有锁
+func (g *Gossip) refute(u memberUpdate) {
+	me := g.members[g.self]
+	if me.State == memberLeft || u.Incarnation < me.Incarnation {
+		return
+	}
+	if u.State == memberAlive && u.Incarnation == me.Incarnation {
+		return
+	}
+	next := me.memberUpdate
+	next.Incarnation = u.Incarnation + 1
+	g.setState(me, next)
+}
+
+// setState 更新成员状态，广播该更新并通知服务变化，调用方需持有锁
+func (g *Gossip) setState(m *member, u memberUpdate) {
+	old := m.memberUpdate
+	m.memberUpdate = u
+	m.changed = time.Now()
+	g.queueBroadcast(u)
+
+	alive := int64(0)
+	for _, m := range g.members {
+		if m.State == memberAlive || m.State == memberSuspect {
+			alive++
+		}
+	}
+	g.numMembers.Store(alive)
+
+	oldKey, oldInfo, hadService := old.service()
+	newKey, newInfo, hasService := u.service()
+	if hadService && (!hasService || oldKey != newKey) && strings.HasPrefix(oldKey, g.cfg.Prefix) {
+		g.subs.publish(Event{Type: EventDelete, Key: oldKey, Service: oldInfo})
+	}
+	if hasService && (!hadService || oldKey != newKey || !sameService(oldInfo, newInfo)) && strings.HasPrefix(newKey, g.cfg.Prefix) {
+		g.subs.publish(Event{Type: EventPut, Key: newKey, Service: newInfo})
+	}
+}
+
+// queueBroadcast 加入待传播的状态更新，同一成员的旧更新被替换
+func (g *Gossip) queueBroadcast(u memberUpdate) {
+	g.bmu.Lock()
+	defer g.bmu.Unlock()
+	for i, b := range g.broadcasts {
+		if b.update.Name == u.Name {
+			g.broadcasts = append(g.broadcasts[:i], g.broadcasts[i+1:]...)
+			break
+		}
+	}
+	g.broadcasts = append(g.broadcasts, &broadcast{update: u})
+}
+
+// piggyback 取出转发次数最少的状态更新，转发次数达到上限的更新被丢弃
+func (g *Gossip) piggyback() []memberUpdate {
+	g.bmu.Lock()
+	defer g.bmu.Unlock()
+	if len(g.broadcasts) == 0 {
+		return nil
+	}
+	limit := g.cfg.RetransmitMult * int(math.Ceil(math.Log10(float64(g.numMembers.Load()+1))))
+
+	sort.SliceStable(g.broadcasts, func(i, j int) bool {
+		return g.broadcasts[i].transmits < g.broadcasts[j].transmits
+	})
+	n := min(len(g.broadcasts), gossipMaxPiggyback)
+	updates := make([]memberUpdate, 0, n)
+	for _, b := range g.broadcasts[:n] {
+		updates = append(updates, b.update)
+		b.transmits++
+	}
+	kept := g.broadcasts[:0]
+	for _, b := range g.broadcasts {
+		if b.transmits < limit {
+			kept = append(kept, b)
+		}
+	}
+	g.broadcasts = kept
+	return updates
+}
+
+// Register 将服务作为本节点的元数据传播到集群
+func (g *Gossip) Register(serviceKey string, info ServiceInfo) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	me := g.members[g.self]
+	if me.Key != "" {
+		return fmt.Errorf("service already registered")
+	}
+	next := me.memberUpdate
+	next.Incarnation++
+	next.Key, next.Info = serviceKey, info
+	g.setState(me, next)
+
+	log.Printf("Service registered: %s -> %s (%s)\n", serviceKey, info.Addr, g.self)
+	return nil
+}
+
+// Deregister 注销服务，本节点仍留在集群中
+func (g *Gossip) Deregister() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	me := g.members[g.self]
+	if me.Key == "" {
+		return nil
+	}
+	key := me.Key
+	next := me.memberUpdate
+	next.Incarnation++
+	next.Key, next.Info = "", ServiceInfo{}
+	g.setState(me, next)
+
+	log.Printf("Service deregistered: %s\n", key)
+	return nil
+}
+
+// Close 通知其他成员本节点离开集群并停止 gossip
+func (g *Gossip) Close() error {
+	g.closeOnce.Do(func() {
+		g.mu.Lock()
+		me := g.members[g.self]
+		leave := me.memberUpdate
+		leave.Incarnation++
+		leave.State = memberLeft
+		g.setState(me, leave)
+		g.mu.Unlock()
+
+		for _, peer := range g.randomMembers(math.MaxInt, "") {
+			g.send(peer, &gossipMessage{Type: gossipUpdate, Updates: []memberUpdate{leave}})
+		}
+
+		close(g.stop)
+		g.conn.Close()
+		g.wg.Wait()
+		g.subs.closeAll()
+	})
+	return nil
+}
+
+// GetServices 获取所有在线成员的服务
+func (g *Gossip) GetServices() map[string]ServiceInfo {
+	return g.GetServicesByPrefix("")
+}
+
+// GetService 获取指定服务
+func (g *Gossip) GetService(serviceKey string) (ServiceInfo, bool) {
+	services := g.GetServicesByPrefix(serviceKey)
+	service, ok := services[serviceKey]
+	return service, ok
+}
+
+// GetServicesByPrefix 获取指定前缀的服务
+func (g *Gossip) GetServicesByPrefix(prefix string) map[string]ServiceInfo {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.servicesLocked(prefix)
+}
+
+// servicesLocked 返回指定前缀的服务，调用方需持有锁
+func (g *Gossip) servicesLocked(prefix string) map[string]ServiceInfo {
+	services := make(map[string]ServiceInfo)
+	for _, m := range g.members {
+		key, info, ok := m.service()
+		if ok && strings.HasPrefix(key, g.cfg.Prefix) && strings.HasPrefix(key, prefix) {
+			services[key] = info
+		}
+	}
+	return services
+}
+
+// Subscribe 订阅指定前缀的服务变化
+func (g *Gossip) Subscribe(prefix string, fn func(Event)) func() {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.subs.add(prefix, fn, g.servicesLocked(prefix))
+}
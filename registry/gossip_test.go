@@ -0,0 +1,262 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lossyConn 模拟网络故障，drop 为 true 时丢弃所有收发的消息
+type lossyConn struct {
+	net.PacketConn
+	drop atomic.Bool
+}
+
+func (c *lossyConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || !c.drop.Load() {
+			return n, addr, err
+		}
+	}
+}
+
+func (c *lossyConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if c.drop.Load() {
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func newTestGossip(t *testing.T, suspicion time.Duration, seeds ...string) (*Gossip, *lossyConn) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	lossy := &lossyConn{PacketConn: conn}
+	g, err := newGossip(GossipConfig{
+		Seeds:            seeds,
+		Prefix:           testPrefix,
+		ProbeInterval:    50 * time.Millisecond,
+		ProbeTimeout:     20 * time.Millisecond,
+		SuspicionTimeout: suspicion,
+		PushPullInterval: 200 * time.Millisecond,
+	}, lossy)
+	if err != nil {
+		t.Fatalf("Failed to start gossip: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g, lossy
+}
+
+// memberState 返回 g 所知道的成员 name 的状态
+func (g *Gossip) memberState(name string) (memberState, uint64) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	m, ok := g.members[name]
+	if !ok {
+		return "", 0
+	}
+	return m.State, m.Incarnation
+}
+
+func TestGossipMembership(t *testing.T) {
+	a, _ := newTestGossip(t, time.Second)
+	b, _ := newTestGossip(t, time.Second, a.LocalAddr())
+	c, _ := newTestGossip(t, time.Second, a.LocalAddr())
+
+	var log eventLog
+	defer a.Subscribe(testPrefix, log.add)()
+
+	nodes := map[string]*Gossip{"a": a, "b": b, "c": c}
+	for name, g := range nodes {
+		if err := g.Register(testPrefix+name, ServiceInfo{Addr: "http://" + name, Metadata: map[string]string{"node": name}}); err != nil {
+			t.Fatalf("Failed to register service: %v", err)
+		}
+	}
+	if err := a.Register(testPrefix+"a", ServiceInfo{Addr: "http://a"}); err == nil {
+		t.Fatal("expected error when registering twice")
+	}
+
+	// 服务信息传播到所有节点，b 和 c 只通过种子节点 a 互相发现
+	for name, g := range nodes {
+		waitFor(t, name+" discovering all services", func() bool {
+			return len(g.GetServices()) == 3
+		})
+	}
+	if service, ok := b.GetService(testPrefix + "c"); !ok || service.Metadata["node"] != "c" {
+		t.Fatalf("GetService(c) = %+v, %v", service, ok)
+	}
+	waitFor(t, "put event", func() bool { return log.has(EventPut, testPrefix+"c") })
+
+	// 注销服务后节点仍在集群中
+	if err := c.Deregister(); err != nil {
+		t.Fatalf("Failed to deregister: %v", err)
+	}
+	waitFor(t, "deregistration", func() bool {
+		_, ok := b.GetService(testPrefix + "c")
+		return !ok
+	})
+	if state, _ := b.memberState(c.LocalAddr()); state != memberAlive {
+		t.Fatalf("member c state = %s, want alive", state)
+	}
+	waitFor(t, "delete event", func() bool { return log.has(EventDelete, testPrefix+"c") })
+
+	// 主动离开的节点立即被移除，不需要等待故障检测
+	b.Close()
+	waitFor(t, "leave", func() bool {
+		state, _ := a.memberState(b.LocalAddr())
+		return state == memberLeft
+	})
+	if _, ok := a.GetService(testPrefix + "b"); ok {
+		t.Fatal("service of the node that left should be removed")
+	}
+}
+
+func TestGossipFailureDetection(t *testing.T) {
+	a, _ := newTestGossip(t, 200*time.Millisecond)
+	b, _ := newTestGossip(t, 200*time.Millisecond, a.LocalAddr())
+	c, conn := newTestGossip(t, 200*time.Millisecond, a.LocalAddr())
+	if err := c.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	waitFor(t, "service", func() bool {
+		_, okA := a.GetService(testKey)
+		_, okB := b.GetService(testKey)
+		return okA && okB
+	})
+
+	// 节点崩溃：先被怀疑，疑似超时后确认下线
+	conn.drop.Store(true)
+	waitFor(t, "suspicion", func() bool {
+		state, _ := a.memberState(c.LocalAddr())
+		return state == memberSuspect || state == memberDead
+	})
+	for name, g := range map[string]*Gossip{"a": a, "b": b} {
+		waitFor(t, name+" confirming death", func() bool {
+			state, _ := g.memberState(c.LocalAddr())
+			return state == memberDead
+		})
+		if _, ok := g.GetService(testKey); ok {
+			t.Fatalf("%s still discovers the service of a dead node", name)
+		}
+	}
+}
+
+func TestGossipRefuteSuspicion(t *testing.T) {
+	a, _ := newTestGossip(t, time.Second)
+	b, conn := newTestGossip(t, time.Second, a.LocalAddr())
+	waitFor(t, "join", func() bool {
+		state, _ := a.memberState(b.LocalAddr())
+		return state == memberAlive
+	})
+	_, before := a.memberState(b.LocalAddr())
+
+	// 短暂的网络故障使 b 被怀疑，恢复后 b 递增 incarnation 反驳
+	conn.drop.Store(true)
+	waitFor(t, "suspicion", func() bool {
+		state, _ := a.memberState(b.LocalAddr())
+		return state == memberSuspect
+	})
+	conn.drop.Store(false)
+	waitFor(t, "refutation", func() bool {
+		state, inc := a.memberState(b.LocalAddr())
+		return state == memberAlive && inc > before
+	})
+
+	// 被反驳的怀疑不会在超时后确认下线
+	time.Sleep(1100 * time.Millisecond)
+	if state, _ := a.memberState(b.LocalAddr()); state != memberAlive {
+		t.Fatalf("member b state = %s after refutation, want alive", state)
+	}
+}
+
+func TestGossipAdvertiseAddr(t *testing.T) {
+	// 没有种子节点的第一个节点使用本机IP
+	first, err := NewGossip(GossipConfig{BindAddr: ":0"})
+	if err != nil {
+		t.Fatalf("Failed to start seedless gossip: %v", err)
+	}
+	defer first.Close()
+	if host, _, _ := net.SplitHostPort(first.LocalAddr()); net.ParseIP(host) == nil || net.ParseIP(host).IsUnspecified() {
+		t.Fatalf("advertise address = %s, want a concrete IP", first.LocalAddr())
+	}
+
+	g, err := NewGossip(GossipConfig{BindAddr: ":0", Seeds: []string{"127.0.0.1:1"}})
+	if err != nil {
+		t.Fatalf("Failed to start gossip: %v", err)
+	}
+	defer g.Close()
+	if host, _, _ := net.SplitHostPort(g.LocalAddr()); host != "127.0.0.1" {
+		t.Fatalf("advertise address = %s, want 127.0.0.1", g.LocalAddr())
+	}
+}
+
+// freeUDPAddr 返回一个空闲的本地 UDP 地址
+func freeUDPAddr(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func TestGossipFactoryCluster(t *testing.T) {
+	addrs := []string{freeUDPAddr(t), freeUDPAddr(t), freeUDPAddr(t)}
+
+	// 第一个节点没有种子，其余节点以第一个节点为种子，同一进程中的各节点互不影响
+	var discoveries []Discovery
+	for i, addr := range addrs {
+		member := NewGossipMember(GossipConfig{
+			BindAddr:         addr,
+			ProbeInterval:    50 * time.Millisecond,
+			ProbeTimeout:     20 * time.Millisecond,
+			PushPullInterval: 200 * time.Millisecond,
+		})
+		var seeds []string
+		if i > 0 {
+			seeds = addrs[:1]
+		}
+		reg, err := NewRegistry(RegistryTypeGossip, seeds, testTTL, WithGossipMember(member))
+		if err != nil {
+			t.Fatalf("Failed to create registry %d: %v", i, err)
+		}
+		defer reg.Close()
+		disc, err := NewDiscovery(RegistryTypeGossip, seeds, testPrefix, WithGossipMember(member))
+		if err != nil {
+			t.Fatalf("Failed to create discovery %d: %v", i, err)
+		}
+		defer disc.Close()
+		discoveries = append(discoveries, disc)
+
+		// 注册中心和服务发现共用节点，每个进程只以一个身份加入集群
+		if reg.(*gossipRegistry).node != disc.(*gossipDiscovery).node {
+			t.Fatalf("registry and discovery %d should share the gossip node", i)
+		}
+
+		if err := reg.Register(testPrefix+addr, ServiceInfo{Addr: addr}); err != nil {
+			t.Fatalf("Failed to register service: %v", err)
+		}
+	}
+
+	for i, disc := range discoveries {
+		waitFor(t, fmt.Sprintf("node %d discovering all services", i), func() bool {
+			return len(disc.GetServices()) == len(addrs)
+		})
+	}
+	node := discoveries[0].(*gossipDiscovery).node
+	node.mu.RLock()
+	members := len(node.members)
+	node.mu.RUnlock()
+	if members != len(addrs) {
+		t.Fatalf("cluster has %d members, want %d", members, len(addrs))
+	}
+	if services := discoveries[0].GetServicesByPrefix("/other/"); len(services) != 0 {
+		t.Fatalf("discovery should only return services under its prefix, got %v", services)
+	}
+}
@@ -22,6 +22,9 @@ type Discovery interface {
 	GetService(serviceKey string) (ServiceInfo, bool)
 	// GetServicesByPrefix 获取指定前缀的服务
 	GetServicesByPrefix(prefix string) map[string]ServiceInfo
+	// Subscribe 订阅指定前缀的服务变化，已存在的服务最先以 EventPut 事件投递。
+	// 回调在独立的goroutine中按事件顺序执行，返回的函数用于取消订阅
+	Subscribe(prefix string, fn func(Event)) (cancel func())
 	// Close 关闭服务发现客户端
 	Close() error
 }
@@ -36,6 +39,9 @@ const (
 	RegistryTypeConsul RegistryType = "consul"
 	// RegistryTypeZookeeper Zookeeper注册中心
 	RegistryTypeZookeeper RegistryType = "zookeeper"
+	// RegistryTypeGossip 基于 SWIM gossip 的去中心化成员管理，endpoints 为种子节点，
+	// 监听和对外地址通过 WithGossipMember 设置，注册中心和服务发现应传入同一个节点
+	RegistryTypeGossip RegistryType = "gossip"
 )
 
 // ServicePrefix 服务前缀
@@ -43,25 +49,58 @@ const (
 	DefaultServicePrefix = "/services/geecache/"
 )
 
+// Option 配置 NewRegistry 和 NewDiscovery 创建的客户端
+type Option func(*options)
+
+type options struct {
+	gossipMember *GossipMember
+}
+
+// WithGossipMember 指定 RegistryTypeGossip 使用的节点，同一进程的注册中心和服务发现
+// 传入同一个 member 时共用一个成员身份。未指定时各自按默认配置启动节点
+func WithGossipMember(member *GossipMember) Option {
+	return func(o *options) {
+		o.gossipMember = member
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // NewRegistry 创建一个新的注册中心客户端
-func NewRegistry(registryType RegistryType, endpoints []string, serviceTTL int64) (Registry, error) {
+func NewRegistry(registryType RegistryType, endpoints []string, serviceTTL int64, opts ...Option) (Registry, error) {
+	o := newOptions(opts)
 	switch registryType {
 	case RegistryTypeEtcd:
 		return NewEtcdRegistry(endpoints, serviceTTL)
+	case RegistryTypeConsul:
+		return NewConsulRegistry(endpoints, serviceTTL)
+	case RegistryTypeZookeeper:
+		return NewZookeeperRegistry(endpoints, serviceTTL)
+	case RegistryTypeGossip:
+		return NewGossipRegistry(o.gossipMember, endpoints)
 	default:
-		return NewEtcdRegistry(endpoints, serviceTTL)
+		return nil, fmt.Errorf("unsupported registry type: %s", registryType)
 	}
 }
 
 // NewDiscovery 创建一个新的服务发现客户端
-func NewDiscovery(registryType RegistryType, endpoints []string, prefix string) (Discovery, error) {
+func NewDiscovery(registryType RegistryType, endpoints []string, prefix string, opts ...Option) (Discovery, error) {
+	o := newOptions(opts)
 	switch registryType {
 	case RegistryTypeEtcd:
 		return NewServiceDiscovery(endpoints, prefix)
 	case RegistryTypeConsul:
-		return nil, fmt.Errorf("consul discovery not implemented yet")
+		return NewConsulDiscovery(endpoints, prefix)
 	case RegistryTypeZookeeper:
-		return nil, fmt.Errorf("zookeeper discovery not implemented yet")
+		return NewZookeeperDiscovery(endpoints, prefix)
+	case RegistryTypeGossip:
+		return NewGossipDiscovery(o.gossipMember, endpoints, prefix)
 	default:
 		return nil, fmt.Errorf("unsupported registry type: %s", registryType)
 	}
@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"strings"
+	"sync"
+)
+
+// EventType 服务变化类型
+type EventType string
+
+const (
+	// EventPut 服务新增或更新
+	EventPut EventType = "put"
+	// EventDelete 服务下线
+	EventDelete EventType = "delete"
+)
+
+// Event 服务变化事件
+type Event struct {
+	Type    EventType
+	Key     string      // 服务键
+	Service ServiceInfo // 删除事件中为下线前的服务信息
+}
+
+// subscribers 管理服务变化的订阅者。每个订阅者有独立的事件队列和goroutine，
+// 回调按事件发生的顺序执行，慢订阅者不会阻塞服务发现
+type subscribers struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]*subscriber
+}
+
+type subscriber struct {
+	prefix string
+	fn     func(Event)
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{} // 有新事件时发送信号
+	done   chan struct{} // 取消订阅
+}
+
+// add 添加订阅者，snapshot 中的服务作为 EventPut 事件最先投递。
+// 调用方需在服务列表的锁内调用，以保证快照和之后的事件之间没有遗漏
+func (s *subscribers) add(prefix string, fn func(Event), snapshot map[string]ServiceInfo) (cancel func()) {
+	sub := &subscriber{
+		prefix: prefix,
+		fn:     fn,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	for key, service := range snapshot {
+		sub.push(Event{Type: EventPut, Key: key, Service: service})
+	}
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[int]*subscriber)
+	}
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = sub
+	s.mu.Unlock()
+
+	go sub.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, id)
+			s.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// publish 将事件投递给前缀匹配的订阅者，不会阻塞
+func (s *subscribers) publish(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		if strings.HasPrefix(ev.Key, sub.prefix) {
+			sub.push(ev)
+		}
+	}
+}
+
+// closeAll 取消所有订阅
+func (s *subscribers) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sub := range s.subs {
+		close(sub.done)
+		delete(s.subs, id)
+	}
+}
+
+func (sub *subscriber) push(ev Event) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, ev)
+	sub.mu.Unlock()
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscriber) run() {
+	for {
+		select {
+		case <-sub.done:
+			return
+		case <-sub.notify:
+		}
+
+		sub.mu.Lock()
+		events := sub.queue
+		sub.queue = nil
+		sub.mu.Unlock()
+
+		for _, ev := range events {
+			select {
+			case <-sub.done:
+				return
+			default:
+			}
+			sub.fn(ev)
+		}
+	}
+}
+
+// diffServices 比较新旧服务列表并发布变化事件
+func (s *subscribers) diffServices(old, cur map[string]ServiceInfo) {
+	for key, service := range old {
+		if _, ok := cur[key]; !ok {
+			s.publish(Event{Type: EventDelete, Key: key, Service: service})
+		}
+	}
+	for key, service := range cur {
+		if prev, ok := old[key]; !ok || !sameService(prev, service) {
+			s.publish(Event{Type: EventPut, Key: key, Service: service})
+		}
+	}
+}
+
+func sameService(a, b ServiceInfo) bool {
+	if a.Addr != b.Addr || len(a.Metadata) != len(b.Metadata) {
+		return false
+	}
+	for k, v := range a.Metadata {
+		if bv, ok := b.Metadata[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"sync"
+	"testing"
+)
+
+// eventLog 记录订阅回调收到的事件
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) add(ev Event) {
+	l.mu.Lock()
+	l.events = append(l.events, ev)
+	l.mu.Unlock()
+}
+
+func (l *eventLog) has(typ EventType, key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, ev := range l.events {
+		if ev.Type == typ && ev.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *eventLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events)
+}
+
+func TestSubscribersPrefixAndCancel(t *testing.T) {
+	var s subscribers
+	var all, sub eventLog
+
+	cancelAll := s.add("", all.add, map[string]ServiceInfo{"/a/1": {Addr: "x"}})
+	defer cancelAll()
+	cancel := s.add("/b/", sub.add, nil)
+
+	s.diffServices(map[string]ServiceInfo{"/a/1": {Addr: "x"}}, map[string]ServiceInfo{
+		"/a/1": {Addr: "x"}, // 未变化，不发布
+		"/b/1": {Addr: "y"},
+	})
+	waitFor(t, "prefix event", func() bool { return sub.has(EventPut, "/b/1") })
+	waitFor(t, "snapshot and put", func() bool { return all.len() == 2 })
+
+	// 取消后不再收到事件
+	cancel()
+	s.publish(Event{Type: EventDelete, Key: "/b/1"})
+	waitFor(t, "delete", func() bool { return all.has(EventDelete, "/b/1") })
+	if sub.has(EventDelete, "/b/1") {
+		t.Fatal("cancelled subscriber received event")
+	}
+}
+
+func TestDiscoverySubscribe(t *testing.T) {
+	_, srv := newFakeConsul()
+	defer srv.Close()
+	endpoints := []string{srv.URL}
+
+	reg, _ := NewConsulRegistry(endpoints, testTTL)
+	defer reg.Close()
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	consul, err := NewConsulDiscovery(endpoints, testPrefix)
+	if err != nil {
+		t.Fatalf("Failed to create discovery: %v", err)
+	}
+	defer consul.Close()
+
+	fake := newFakeZK()
+	zkReg, _ := newZookeeperRegistry(fake.dial)
+	defer zkReg.Close()
+	if err := zkReg.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	zookeeper, err := newZookeeperDiscovery(fake.dial, testPrefix)
+	if err != nil {
+		t.Fatalf("Failed to create discovery: %v", err)
+	}
+	defer zookeeper.Close()
+
+	otherKey := testPrefix + "other-service"
+	for name, d := range map[string]Discovery{"consul": consul, "zookeeper": zookeeper} {
+		var log eventLog
+		cancel := d.Subscribe(testPrefix, log.add)
+
+		// 已存在的服务作为快照最先投递
+		waitFor(t, name+" snapshot", func() bool { return log.has(EventPut, testKey) })
+
+		var other Registry
+		if name == "consul" {
+			other, _ = NewConsulRegistry(endpoints, testTTL)
+		} else {
+			other, _ = newZookeeperRegistry(fake.dial)
+		}
+		if err := other.Register(otherKey, ServiceInfo{Addr: "http://localhost:8002"}); err != nil {
+			t.Fatalf("Failed to register service: %v", err)
+		}
+		waitFor(t, name+" put", func() bool { return log.has(EventPut, otherKey) })
+
+		other.Close()
+		waitFor(t, name+" delete", func() bool { return log.has(EventDelete, otherKey) })
+		cancel()
+	}
+}
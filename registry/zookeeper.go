@@ -0,0 +1,381 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// 每个节点注册为 DefaultServicePrefix 下的临时顺序节点，节点名由 ZooKeeper 生成，
+// 服务键和服务信息保存在节点数据中
+const zkNodePrefix = "node-"
+
+// zkConn 是注册与发现用到的 ZooKeeper 操作，*zk.Conn 实现了该接口，
+// 测试中可替换为进程内的假服务
+type zkConn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	Delete(path string, version int32) error
+	Close()
+}
+
+// zkDialer 建立 ZooKeeper 会话，返回连接以及会话事件通道
+type zkDialer func() (zkConn, <-chan zk.Event, error)
+
+func newZKDialer(endpoints []string, sessionTimeout time.Duration) zkDialer {
+	return func() (zkConn, <-chan zk.Event, error) {
+		conn, events, err := zk.Connect(endpoints, sessionTimeout, zk.WithLogInfo(false))
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn, events, nil
+	}
+}
+
+// zkServiceDir 返回注册服务的父节点路径，ZooKeeper 路径不能以 / 结尾
+func zkServiceDir() string {
+	return strings.TrimSuffix(DefaultServicePrefix, "/")
+}
+
+// zkNodeData 是节点中保存的数据
+type zkNodeData struct {
+	Key  string
+	Info ServiceInfo
+}
+
+// ensurePath 逐级创建持久节点，已存在的节点忽略
+func ensurePath(conn zkConn, p string) error {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	cur := ""
+	for _, part := range parts {
+		cur += "/" + part
+		if _, err := conn.Create(cur, nil, zk.FlagPersistent, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("create %s error: %v", cur, err)
+		}
+	}
+	return nil
+}
+
+// ZookeeperRegistry 实现基于 ZooKeeper 的服务注册，节点随会话结束自动删除
+type ZookeeperRegistry struct {
+	conn        zkConn
+	events      <-chan zk.Event // 会话事件
+	node        string          // 创建的临时顺序节点路径
+	serviceKey  string          // 服务键名
+	serviceInfo ServiceInfo     // 服务信息
+	stopSignal  chan struct{}   // 停止信号
+	registered  bool            // 是否已注册
+	mu          sync.Mutex      // 互斥锁
+}
+
+// NewZookeeperRegistry 创建一个新的 ZooKeeper 注册中心客户端，serviceTTL 为会话超时时间(秒)
+func NewZookeeperRegistry(endpoints []string, serviceTTL int64) (*ZookeeperRegistry, error) {
+	if serviceTTL <= 0 {
+		serviceTTL = 10
+	}
+	return newZookeeperRegistry(newZKDialer(endpoints, time.Duration(serviceTTL)*time.Second))
+}
+
+func newZookeeperRegistry(dial zkDialer) (*ZookeeperRegistry, error) {
+	conn, events, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	return &ZookeeperRegistry{
+		conn:   conn,
+		events: events,
+	}, nil
+}
+
+// Register 注册服务到 ZooKeeper
+func (z *ZookeeperRegistry) Register(serviceKey string, info ServiceInfo) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.registered {
+		return fmt.Errorf("service already registered")
+	}
+
+	z.serviceKey = serviceKey
+	z.serviceInfo = info
+	if err := z.createNode(); err != nil {
+		return err
+	}
+
+	z.stopSignal = make(chan struct{})
+	z.registered = true
+
+	// 启动监听会话事件的goroutine
+	go z.watchSession(z.stopSignal)
+
+	log.Printf("Service registered: %s -> %s (%s)\n", serviceKey, info.Addr, z.node)
+	return nil
+}
+
+// createNode 创建临时顺序节点，调用方需持有锁
+func (z *ZookeeperRegistry) createNode() error {
+	data, err := json.Marshal(zkNodeData{Key: z.serviceKey, Info: z.serviceInfo})
+	if err != nil {
+		return fmt.Errorf("marshal service info error: %v", err)
+	}
+	dir := zkServiceDir()
+	if err := ensurePath(z.conn, dir); err != nil {
+		return err
+	}
+	node, err := z.conn.Create(path.Join(dir, zkNodePrefix), data, zk.FlagEphemeralSequential, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return fmt.Errorf("create service node error: %v", err)
+	}
+	z.node = node
+	return nil
+}
+
+// watchSession 会话过期后临时节点会被删除，在新会话建立后重新注册
+func (z *ZookeeperRegistry) watchSession(stop chan struct{}) {
+	expired := false
+	for {
+		select {
+		case <-stop:
+			return
+		case ev, ok := <-z.events:
+			if !ok {
+				return
+			}
+			switch ev.State {
+			case zk.StateExpired:
+				log.Println("Session expired, waiting to re-register...")
+				expired = true
+			case zk.StateHasSession:
+				if !expired {
+					continue
+				}
+				if err := z.reRegister(stop); err != nil {
+					log.Printf("Re-register failed: %v\n", err)
+					continue
+				}
+				expired = false
+			}
+		}
+	}
+}
+
+// 重新注册服务
+func (z *ZookeeperRegistry) reRegister(stop chan struct{}) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	// 已注销时不再注册
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	if err := z.createNode(); err != nil {
+		return err
+	}
+	log.Printf("Service re-registered: %s (%s)\n", z.serviceKey, z.node)
+	return nil
+}
+
+// Deregister 注销服务
+func (z *ZookeeperRegistry) Deregister() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if !z.registered {
+		return nil
+	}
+
+	if err := z.conn.Delete(z.node, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		// 节点仍然存在，保持会话监听，之后可以重试注销
+		return fmt.Errorf("delete service node error: %v", err)
+	}
+
+	close(z.stopSignal)
+	z.registered = false
+	log.Printf("Service deregistered: %s\n", z.serviceKey)
+	return nil
+}
+
+// Close 注销服务并关闭会话
+func (z *ZookeeperRegistry) Close() error {
+	err := z.Deregister()
+	z.conn.Close()
+	return err
+}
+
+// ZookeeperDiscovery 实现基于 ZooKeeper 的服务发现，监听子节点变化
+type ZookeeperDiscovery struct {
+	conn       zkConn
+	services   map[string]ServiceInfo // 服务缓存
+	prefix     string                 // 服务前缀，用于过滤服务
+	mu         sync.RWMutex           // 读写锁
+	stopSignal chan struct{}          // 停止信号
+	subs       subscribers            // 服务变化的订阅者
+}
+
+// NewZookeeperDiscovery 创建一个新的 ZooKeeper 服务发现客户端
+func NewZookeeperDiscovery(endpoints []string, prefix string) (*ZookeeperDiscovery, error) {
+	return newZookeeperDiscovery(newZKDialer(endpoints, 10*time.Second), prefix)
+}
+
+func newZookeeperDiscovery(dial zkDialer, prefix string) (*ZookeeperDiscovery, error) {
+	conn, _, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensurePath(conn, zkServiceDir()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	sd := &ZookeeperDiscovery{
+		conn:       conn,
+		services:   make(map[string]ServiceInfo),
+		prefix:     prefix,
+		stopSignal: make(chan struct{}),
+	}
+
+	// 初始化服务列表并设置监听
+	watch, err := sd.fetchServices()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go sd.watchServices(watch)
+	return sd, nil
+}
+
+// fetchServices 读取所有子节点并设置新的子节点监听
+func (sd *ZookeeperDiscovery) fetchServices() (<-chan zk.Event, error) {
+	dir := zkServiceDir()
+	children, _, watch, err := sd.conn.ChildrenW(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]ServiceInfo, len(children))
+	for _, child := range children {
+		data, _, err := sd.conn.Get(path.Join(dir, child))
+		if err != nil {
+			// 节点可能在读取前随会话结束被删除
+			if !errors.Is(err, zk.ErrNoNode) {
+				log.Printf("Get service node %s error: %v\n", child, err)
+			}
+			continue
+		}
+		var node zkNodeData
+		if err := json.Unmarshal(data, &node); err != nil {
+			log.Printf("Unmarshal service error: %v\n", err)
+			continue
+		}
+		if strings.HasPrefix(node.Key, sd.prefix) {
+			services[node.Key] = node.Info
+		}
+	}
+
+	sd.mu.Lock()
+	for key := range sd.services {
+		if _, ok := services[key]; !ok {
+			log.Printf("Service removed: %s\n", key)
+		}
+	}
+	for key, service := range services {
+		if _, ok := sd.services[key]; !ok {
+			log.Printf("Service added/updated: %s -> %s\n", key, service.Addr)
+		}
+	}
+	sd.subs.diffServices(sd.services, services)
+	sd.services = services
+	sd.mu.Unlock()
+	return watch, nil
+}
+
+// watchServices ZooKeeper 的监听只触发一次，每次触发后重新读取并设置监听
+func (sd *ZookeeperDiscovery) watchServices(watch <-chan zk.Event) {
+	for {
+		select {
+		case <-sd.stopSignal:
+			return
+		case <-watch:
+		}
+
+		var err error
+		for {
+			if watch, err = sd.fetchServices(); err == nil {
+				break
+			}
+			log.Printf("Watch zookeeper services error: %v\n", err)
+			select {
+			case <-sd.stopSignal:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// GetServices 获取所有服务
+func (sd *ZookeeperDiscovery) GetServices() map[string]ServiceInfo {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	services := make(map[string]ServiceInfo, len(sd.services))
+	maps.Copy(services, sd.services)
+	return services
+}
+
+// GetService 获取指定服务
+func (sd *ZookeeperDiscovery) GetService(serviceKey string) (ServiceInfo, bool) {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	service, ok := sd.services[serviceKey]
+	return service, ok
+}
+
+// GetServicesByPrefix 获取指定前缀的服务
+func (sd *ZookeeperDiscovery) GetServicesByPrefix(prefix string) map[string]ServiceInfo {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	services := make(map[string]ServiceInfo)
+	for k, v := range sd.services {
+		if strings.HasPrefix(k, prefix) {
+			services[k] = v
+		}
+	}
+	return services
+}
+
+// Subscribe 订阅指定前缀的服务变化
+func (sd *ZookeeperDiscovery) Subscribe(prefix string, fn func(Event)) func() {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+
+	snapshot := make(map[string]ServiceInfo)
+	for k, v := range sd.services {
+		if strings.HasPrefix(k, prefix) {
+			snapshot[k] = v
+		}
+	}
+	return sd.subs.add(prefix, fn, snapshot)
+}
+
+// Close 停止监听并关闭会话
+func (sd *ZookeeperDiscovery) Close() error {
+	close(sd.stopSignal)
+	sd.subs.closeAll()
+	sd.conn.Close()
+	return nil
+}
@@ -0,0 +1,265 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// fakeZK 是进程内的 ZooKeeper 假服务，支持持久/临时/顺序节点、一次性子节点监听和会话过期
+type fakeZK struct {
+	mu      sync.Mutex
+	nodes   map[string]*fakeZNode
+	seq     map[string]int // 父节点 -> 下一个顺序号
+	watches map[string][]chan zk.Event
+	nextID  int64
+
+	failDelete int // 之后的若干次删除请求返回错误
+}
+
+type fakeZNode struct {
+	data  []byte
+	owner int64 // 临时节点所属的会话，0 表示持久节点
+}
+
+func newFakeZK() *fakeZK {
+	return &fakeZK{
+		nodes:   map[string]*fakeZNode{"/": {}},
+		seq:     make(map[string]int),
+		watches: make(map[string][]chan zk.Event),
+	}
+}
+
+func (f *fakeZK) dial() (zkConn, <-chan zk.Event, error) {
+	f.mu.Lock()
+	f.nextID++
+	c := &fakeZKConn{srv: f, session: f.nextID, events: make(chan zk.Event, 16)}
+	f.mu.Unlock()
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	return c, c.events, nil
+}
+
+// fire 触发父节点上的子节点监听，调用方需持有锁
+func (f *fakeZK) fire(parent string) {
+	for _, ch := range f.watches[parent] {
+		ch <- zk.Event{Type: zk.EventNodeChildrenChanged, Path: parent}
+	}
+	delete(f.watches, parent)
+}
+
+// endSession 删除会话创建的临时节点，调用方需持有锁
+func (f *fakeZK) endSession(session int64) {
+	for p, node := range f.nodes {
+		if node.owner == session {
+			delete(f.nodes, p)
+			f.fire(path.Dir(p))
+		}
+	}
+}
+
+// expire 模拟会话过期：临时节点被删除，客户端随后建立新会话
+func (f *fakeZK) expire(c *fakeZKConn) {
+	f.mu.Lock()
+	f.endSession(c.session)
+	f.nextID++
+	c.session = f.nextID
+	f.mu.Unlock()
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	c.events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+}
+
+func (f *fakeZK) ephemeralNodes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var nodes []string
+	for p, node := range f.nodes {
+		if node.owner != 0 {
+			nodes = append(nodes, p)
+		}
+	}
+	return nodes
+}
+
+type fakeZKConn struct {
+	srv     *fakeZK
+	session int64
+	events  chan zk.Event
+}
+
+func (c *fakeZKConn) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	f := c.srv
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parent := path.Dir(p)
+	if _, ok := f.nodes[parent]; !ok {
+		return "", zk.ErrNoNode
+	}
+	if flags&zk.FlagSequence != 0 {
+		p += fmt.Sprintf("%010d", f.seq[parent])
+		f.seq[parent]++
+	}
+	if _, ok := f.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	node := &fakeZNode{data: data}
+	if flags&zk.FlagEphemeral != 0 {
+		node.owner = c.session
+	}
+	f.nodes[p] = node
+	f.fire(parent)
+	return p, nil
+}
+
+func (c *fakeZKConn) Get(p string) ([]byte, *zk.Stat, error) {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+	node, ok := c.srv.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return node.data, &zk.Stat{}, nil
+}
+
+func (c *fakeZKConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	f := c.srv
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.nodes[p]; !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	var children []string
+	for child := range f.nodes {
+		if child != p && path.Dir(child) == p {
+			children = append(children, path.Base(child))
+		}
+	}
+	ch := make(chan zk.Event, 1)
+	f.watches[p] = append(f.watches[p], ch)
+	return children, &zk.Stat{}, ch, nil
+}
+
+func (c *fakeZKConn) Delete(p string, version int32) error {
+	f := c.srv
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failDelete > 0 {
+		f.failDelete--
+		return zk.ErrConnectionClosed
+	}
+	if _, ok := f.nodes[p]; !ok {
+		return zk.ErrNoNode
+	}
+	delete(f.nodes, p)
+	f.fire(path.Dir(p))
+	return nil
+}
+
+func (c *fakeZKConn) Close() {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+	c.srv.endSession(c.session)
+}
+
+func TestZookeeperRegistryAndDiscovery(t *testing.T) {
+	fake := newFakeZK()
+
+	reg, err := newZookeeperRegistry(fake.dial)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	defer reg.Close()
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr, Metadata: map[string]string{"zone": "a"}}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	if !strings.HasPrefix(reg.node, zkServiceDir()+"/"+zkNodePrefix) {
+		t.Fatalf("unexpected node path %s", reg.node)
+	}
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr}); err == nil {
+		t.Fatal("expected error when registering twice")
+	}
+
+	disc, err := newZookeeperDiscovery(fake.dial, testPrefix)
+	if err != nil {
+		t.Fatalf("Failed to create discovery: %v", err)
+	}
+	defer disc.Close()
+
+	service, ok := disc.GetService(testKey)
+	if !ok || service.Addr != testAddr || service.Metadata["zone"] != "a" {
+		t.Fatalf("GetService(%s) = %+v, %v", testKey, service, ok)
+	}
+
+	// 监听到新注册的节点
+	otherKey := testPrefix + "other-service"
+	other, _ := newZookeeperRegistry(fake.dial)
+	if err := other.Register(otherKey, ServiceInfo{Addr: "http://localhost:8002"}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	waitFor(t, "new service", func() bool {
+		return len(disc.GetServicesByPrefix(testPrefix)) == 2
+	})
+
+	// 会话过期后临时节点被删除，新会话建立后重新注册
+	other.mu.Lock()
+	oldNode := other.node
+	other.mu.Unlock()
+	fake.expire(other.conn.(*fakeZKConn))
+	waitFor(t, "re-registration", func() bool {
+		other.mu.Lock()
+		defer other.mu.Unlock()
+		return other.node != oldNode
+	})
+	if nodes := fake.ephemeralNodes(); len(nodes) != 2 {
+		t.Fatalf("expected 2 ephemeral nodes after re-registration, got %v", nodes)
+	}
+	waitFor(t, "re-registered service", func() bool {
+		_, ok := disc.GetService(otherKey)
+		return ok
+	})
+
+	// 会话关闭后节点被删除
+	other.Close()
+	waitFor(t, "service removal", func() bool {
+		_, ok := disc.GetService(otherKey)
+		return !ok
+	})
+	if services := disc.GetServices(); len(services) != 1 {
+		t.Fatalf("expected 1 service, got %v", services)
+	}
+}
+
+func TestZookeeperDeregisterRetry(t *testing.T) {
+	fake := newFakeZK()
+	reg, err := newZookeeperRegistry(fake.dial)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	if err := reg.Register(testKey, ServiceInfo{Addr: testAddr}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	// 删除节点失败时服务仍然注册，重试注销和关闭不会 panic
+	fake.mu.Lock()
+	fake.failDelete = 1
+	fake.mu.Unlock()
+	if err := reg.Deregister(); err == nil {
+		t.Fatal("expected the first deregister to fail")
+	}
+	if nodes := fake.ephemeralNodes(); len(nodes) != 1 {
+		t.Fatalf("node should survive a failed deregister, got %v", nodes)
+	}
+	if err := reg.Deregister(); err != nil {
+		t.Fatalf("Failed to retry deregister: %v", err)
+	}
+	if err := reg.Close(); err != nil {
+		t.Fatalf("Close after deregister: %v", err)
+	}
+	if nodes := fake.ephemeralNodes(); len(nodes) != 0 {
+		t.Fatalf("expected no nodes after deregister, got %v", nodes)
+	}
+}
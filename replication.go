@@ -0,0 +1,280 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	pb "geecache/geecachepb"
+	"log"
+)
+
+// ReadConsistency is the number of replicas that must answer a read before
+// a cached value is trusted.
+type ReadConsistency string
+
+const (
+	ReadOne    ReadConsistency = "one"    // 任意一个副本命中即可
+	ReadQuorum ReadConsistency = "quorum" // 多数副本响应
+	ReadAll    ReadConsistency = "all"    // 所有副本响应
+)
+
+// required 返回 n 个副本时需要响应的副本数量
+func (c ReadConsistency) required(n int) int {
+	switch c {
+	case ReadAll:
+		return n
+	case ReadQuorum:
+		return n/2 + 1
+	default:
+		return 1
+	}
+}
+
+// SetReplication 设置每个key的副本数量和读一致性级别，应在使用组之前调用。
+// 副本为哈希环上从主节点开始顺时针的 n 个节点，回源加载的数据会写入所有副本。
+// n 不大于 1 或 PeerPicker 未实现 ReplicaPicker 时只使用主节点。
+func (g *Group) SetReplication(n int, level ReadConsistency) {
+	if n < 1 {
+		n = 1
+	}
+	g.replication = n
+	g.consistency = level
+}
+
+func (g *Group) replicaPicker() (ReplicaPicker, bool) {
+	if g.replication <= 1 || g.peers == nil {
+		return nil, false
+	}
+	rp, ok := g.peers.(ReplicaPicker)
+	return rp, ok
+}
+
+// replicaReply 是一个副本对只读缓存请求的响应
+type replicaReply struct {
+	index int // 副本在哈希环上的顺序
+	view  ByteView
+	hit   bool
+	lease uint64 // 副本未命中时发放的租约
+	err   error
+}
+
+// getReplicated 按读一致性级别读取副本：副本间的数据不一致时以多数副本的值为准，
+// 票数相同时以靠近主节点的副本为准，并将该值修复到缺失或不一致的副本上。
+// 响应的副本不足或均未命中时回源加载，加载的数据会写入所有副本。
+func (g *Group) getReplicated(ctx context.Context, rp ReplicaPicker, key string) (ByteView, error) {
+	viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		replicas := rp.PickReplicas(key, g.replication)
+		required := g.consistency.required(len(replicas))
+		replies, ok := g.readReplicas(ctx, key, replicas, required)
+
+		if ok {
+			if view, found := g.resolveReplicas(key, replicas, replies); found {
+				g.stats.hits.Add(1)
+				return view, nil
+			}
+		} else {
+			log.Printf("[GeeCache] Only %d of %d replicas answered for key %s", len(replies), required, key)
+			g.releaseLeases(key, replicas, replies)
+		}
+		g.stats.misses.Add(1)
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// 本节点不是副本时交给主节点加载，由主节点写入所有副本
+		if len(replicas) > 0 && !containsSelf(replicas) {
+			value, err := g.getFromPeer(ctx, replicas[0], key)
+			if err == nil {
+				return value, nil
+			}
+			if errors.Is(err, ErrNotFound) {
+				g.cacheNotFound(key, err)
+				return nil, err
+			}
+			log.Println("[GeeCache] Failed to get from peer", err)
+		}
+		if value, ok := g.getFromPreviousOwner(ctx, key); ok {
+			return value, nil
+		}
+		return g.getLocally(ctx, key)
+	})
+	if err != nil {
+		return ByteView{}, err
+	}
+	return viewi.(ByteView), nil
+}
+
+// readReplicas 并行读取各副本的缓存，收到足够的响应后返回。
+// ReadOne 会等待第一个命中的副本，其余级别等待 required 个成功的响应。
+// 提前返回时不取消其余的读取，之后到达的响应中的租约在后台释放
+func (g *Group) readReplicas(ctx context.Context, key string, replicas []PeerGetter, required int) ([]replicaReply, bool) {
+	replyChan := make(chan replicaReply, len(replicas))
+	received := 0
+	defer func() {
+		if pending := len(replicas) - received; pending > 0 {
+			go g.releaseLateReplies(key, replicas, replyChan, pending)
+		}
+	}()
+	for i, replica := range replicas {
+		go func(i int, p PeerGetter) {
+			reply := replicaReply{index: i}
+			if p == nil {
+				reply.view, reply.hit = g.lookupLocally(key)
+			} else {
+				res := &pb.Response{}
+				// 未命中的副本由 resolveReplicas 修复，需要租约
+				req := &pb.Request{Group: g.name, Key: key, CacheOnly: true, WantLease: true, Generation: g.Generation()}
+				reply.err = p.Get(ctx, req, res)
+				if reply.err == nil {
+					g.observeGeneration(res.GetGeneration())
+				}
+				reply.hit = reply.err == nil && !res.GetMiss()
+				if reply.hit {
+					reply.view = viewFromResponse(res)
+				}
+				reply.lease = res.GetLease()
+			}
+			replyChan <- reply
+		}(i, replica)
+	}
+
+	var replies []replicaReply
+	hits := 0
+	for received < len(replicas) {
+		var reply replicaReply
+		select {
+		case reply = <-replyChan:
+			received++
+		case <-ctx.Done():
+			return replies, false
+		}
+		if reply.err != nil {
+			log.Printf("[GeeCache] Failed to read replica of %s: %v", key, reply.err)
+			continue
+		}
+		replies = append(replies, reply)
+		if reply.hit {
+			hits++
+		}
+		if g.consistency == ReadOne && hits > 0 ||
+			g.consistency != ReadOne && len(replies) >= required {
+			return replies, true
+		}
+	}
+	return replies, len(replies) >= required
+}
+
+// releaseLateReplies 接收提前返回后到达的 pending 个响应，释放其中的租约
+func (g *Group) releaseLateReplies(key string, replicas []PeerGetter, replyChan <-chan replicaReply, pending int) {
+	for i := 0; i < pending; i++ {
+		reply := <-replyChan
+		if reply.err == nil {
+			g.releaseLeases(key, replicas, []replicaReply{reply})
+		}
+	}
+}
+
+// releaseLeases 释放不会用于修复副本的租约，避免副本在租约有效期内拒绝自己的写入
+func (g *Group) releaseLeases(key string, replicas []PeerGetter, replies []replicaReply) {
+	for _, reply := range replies {
+		if reply.lease == 0 || replicas[reply.index] == nil {
+			continue
+		}
+		go func(peer PeerGetter, lease uint64) {
+			req := &pb.Request{Group: g.name, Key: key, Lease: lease, Generation: g.Generation()}
+			if err := peer.Delete(req); err != nil {
+				log.Printf("[GeeCache] Failed to release lease of %s: %v", key, err)
+			}
+		}(replicas[reply.index], reply.lease)
+	}
+}
+
+// resolveReplicas 选出多数副本的值，并异步修复缺失或不一致的副本
+func (g *Group) resolveReplicas(key string, replicas []PeerGetter, replies []replicaReply) (ByteView, bool) {
+	var (
+		winner replicaReply
+		best   int
+	)
+	for _, reply := range replies {
+		if !reply.hit {
+			continue
+		}
+		votes := 0
+		for _, other := range replies {
+			if other.hit && bytes.Equal(other.view.b, reply.view.b) {
+				votes++
+			}
+		}
+		if votes > best || votes == best && reply.index < winner.index {
+			winner, best = reply, votes
+		}
+	}
+	if best == 0 {
+		g.releaseLeases(key, replicas, replies)
+		return ByteView{}, false
+	}
+
+	for _, reply := range replies {
+		if reply.hit && bytes.Equal(reply.view.b, winner.view.b) {
+			continue
+		}
+		log.Printf("[GeeCache] Repairing replica %d of key %s", reply.index, key)
+		if replicas[reply.index] == nil {
+			g.setFromPeer(key, winner.view, 0)
+			continue
+		}
+		go g.setOnPeer(replicas[reply.index], key, winner.view, reply.lease)
+	}
+	return winner.view, true
+}
+
+// replicate 将回源加载的数据写入除本节点外的所有副本
+func (g *Group) replicate(rp ReplicaPicker, key string, value ByteView) {
+	for _, replica := range rp.PickReplicas(key, g.replication) {
+		if replica != nil {
+			go g.setOnPeer(replica, key, value, 0)
+		}
+	}
+}
+
+// setOnPeer 将数据写入其他节点，lease 为该节点发放的租约，0 表示不带租约
+func (g *Group) setOnPeer(peer PeerGetter, key string, value ByteView, lease uint64) {
+	req := &pb.Request{
+		Group:      g.name,
+		Key:        key,
+		Lease:      lease,
+		Generation: g.Generation(),
+	}
+	if err := peer.Set(req, responseFromView(value)); err != nil {
+		log.Printf("[GeeCache] Failed to write replica of %s: %v", key, err)
+	}
+}
+
+// peekLocally 只读取本节点缓存，用于响应其他节点的副本读取和哈希环变化后的交接
+func (g *Group) peekLocally(key string, wantLease bool) *pb.Response {
+	if view, ok := g.lookupLocally(key); ok {
+		return responseFromView(view)
+	}
+	res := &pb.Response{Miss: true}
+	// 读取方会修复本节点的副本时发放租约，由其写入或释放
+	if wantLease && g.leases != nil {
+		res.Lease, _, _ = g.leases.acquire(key)
+	}
+	return res
+}
+
+// releaseLease 释放其他节点不再使用的租约
+func (g *Group) releaseLease(key string, token uint64) {
+	if g.leases != nil {
+		g.leases.release(key, token)
+	}
+}
+
+func containsSelf(replicas []PeerGetter) bool {
+	for _, replica := range replicas {
+		if replica == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	pb "geecache/geecachepb"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// replicaPeer 是只保存缓存数据的远程副本
+type replicaPeer struct {
+	fakePeer
+	mu     sync.Mutex
+	values map[string]string
+	peeks  int
+}
+
+func newReplicaPeer(values map[string]string) *replicaPeer {
+	if values == nil {
+		values = make(map[string]string)
+	}
+	return &replicaPeer{values: values}
+}
+
+func (p *replicaPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !in.GetCacheOnly() {
+		return fmt.Errorf("unexpected load request")
+	}
+	p.peeks++
+	v, ok := p.values[in.GetKey()]
+	out.Value, out.Miss = []byte(v), !ok
+	return nil
+}
+
+func (p *replicaPeer) Set(in *pb.Request, out *pb.Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[in.GetKey()] = string(out.GetValue())
+	return nil
+}
+
+func (p *replicaPeer) value(key string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.values[key]
+}
+
+// waitValue 等待异步写入的副本
+func (p *replicaPeer) waitValue(t *testing.T, key, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for p.value(key) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica has %s=%q, want %q", key, p.value(key), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// replicaSet 按给定顺序返回副本，nil 表示本节点
+type replicaSet []PeerGetter
+
+func (s replicaSet) PickPeer(key string) (PeerGetter, bool) {
+	if s[0] == nil {
+		return nil, false
+	}
+	return s[0], true
+}
+
+func (s replicaSet) PickPeers(key string, count int) ([]PeerGetter, bool) {
+	return nil, false
+}
+
+func (s replicaSet) PickReplicas(key string, n int) []PeerGetter {
+	return s[:n]
+}
+
+func newReplicatedGroup(name string, loads *int) *Group {
+	return NewGroup(name, 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		*loads++
+		return []byte("source-" + key), nil
+	}))
+}
+
+func TestReplicatedReadRepair(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-repair", &loads)
+	p1 := newReplicaPeer(map[string]string{"k": "new"})
+	p2 := newReplicaPeer(map[string]string{"k": "new"})
+	g.RegisterPeers(replicaSet{nil, p1, p2})
+	g.SetReplication(3, ReadAll)
+	g.setLocally("k", ByteView{b: []byte("old")})
+
+	view, err := g.Get("k")
+	if err != nil || view.String() != "new" {
+		t.Fatalf("Get(k) = %q, %v; want the majority value", view, err)
+	}
+	if local, _ := g.mainCache.get("k"); local.String() != "new" {
+		t.Fatalf("local replica was not repaired, has %q", local)
+	}
+	if loads != 0 {
+		t.Fatalf("expected no load from source, got %d", loads)
+	}
+}
+
+func TestReplicatedGetMany(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-get-many", &loads)
+	p1 := newReplicaPeer(map[string]string{"k": "new"})
+	p2 := newReplicaPeer(map[string]string{"k": "new"})
+	g.RegisterPeers(replicaSet{nil, p1, p2})
+	g.SetReplication(3, ReadAll)
+	g.setLocally("k", ByteView{b: []byte("old")})
+
+	// 批量读取与 Get 一样按一致性级别读取副本，不直接返回本地的旧值
+	values, errs := g.GetMany([]string{"k", "k"})
+	if len(errs) != 0 || values["k"].String() != "new" {
+		t.Fatalf("GetMany(k) = %v, %v; want the majority value", values, errs)
+	}
+	if local, _ := g.mainCache.get("k"); local.String() != "new" || loads != 0 {
+		t.Fatalf("local replica has %q after %d loads, want it repaired", local, loads)
+	}
+}
+
+func TestReplicatedReadTieBreak(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-tie", &loads)
+	p1 := newReplicaPeer(map[string]string{"k": "a"})
+	p2 := newReplicaPeer(map[string]string{"k": "b"})
+	g.RegisterPeers(replicaSet{p1, nil, p2})
+	g.SetReplication(3, ReadAll)
+
+	// 票数相同时以靠近主节点的副本为准
+	view, err := g.Get("k")
+	if err != nil || view.String() != "a" {
+		t.Fatalf("Get(k) = %q, %v; want the primary's value", view, err)
+	}
+	p2.waitValue(t, "k", "a")
+	if local, _ := g.mainCache.get("k"); local.String() != "a" {
+		t.Fatalf("local replica was not repaired, has %q", local)
+	}
+}
+
+func TestReplicatedLoadPopulatesAll(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-load", &loads)
+	p1, p2 := newReplicaPeer(nil), newReplicaPeer(nil)
+	g.RegisterPeers(replicaSet{nil, p1, p2})
+	g.SetReplication(3, ReadQuorum)
+
+	view, err := g.Get("k")
+	if err != nil || view.String() != "source-k" || loads != 1 {
+		t.Fatalf("Get(k) = %q, %v with %d loads", view, err, loads)
+	}
+	p1.waitValue(t, "k", "source-k")
+	p2.waitValue(t, "k", "source-k")
+
+	// 所有副本一致后不再回源
+	if _, err := g.Get("k"); err != nil || loads != 1 {
+		t.Fatalf("expected value from replicas, got %v with %d loads", err, loads)
+	}
+}
+
+func TestReplicatedReadOne(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-one", &loads)
+	p1 := newReplicaPeer(map[string]string{"remote": "v"})
+	g.RegisterPeers(replicaSet{nil, p1})
+	g.SetReplication(2, ReadOne)
+	g.setLocally("local", ByteView{b: []byte("v")})
+
+	if _, err := g.Get("local"); err != nil || p1.peeks != 0 {
+		t.Fatalf("local hit should not read other replicas, got %v with %d peeks", err, p1.peeks)
+	}
+	if view, err := g.Get("remote"); err != nil || view.String() != "v" || loads != 0 {
+		t.Fatalf("Get(remote) = %q, %v with %d loads", view, err, loads)
+	}
+}
+
+// leasingPeer 是缓存未命中并发放租约的副本，记录被释放的租约
+type leasingPeer struct {
+	fakePeer
+	delay    time.Duration
+	mu       sync.Mutex
+	released []uint64
+}
+
+func (p *leasingPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	time.Sleep(p.delay)
+	out.Miss = true
+	if in.GetWantLease() {
+		out.Lease = 7
+	}
+	return nil
+}
+
+func (p *leasingPeer) Delete(in *pb.Request) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if in.GetLease() != 0 {
+		p.released = append(p.released, in.GetLease())
+	}
+	return nil
+}
+
+func (p *leasingPeer) waitReleased(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		n := len(p.released)
+		p.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the lease to be released once, got %d releases", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplicaLeasesReleased(t *testing.T) {
+	// 所有副本都未命中时不修复副本，租约被释放
+	loads := 0
+	g := newReplicatedGroup("replica-lease-miss", &loads)
+	lp := &leasingPeer{}
+	g.RegisterPeers(replicaSet{nil, lp})
+	g.SetReplication(2, ReadAll)
+	if view, err := g.Get("k"); err != nil || view.String() != "source-k" {
+		t.Fatalf("Get(k) = %q, %v", view, err)
+	}
+	lp.waitReleased(t)
+
+	// ReadOne 收到命中后返回，之后到达的响应中的租约也被释放
+	g2 := newReplicatedGroup("replica-lease-late", &loads)
+	slow := &leasingPeer{delay: 50 * time.Millisecond}
+	g2.RegisterPeers(replicaSet{nil, newReplicaPeer(map[string]string{"k": "v"}), slow})
+	g2.SetReplication(3, ReadOne)
+	if view, err := g2.Get("k"); err != nil || view.String() != "v" {
+		t.Fatalf("Get(k) = %q, %v", view, err)
+	}
+	slow.waitReleased(t)
+}
+
+func TestRemoveReplicas(t *testing.T) {
+	loads := 0
+	g := newReplicatedGroup("replica-remove", &loads)
+	p1, p2 := newReplicaPeer(nil), newReplicaPeer(nil)
+	g.RegisterPeers(replicaSet{nil, p1, p2})
+	g.SetReplication(3, ReadOne)
+
+	if err := g.Remove("k"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p1.deleted) != 1 || len(p2.deleted) != 1 {
+		t.Fatalf("expected both replicas to be notified, got %v and %v", p1.deleted, p2.deleted)
+	}
+}
+
+func TestHTTPCacheOnlyGet(t *testing.T) {
+	g := NewGroup("cache-only", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+
+	req := &pb.Request{Group: "cache-only", Key: "k", CacheOnly: true}
+	res := &pb.Response{}
+	if err := getter.Get(context.Background(), req, res); err != nil || !res.GetMiss() {
+		t.Fatalf("expected a miss without loading, got %v, %v", res, err)
+	}
+
+	g.setLocally("k", ByteView{b: []byte("cached")})
+	res = &pb.Response{}
+	if err := getter.Get(context.Background(), req, res); err != nil || res.GetMiss() || string(res.GetValue()) != "cached" {
+		t.Fatalf("expected cached value, got %v, %v", res, err)
+	}
+}
+
+func TestHTTPPoolPickReplicas(t *testing.T) {
+	pool := NewHTTPPool("http://a")
+	pool.Set("http://a", "http://b", "http://c")
+
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		replicas := pool.PickReplicas(key, 3)
+		if len(replicas) != 3 || !containsSelf(replicas) {
+			t.Fatalf("PickReplicas(%s, 3) = %v", key, replicas)
+		}
+		// 同一key总是选中相同的备份节点
+		first, _ := pool.PickPeers(key, 2)
+		second, _ := pool.PickPeers(key, 2)
+		if len(first) != len(second) {
+			t.Fatalf("PickPeers(%s) is not deterministic", key)
+		}
+		for i := range first {
+			if first[i] != second[i] {
+				t.Fatalf("PickPeers(%s) is not deterministic", key)
+			}
+		}
+	}
+}
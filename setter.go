@@ -0,0 +1,137 @@
+package geecache
+
+import (
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"time"
+)
+
+// A Setter writes data for a key to the source of truth.
+type Setter interface {
+	Set(key string, value []byte) error
+}
+
+// A SetterFunc implements Setter with a function.
+type SetterFunc func(key string, value []byte) error
+
+// Set implements Setter interface function
+func (f SetterFunc) Set(key string, value []byte) error {
+	return f(key, value)
+}
+
+// A BatchSetter writes multiple keys to the source of truth at once. The
+// write-behind queue uses it instead of Set when the Setter implements it.
+type BatchSetter interface {
+	SetMany(values map[string][]byte) error
+}
+
+// ErrNoSetter is returned by Group.Set when the group has no Setter.
+var ErrNoSetter = errors.New("geecache: group has no Setter")
+
+// SetWriteThrough 配置写穿透：Set 先同步写入数据源，成功后再更新缓存。
+// 之前配置了写回时，先停止写回队列并将剩余数据写入之前的 Setter，失败时保持原配置。
+// 所有节点上的同名组应使用相同的配置，应在使用组之前调用
+func (g *Group) SetWriteThrough(setter Setter) error {
+	if err := g.stopWriteBehind(); err != nil {
+		return err
+	}
+	g.setter = setter
+	return nil
+}
+
+// SetWriteBehind 配置写回：Set 将数据写入持久化队列并更新缓存后立即返回，
+// 队列在后台按批写入数据源，失败时退避重试。opts.Store 中尚未写入数据源的数据
+// 在重启后继续写入。之前的写回队列先停止并写入剩余数据，失败时保持原配置。
+// 所有节点上的同名组应使用相同的配置，应在使用组之前调用
+func (g *Group) SetWriteBehind(setter Setter, opts WriteBehindOptions) error {
+	// 新队列可能与旧队列共用存储，旧队列清空后再恢复
+	if err := g.stopWriteBehind(); err != nil {
+		return err
+	}
+	w, err := newWriteBehind(g.name, setter, opts)
+	if err != nil {
+		return err
+	}
+	g.setter = setter
+	g.writeBehind = w
+	return nil
+}
+
+// stopWriteBehind 停止当前的写回队列并写入剩余的数据
+func (g *Group) stopWriteBehind() error {
+	if g.writeBehind == nil {
+		return nil
+	}
+	if err := g.writeBehind.close(); err != nil {
+		return fmt.Errorf("flush previous write-behind queue: %v", err)
+	}
+	g.writeBehind = nil
+	return nil
+}
+
+// Flush 将写回队列中的数据全部写入数据源，用于关闭节点之前。未配置写回时直接返回
+func (g *Group) Flush() error {
+	if g.writeBehind == nil {
+		return nil
+	}
+	return g.writeBehind.flushAll()
+}
+
+// Set 写入key的新值。写请求转发给拥有该key的节点，由拥有者写入数据源
+// 并更新缓存、副本和热点备份，本节点缓存的旧值被删除
+func (g *Group) Set(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if g.setter == nil {
+		return ErrNoSetter
+	}
+
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			// 拥有者不可用时本节点可能回源，需要知道该key存在
+			g.rememberKey(key)
+			// 先删除本地的旧值，拥有者随后推送的副本不会被误删
+			g.removeLocally(key)
+			req := &pb.Request{
+				Group:      g.name,
+				Key:        key,
+				Write:      true,
+				Generation: g.Generation(),
+			}
+			if err := peer.Set(req, &pb.Response{Value: value}); err != nil {
+				return fmt.Errorf("write %s to owner: %v", key, err)
+			}
+			return nil
+		}
+	}
+	return g.write(key, value)
+}
+
+// write 在拥有者节点上写入数据源并更新缓存
+func (g *Group) write(key string, value []byte) error {
+	if g.setter == nil {
+		return ErrNoSetter
+	}
+	value = cloneBytes(value)
+	if g.writeBehind != nil {
+		if err := g.writeBehind.enqueue(key, value); err != nil {
+			return err
+		}
+	} else if err := g.setter.Set(key, value); err != nil {
+		return err
+	}
+
+	view := ByteView{b: value}
+	if g.defaultTTL > 0 {
+		view.e = time.Now().Add(g.defaultTTL)
+	}
+	g.removeFromL2(key)
+	// 作废写入之前开始的加载持有的租约，避免其写入旧值
+	if g.leases != nil {
+		g.leases.invalidate(key)
+	}
+	g.populateCache(key, view)
+	return nil
+}
@@ -0,0 +1,252 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	pb "geecache/geecachepb"
+	"geecache/storage"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB 是测试用的数据源，failures 次写入失败后恢复
+type fakeDB struct {
+	mu       sync.Mutex
+	values   map[string]string
+	failures int
+	batches  int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{values: make(map[string]string)}
+}
+
+func (db *fakeDB) Get(key string) ([]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return []byte(db.values[key]), nil
+}
+
+func (db *fakeDB) Set(key string, value []byte) error {
+	return db.SetMany(map[string][]byte{key: value})
+}
+
+func (db *fakeDB) SetMany(values map[string][]byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.failures > 0 {
+		db.failures--
+		return errors.New("db unavailable")
+	}
+	db.batches++
+	for k, v := range values {
+		db.values[k] = string(v)
+	}
+	return nil
+}
+
+func (db *fakeDB) value(key string) string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.values[key]
+}
+
+// writePeer 记录转发给拥有者的写请求
+type writePeer struct {
+	fakePeer
+	writes map[string]string
+}
+
+func (p *writePeer) Set(in *pb.Request, out *pb.Response) error {
+	if in.GetWrite() {
+		p.writes[in.GetKey()] = string(out.GetValue())
+	}
+	return nil
+}
+
+func (p *writePeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	v, ok := p.writes[in.GetKey()]
+	if !ok {
+		out.NotFound = true
+		return notFoundError(in.GetKey())
+	}
+	out.Value = []byte(v)
+	return nil
+}
+
+func TestGroupSetWriteThrough(t *testing.T) {
+	db := newFakeDB()
+	db.values["k"] = "old"
+	g := NewGroup("write-through", 2<<10, db)
+	if err := g.Set("k", []byte("new")); !errors.Is(err, ErrNoSetter) {
+		t.Fatalf("Set without Setter error = %v, want ErrNoSetter", err)
+	}
+	g.SetWriteThrough(db)
+
+	g.Get("k")
+	if err := g.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if view, _ := g.mainCache.get("k"); db.value("k") != "new" || view.String() != "new" {
+		t.Fatalf("db has %q and cache has %q, want new", db.value("k"), view)
+	}
+
+	// 写入数据源失败时不更新缓存
+	db.failures = 1
+	if err := g.Set("k", []byte("lost")); err == nil {
+		t.Fatal("expected error when the Setter fails")
+	}
+	if view, _ := g.mainCache.get("k"); view.String() != "new" {
+		t.Fatalf("cache has %q after a failed write, want new", view)
+	}
+
+	// 其他节点通过 HTTP 转发的写请求
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath, client: srv.Client()}
+	req := &pb.Request{Group: "write-through", Key: "remote", Write: true}
+	if err := getter.Set(req, &pb.Response{Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if view, _ := g.mainCache.get("remote"); db.value("remote") != "v" || view.String() != "v" {
+		t.Fatalf("forwarded write not applied, db has %q and cache has %q", db.value("remote"), view)
+	}
+}
+
+func TestGroupSetRoutesToOwner(t *testing.T) {
+	db := newFakeDB()
+	g := NewGroup("write-route", 2<<10, db)
+	g.SetWriteThrough(db)
+	owner := &writePeer{writes: make(map[string]string)}
+	g.RegisterPeers(replicaSet{owner})
+	g.setLocally("k", ByteView{b: []byte("old")})
+
+	if err := g.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if owner.writes["k"] != "new" || db.value("k") != "" {
+		t.Fatalf("write should be forwarded to the owner, owner got %v", owner.writes)
+	}
+	if _, ok := g.mainCache.get("k"); ok {
+		t.Fatal("stale local copy should be removed")
+	}
+}
+
+func TestGroupSetWriteBehind(t *testing.T) {
+	db := newFakeDB()
+	db.values["a"] = "old"
+	db.failures = 2
+	g := NewGroup("write-behind", 2<<10, db)
+	store, _ := storage.NewMemoryStorage(storage.StorageOptions{})
+	defer store.Close()
+	err := g.SetWriteBehind(db, WriteBehindOptions{
+		Store:         store,
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Set("a", []byte("1"))
+	g.Set("a", []byte("2"))
+	g.Set("b", []byte("2"))
+	if db.value("a") != "old" {
+		t.Fatal("write-behind should not write to the source synchronously")
+	}
+	// 缓存被淘汰后读取队列中的值，而不是数据源中的旧值
+	g.mainCache.remove("a")
+	if view, err := g.Get("a"); err != nil || view.String() != "2" {
+		t.Fatalf("Get(a) = %q, %v, want the pending value", view, err)
+	}
+
+	// 积压达到批量大小后写入，失败时重试
+	g.Set("c", []byte("3"))
+	deadline := time.Now().Add(time.Second)
+	for db.value("c") != "3" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for write-behind flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if db.value("a") != "2" || db.value("b") != "2" || db.batches != 1 {
+		t.Fatalf("db has %v after %d batches, want one batch", db.values, db.batches)
+	}
+	if keys, _ := store.Keys(); len(keys) != 0 {
+		t.Fatalf("flushed writes should be removed from the store, got %v", keys)
+	}
+}
+
+func TestGroupReplaceWriteBehind(t *testing.T) {
+	old := newFakeDB()
+	g := NewGroup("write-behind-replace", 2<<10, old)
+	store, _ := storage.NewMemoryStorage(storage.StorageOptions{})
+	defer store.Close()
+	opts := WriteBehindOptions{Store: store, FlushInterval: time.Hour}
+	if err := g.SetWriteBehind(old, opts); err != nil {
+		t.Fatal(err)
+	}
+	g.Set("a", []byte("1"))
+
+	// 切换为写穿透前先写入旧队列中剩余的数据，之后旧的 Setter 不再收到写入
+	db := newFakeDB()
+	if err := g.SetWriteThrough(db); err != nil {
+		t.Fatal(err)
+	}
+	if old.value("a") != "1" {
+		t.Fatalf("pending write not flushed before replacing the queue, db has %v", old.values)
+	}
+	if keys, _ := store.Keys(); len(keys) != 0 {
+		t.Fatalf("flushed writes should be removed from the store, got %v", keys)
+	}
+	g.Set("a", []byte("2"))
+	if db.value("a") != "2" || old.value("a") != "1" {
+		t.Fatalf("old setter has %v, new setter has %v", old.values, db.values)
+	}
+
+	// 旧队列写入失败时保持原配置
+	if err := g.SetWriteBehind(old, opts); err != nil {
+		t.Fatal(err)
+	}
+	g.Set("b", []byte("3"))
+	old.failures = 1
+	if err := g.SetWriteBehind(db, opts); err == nil {
+		t.Fatal("expected error when the previous queue cannot be flushed")
+	}
+	if g.setter != old || g.writeBehind == nil {
+		t.Fatal("failed replacement should keep the previous write-behind queue")
+	}
+	if err := g.Flush(); err != nil || old.value("b") != "3" {
+		t.Fatalf("Flush() = %v, db has %v", err, old.values)
+	}
+}
+
+func TestWriteBehindRecover(t *testing.T) {
+	store, _ := storage.NewMemoryStorage(storage.StorageOptions{})
+	defer store.Close()
+	opts := WriteBehindOptions{Store: store, FlushInterval: time.Hour}
+
+	down := newFakeDB()
+	down.failures = 1
+	w, err := newWriteBehind("recover", down, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.enqueue("k", []byte("v"))
+	if err := w.flushAll(); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	// 重启后继续写入上次未写入的数据
+	db := newFakeDB()
+	w, err = newWriteBehind("recover", db, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flushAll(); err != nil || db.value("k") != "v" {
+		t.Fatalf("recovered write not flushed: %v, db has %v", err, db.values)
+	}
+}
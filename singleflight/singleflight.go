@@ -1,12 +1,16 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"sync"
+)
 
 // call is an in-flight or completed Do call
 type call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	done chan struct{} // closed when fn returns
+	val  interface{}
+	err  error
 }
 
 // Group represents a class of work and forms a namespace in which
@@ -21,26 +25,66 @@ type Group struct {
 // time. If a duplicate comes in, the duplicate caller waits for the
 // original to complete and receives the same results.
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c, leader := g.join(key)
+	if !leader {
+		<-c.done
+		return c.val, c.err
+	}
+	g.run(key, c, fn)
+	return c.val, c.err
+}
+
+// DoContext is like Do but fn receives the caller's context, and each
+// caller stops waiting when its own context is done. A result that failed
+// only because the leader's context was canceled or timed out is not
+// handed to the other waiters: callers whose context is still alive retry
+// instead, so one impatient caller cannot poison the result for the rest.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	for {
+		c, leader := g.join(key)
+		if leader {
+			g.run(key, c, func() (interface{}, error) { return fn(ctx) })
+			return c.val, c.err
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextErr(c.err) && ctx.Err() == nil {
+			// 领头调用者的上下文被取消，结果不可共享，重新发起
+			continue
+		}
+		return c.val, c.err
+	}
+}
+
+// join returns the in-flight call for key, creating one if none exists.
+// leader reports whether the caller created the call and must run it.
+func (g *Group) join(key string) (c *call, leader bool) {
 	g.mu.Lock()
+	defer g.mu.Unlock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
-		g.mu.Unlock()
-		c.wg.Wait()
-		return c.val, c.err
+		return c, false
 	}
-	c := new(call)
-	c.wg.Add(1)
+	c = &call{done: make(chan struct{})}
 	g.m[key] = c
-	g.mu.Unlock()
+	return c, true
+}
 
+func (g *Group) run(key string, c *call, fn func() (interface{}, error)) {
 	c.val, c.err = fn()
-	c.wg.Done()
+	close(c.done)
 
 	g.mu.Lock()
 	delete(g.m, key)
 	g.mu.Unlock()
+}
 
-	return c.val, c.err
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -1,7 +1,9 @@
 package singleflight
 
 import (
+	"context"
 	"testing"
+	"time"
 )
 
 func TestDo(t *testing.T) {
@@ -14,3 +16,65 @@ func TestDo(t *testing.T) {
 		t.Errorf("Do v = %v, error = %v", v, err)
 	}
 }
+
+func TestDoContextCancelDoesNotPoison(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	leaderCtx, cancel := context.WithCancel(context.Background())
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := g.DoContext(leaderCtx, "key", func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	waiter := make(chan interface{}, 1)
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+			return "bar", nil
+		})
+		waiter <- v
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("leader error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case v := <-waiter:
+		if v != "bar" {
+			t.Errorf("waiter got %v, want bar", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter did not retry after leader was canceled")
+	}
+}
+
+func TestDoContextWaiterTimeout(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	go g.Do("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		return "baz", nil
+	}); err != context.DeadlineExceeded {
+		t.Errorf("DoContext error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -1,8 +1,13 @@
+//go:build cppstorage
+
+// C++ 存储引擎需要先在 cpp 目录执行 make 生成 libstorage.so，
+// 再使用 -tags cppstorage 构建
+
 package storage
 
 /*
 #cgo CXXFLAGS: -std=c++11
-#cgo LDFLAGS: -lstdc++
+#cgo LDFLAGS: -L${SRCDIR}/cpp -Wl,-rpath,${SRCDIR}/cpp -lstorage -lstdc++
 #include <stdlib.h>
 #include "cpp/storage_wrapper.h"
 */
@@ -10,6 +15,7 @@ import "C"
 import (
 	"errors"
 	"runtime"
+	"time"
 	"unsafe"
 )
 
@@ -82,7 +88,7 @@ func (s *CppStorage) Get(key string) ([]byte, error) {
 	var valueLen C.int
 	cValue := C.storage_get(s.handle, cKey, C.int(len(key)), &valueLen)
 	if cValue == nil {
-		return nil, errors.New("key not found")
+		return nil, ErrNotFound
 	}
 	defer C.free(unsafe.Pointer(cValue))
 
@@ -111,7 +117,7 @@ func (s *CppStorage) Set(key string, value []byte) error {
 }
 
 // SetWithExpire 设置指定键的值，并指定过期时间
-func (s *CppStorage) SetWithExpire(key string, value []byte, expire int64) error {
+func (s *CppStorage) SetWithExpire(key string, value []byte, expire time.Duration) error {
 	if s.handle == nil {
 		return errors.New("storage is closed")
 	}
@@ -122,7 +128,7 @@ func (s *CppStorage) SetWithExpire(key string, value []byte, expire int64) error
 	cValue := C.CBytes(value)
 	defer C.free(unsafe.Pointer(cValue))
 
-	result := C.storage_set_with_expire(s.handle, cKey, C.int(len(key)), (*C.char)(cValue), C.int(len(value)), C.longlong(expire))
+	result := C.storage_set_with_expire(s.handle, cKey, C.int(len(key)), (*C.char)(cValue), C.int(len(value)), C.longlong(expire.Milliseconds()))
 	if result == 0 {
 		return errors.New("failed to set value with expire")
 	}
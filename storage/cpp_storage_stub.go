@@ -0,0 +1,23 @@
+//go:build !cppstorage
+
+package storage
+
+import "errors"
+
+// errCppStorageDisabled 未使用 cppstorage 构建标签时 C++ 存储引擎不可用
+var errCppStorageDisabled = errors.New("C++ storage is not built in, run make in storage/cpp and build with -tags cppstorage")
+
+// NewCppMemoryStorage 创建一个新的C++内存存储引擎
+func NewCppMemoryStorage(options StorageOptions) (Storage, error) {
+	return nil, errCppStorageDisabled
+}
+
+// NewCppLevelDBStorage 创建一个新的C++ LevelDB存储引擎
+func NewCppLevelDBStorage(options StorageOptions) (Storage, error) {
+	return nil, errCppStorageDisabled
+}
+
+// NewCppRocksDBStorage 创建一个新的C++ RocksDB存储引擎
+func NewCppRocksDBStorage(options StorageOptions) (Storage, error) {
+	return nil, errCppStorageDisabled
+}
@@ -0,0 +1,537 @@
+package storage
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"geecache/compression"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DiskStorage 是纯 Go 实现的日志结构磁盘存储引擎(类似 Bitcask)：
+// 所有写入追加到数据文件末尾，内存中的哈希索引记录每个键最新值的位置。
+// 每条记录带 CRC 校验，重启时从头扫描数据文件重建索引，末尾写了一半的记录会被截断。
+// 被覆盖和删除的记录在垃圾数据较多时通过压缩清理，压缩写入临时文件后原子替换数据文件。
+
+const (
+	diskDataFile    = "data.log"
+	diskCompactFile = "data.log.compact"
+
+	// 记录头：crc(4) + 过期时间(8) + 标志(1) + 键长度(4) + 值长度(4)
+	diskHeaderSize = 21
+	// 单条记录的键和值长度上限，超过时视为损坏的记录
+	diskMaxKeySize   = 1 << 16
+	diskMaxValueSize = 1 << 30
+
+	// 垃圾数据超过该大小且超过有效数据时压缩
+	diskMinCompactBytes = 1 << 20
+	// 未开启 SyncWrites 时后台同步到磁盘的间隔
+	diskSyncInterval = time.Second
+	// 从索引中清理过期键的间隔
+	diskExpireInterval = time.Minute
+)
+
+const (
+	diskFlagDelete     byte = 1 << iota // 删除标记
+	diskFlagCompressed                  // 值经过压缩
+)
+
+// diskEntry 是索引中一个键的最新记录
+type diskEntry struct {
+	offset int64 // 值在数据文件中的偏移
+	size   int   // 值在数据文件中的长度
+	expire int64 // 过期时间(UnixNano)，0 表示永不过期
+	flags  byte
+	elem   *list.Element // 在写入顺序中的位置
+}
+
+func (e *diskEntry) expired(now int64) bool {
+	return e.expire > 0 && now > e.expire
+}
+
+// DiskStorage 磁盘存储引擎
+type DiskStorage struct {
+	mu      sync.RWMutex
+	dir     string
+	file    *os.File
+	offset  int64 // 数据文件末尾
+	index   map[string]*diskEntry
+	order   *list.List // 按写入顺序排列的键，超出容量时先淘汰最早写入的键
+	maxSize int64
+	size    int64 // 有效数据的大小(键 + 值)
+	garbage int64 // 被覆盖、删除或过期的记录占用的字节数
+
+	compressor compression.Compressor // nil 表示不压缩
+	syncWrites bool
+	dirty      bool // 有尚未同步到磁盘的写入
+
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// NewDiskStorage 打开或创建 options.Path 目录下的磁盘存储，并从数据文件恢复索引
+func NewDiskStorage(options StorageOptions) (*DiskStorage, error) {
+	if options.Path == "" {
+		return nil, errors.New("disk storage requires a path")
+	}
+	if err := os.MkdirAll(options.Path, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage directory error: %v", err)
+	}
+	// 上次压缩未完成时残留的临时文件，数据文件仍然完整
+	os.Remove(filepath.Join(options.Path, diskCompactFile))
+
+	file, err := os.OpenFile(filepath.Join(options.Path, diskDataFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open data file error: %v", err)
+	}
+
+	s := &DiskStorage{
+		dir:        options.Path,
+		file:       file,
+		index:      make(map[string]*diskEntry),
+		order:      list.New(),
+		maxSize:    options.MaxSize,
+		syncWrites: options.SyncWrites,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if options.Compression {
+		s.compressor, _ = compression.NewSnappyCompressor(compression.CompressionLevelDefault)
+	}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	go s.background()
+	return s, nil
+}
+
+// load 扫描数据文件重建索引，遇到不完整或校验失败的记录时截断文件
+func (s *DiskStorage) load() error {
+	r := bufio.NewReader(s.file)
+	header := make([]byte, diskHeaderSize)
+	now := time.Now().UnixNano()
+	var offset int64
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				return fmt.Errorf("read data file error: %v", err)
+			}
+			break
+		}
+		expire, flags, keyLen, valueLen := decodeDiskHeader(header)
+		if keyLen > diskMaxKeySize || valueLen > diskMaxValueSize {
+			break
+		}
+		body := make([]byte, keyLen+valueLen)
+		if _, err := io.ReadFull(r, body); err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				return fmt.Errorf("read data file error: %v", err)
+			}
+			break
+		}
+		if diskChecksum(header[4:], body) != binary.BigEndian.Uint32(header) {
+			break
+		}
+
+		key := string(body[:keyLen])
+		recordSize := int64(diskHeaderSize + keyLen + valueLen)
+		s.removeLocked(key)
+		if flags&diskFlagDelete != 0 || (expire > 0 && now > expire) {
+			s.garbage += recordSize
+		} else {
+			s.addLocked(key, &diskEntry{
+				offset: offset + diskHeaderSize + int64(keyLen),
+				size:   valueLen,
+				expire: expire,
+				flags:  flags,
+			})
+		}
+		offset += recordSize
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat data file error: %v", err)
+	}
+	if info.Size() > offset {
+		// 崩溃时写了一半的记录
+		log.Printf("[DiskStorage] Truncating %d bytes of incomplete records", info.Size()-offset)
+		if err := s.file.Truncate(offset); err != nil {
+			return fmt.Errorf("truncate data file error: %v", err)
+		}
+	}
+	s.offset = offset
+	return nil
+}
+
+// Get 获取指定键的值
+func (s *DiskStorage) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, errors.New("storage is closed")
+	}
+
+	entry, ok := s.index[key]
+	if !ok || entry.expired(time.Now().UnixNano()) {
+		return nil, fmt.Errorf("key %s: %w", key, ErrNotFound)
+	}
+	value := make([]byte, entry.size)
+	if _, err := s.file.ReadAt(value, entry.offset); err != nil {
+		return nil, fmt.Errorf("read value error: %v", err)
+	}
+	if entry.flags&diskFlagCompressed != 0 {
+		return s.decompress(value)
+	}
+	return value, nil
+}
+
+func (s *DiskStorage) decompress(value []byte) ([]byte, error) {
+	compressor := s.compressor
+	if compressor == nil {
+		// 以不压缩的方式重新打开时仍能读取之前压缩的值
+		compressor, _ = compression.NewSnappyCompressor(compression.CompressionLevelDefault)
+	}
+	return compressor.Decompress(value)
+}
+
+// Set 设置指定键的值
+func (s *DiskStorage) Set(key string, value []byte) error {
+	return s.SetWithExpire(key, value, 0)
+}
+
+// SetWithExpire 设置指定键的值，并指定过期时间
+func (s *DiskStorage) SetWithExpire(key string, value []byte, expire time.Duration) error {
+	if len(key) > diskMaxKeySize || len(value) > diskMaxValueSize {
+		return fmt.Errorf("key or value too large")
+	}
+
+	var flags byte
+	if s.compressor != nil {
+		// 只保存能变小的压缩结果
+		if compressed, err := s.compressor.Compress(value); err == nil && len(compressed) < len(value) {
+			value = compressed
+			flags |= diskFlagCompressed
+		}
+	}
+	var expireAt int64
+	if expire > 0 {
+		expireAt = time.Now().Add(expire).UnixNano()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("storage is closed")
+	}
+
+	entrySize := int64(len(key) + len(value))
+	if s.maxSize > 0 && entrySize > s.maxSize {
+		return fmt.Errorf("storage size limit exceeded")
+	}
+
+	offset, err := s.appendLocked(key, value, expireAt, flags)
+	if err != nil {
+		return err
+	}
+	s.removeLocked(key)
+	s.addLocked(key, &diskEntry{offset: offset, size: len(value), expire: expireAt, flags: flags})
+
+	// 超出容量时淘汰最早写入的键，写入删除标记保证重启后不会恢复
+	for s.maxSize > 0 && s.size > s.maxSize {
+		oldest := s.order.Front().Value.(string)
+		if _, err := s.appendLocked(oldest, nil, 0, diskFlagDelete); err != nil {
+			return err
+		}
+		s.removeLocked(oldest)
+	}
+
+	return s.afterWriteLocked()
+}
+
+// Delete 删除指定键
+func (s *DiskStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("storage is closed")
+	}
+	if _, ok := s.index[key]; !ok {
+		return nil
+	}
+	if _, err := s.appendLocked(key, nil, 0, diskFlagDelete); err != nil {
+		return err
+	}
+	s.removeLocked(key)
+	return s.afterWriteLocked()
+}
+
+// Has 判断指定键是否存在
+func (s *DiskStorage) Has(key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.index[key]
+	return ok && !entry.expired(time.Now().UnixNano()), nil
+}
+
+// Keys 获取所有键
+func (s *DiskStorage) Keys() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now().UnixNano()
+	keys := make([]string, 0, len(s.index))
+	for key, entry := range s.index {
+		if !entry.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Clear 清空存储
+func (s *DiskStorage) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("storage is closed")
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncate data file error: %v", err)
+	}
+	s.offset, s.size, s.garbage = 0, 0, 0
+	s.index = make(map[string]*diskEntry)
+	s.order.Init()
+	return s.file.Sync()
+}
+
+// Close 将未同步的写入刷到磁盘并关闭数据文件
+func (s *DiskStorage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// Compact 重写数据文件，只保留有效的记录
+func (s *DiskStorage) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("storage is closed")
+	}
+	return s.compactLocked()
+}
+
+// appendLocked 在数据文件末尾追加一条记录，返回值的偏移。调用方需持有写锁
+func (s *DiskStorage) appendLocked(key string, value []byte, expire int64, flags byte) (int64, error) {
+	record := encodeDiskRecord(key, value, expire, flags)
+	if _, err := s.file.WriteAt(record, s.offset); err != nil {
+		// 丢弃写了一半的记录
+		s.file.Truncate(s.offset)
+		return 0, fmt.Errorf("write data file error: %v", err)
+	}
+	offset := s.offset + diskHeaderSize + int64(len(key))
+	s.offset += int64(len(record))
+	s.dirty = true
+	if flags&diskFlagDelete != 0 {
+		s.garbage += int64(len(record))
+	}
+	return offset, nil
+}
+
+// afterWriteLocked 按配置同步到磁盘，垃圾数据过多时压缩。调用方需持有写锁
+func (s *DiskStorage) afterWriteLocked() error {
+	if s.syncWrites {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("sync data file error: %v", err)
+		}
+		s.dirty = false
+	}
+	if s.garbage > diskMinCompactBytes && s.garbage > s.size {
+		if err := s.compactLocked(); err != nil {
+			log.Printf("[DiskStorage] Compaction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// addLocked 将记录加入索引，调用方需持有写锁
+func (s *DiskStorage) addLocked(key string, entry *diskEntry) {
+	entry.elem = s.order.PushBack(key)
+	s.index[key] = entry
+	s.size += int64(len(key) + entry.size)
+}
+
+// removeLocked 从索引中删除键，其记录成为垃圾数据。调用方需持有写锁
+func (s *DiskStorage) removeLocked(key string) {
+	entry, ok := s.index[key]
+	if !ok {
+		return
+	}
+	s.order.Remove(entry.elem)
+	delete(s.index, key)
+	s.size -= int64(len(key) + entry.size)
+	s.garbage += int64(diskHeaderSize + len(key) + entry.size)
+}
+
+// compactLocked 将有效记录按写入顺序写入临时文件，同步后原子替换数据文件。
+// 替换前崩溃时原数据文件保持完整。调用方需持有写锁
+func (s *DiskStorage) compactLocked() error {
+	tmpPath := filepath.Join(s.dir, diskCompactFile)
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	w := bufio.NewWriter(tmp)
+	offsets := make(map[string]int64, len(s.index))
+	var expired []string
+	var offset int64
+	for e := s.order.Front(); e != nil; e = e.Next() {
+		key := e.Value.(string)
+		entry := s.index[key]
+		if entry.expired(now) {
+			expired = append(expired, key)
+			continue
+		}
+		value := make([]byte, entry.size)
+		if _, err := s.file.ReadAt(value, entry.offset); err != nil {
+			return fail(err)
+		}
+		record := encodeDiskRecord(key, value, entry.expire, entry.flags)
+		if _, err := w.Write(record); err != nil {
+			return fail(err)
+		}
+		offsets[key] = offset + diskHeaderSize + int64(len(key))
+		offset += int64(len(record))
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, diskDataFile)); err != nil {
+		return fail(err)
+	}
+	syncDir(s.dir)
+
+	s.file.Close()
+	s.file = tmp
+	s.offset = offset
+	s.dirty = false
+	for _, key := range expired {
+		s.removeLocked(key)
+	}
+	for key, off := range offsets {
+		s.index[key].offset = off
+	}
+	s.garbage = 0
+	return nil
+}
+
+// background 定期同步未刷盘的写入，并从索引中清理过期的键
+func (s *DiskStorage) background() {
+	defer close(s.done)
+	syncTicker := time.NewTicker(diskSyncInterval)
+	defer syncTicker.Stop()
+	expireTicker := time.NewTicker(diskExpireInterval)
+	defer expireTicker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-syncTicker.C:
+			s.mu.Lock()
+			if s.dirty {
+				if err := s.file.Sync(); err != nil {
+					log.Printf("[DiskStorage] Sync failed: %v", err)
+				} else {
+					s.dirty = false
+				}
+			}
+			s.mu.Unlock()
+		case <-expireTicker.C:
+			s.mu.Lock()
+			now := time.Now().UnixNano()
+			for key, entry := range s.index {
+				// 记录中带有过期时间，重启后同样会被忽略，不需要写删除标记
+				if entry.expired(now) {
+					s.removeLocked(key)
+				}
+			}
+			if s.garbage > diskMinCompactBytes && s.garbage > s.size {
+				if err := s.compactLocked(); err != nil {
+					log.Printf("[DiskStorage] Compaction failed: %v", err)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func encodeDiskRecord(key string, value []byte, expire int64, flags byte) []byte {
+	record := make([]byte, diskHeaderSize+len(key)+len(value))
+	binary.BigEndian.PutUint64(record[4:], uint64(expire))
+	record[12] = flags
+	binary.BigEndian.PutUint32(record[13:], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[17:], uint32(len(value)))
+	copy(record[diskHeaderSize:], key)
+	copy(record[diskHeaderSize+len(key):], value)
+	binary.BigEndian.PutUint32(record, diskChecksum(record[4:diskHeaderSize], record[diskHeaderSize:]))
+	return record
+}
+
+func decodeDiskHeader(header []byte) (expire int64, flags byte, keyLen, valueLen int) {
+	expire = int64(binary.BigEndian.Uint64(header[4:]))
+	flags = header[12]
+	keyLen = int(binary.BigEndian.Uint32(header[13:]))
+	valueLen = int(binary.BigEndian.Uint32(header[17:]))
+	return
+}
+
+// diskChecksum 计算记录头(不含 crc)和键值的校验和
+func diskChecksum(header, body []byte) uint32 {
+	crc := crc32.ChecksumIEEE(header)
+	return crc32.Update(crc, crc32.IEEETable, body)
+}
+
+// syncDir 同步目录，保证重命名持久化
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
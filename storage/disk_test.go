@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openDisk(t *testing.T, options StorageOptions) *DiskStorage {
+	t.Helper()
+	s, err := NewDiskStorage(options)
+	if err != nil {
+		t.Fatalf("Create disk storage error: %v", err)
+	}
+	return s
+}
+
+func TestDiskStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewStorage(StorageTypeDisk, StorageOptions{Path: dir})
+	if err != nil {
+		t.Fatalf("Create disk storage error: %v", err)
+	}
+
+	storage.Set("a", []byte("1"))
+	storage.Set("b", []byte("2"))
+	storage.Set("a", []byte("3"))
+	storage.SetWithExpire("tmp", []byte("x"), 20*time.Millisecond)
+	storage.SetWithExpire("ttl", []byte("y"), time.Hour)
+	storage.Set("deleted", []byte("z"))
+	storage.Delete("deleted")
+
+	if v, err := storage.Get("a"); err != nil || string(v) != "3" {
+		t.Fatalf("Get a = %q, %v", v, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := storage.Get("tmp"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get expired key error = %v, want ErrNotFound", err)
+	}
+	if _, err := storage.Get("deleted"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get deleted key error = %v, want ErrNotFound", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// 重启后恢复最新的值、删除和过期时间
+	reopened := openDisk(t, StorageOptions{Path: dir})
+	defer reopened.Close()
+	for key, want := range map[string]string{"a": "3", "b": "2", "ttl": "y"} {
+		if v, err := reopened.Get(key); err != nil || string(v) != want {
+			t.Fatalf("Get %s after restart = %q, %v, want %q", key, v, err, want)
+		}
+	}
+	if keys, _ := reopened.Keys(); len(keys) != 3 {
+		t.Fatalf("Keys after restart = %v, want a, b and ttl", keys)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if has, _ := reopened.Has("a"); has {
+		t.Fatal("Has should return false after clear")
+	}
+}
+
+// 崩溃时写了一半的记录在重启时被截断，之前的数据不受影响
+func TestDiskStorageTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s := openDisk(t, StorageOptions{Path: dir})
+	s.Set("a", []byte("1"))
+	s.Set("b", []byte("2"))
+	s.Close()
+
+	path := filepath.Join(dir, diskDataFile)
+	info, _ := os.Stat(path)
+	if err := os.Truncate(path, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openDisk(t, StorageOptions{Path: dir})
+	if v, err := s.Get("a"); err != nil || string(v) != "1" {
+		t.Fatalf("Get a = %q, %v", v, err)
+	}
+	if has, _ := s.Has("b"); has {
+		t.Fatal("torn record b should be discarded")
+	}
+	// 截断后可以继续追加
+	s.Set("c", []byte("3"))
+	s.Close()
+
+	s = openDisk(t, StorageOptions{Path: dir})
+	defer s.Close()
+	if v, err := s.Get("c"); err != nil || string(v) != "3" {
+		t.Fatalf("Get c after restart = %q, %v", v, err)
+	}
+}
+
+// 压缩只保留有效记录，超出容量淘汰的键在重启后不会恢复
+func TestDiskStorageCompactAndEviction(t *testing.T) {
+	dir := t.TempDir()
+	s := openDisk(t, StorageOptions{Path: dir, MaxSize: 100, SyncWrites: true})
+	for i := 0; i < 50; i++ {
+		s.Set(fmt.Sprintf("k%d", i%10), bytes.Repeat([]byte{byte(i)}, 8))
+	}
+	if err := s.Set("big", make([]byte, 200)); err == nil {
+		t.Fatal("expected error for value larger than MaxSize")
+	}
+	// 10 个键共 100 字节，写入 k10 后淘汰最早写入的 k0
+	s.Set("k10", []byte("12345678"))
+	if has, _ := s.Has("k0"); has {
+		t.Fatal("oldest key should be evicted")
+	}
+
+	path := filepath.Join(dir, diskDataFile)
+	before, _ := os.Stat(path)
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact error: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("file size after compaction %d, want less than %d", after.Size(), before.Size())
+	}
+	s.Set("k10", []byte("87654321"))
+	s.Close()
+
+	s = openDisk(t, StorageOptions{Path: dir, MaxSize: 100})
+	defer s.Close()
+	if has, _ := s.Has("k0"); has {
+		t.Fatal("evicted key should not come back after restart")
+	}
+	if v, err := s.Get("k9"); err != nil || !bytes.Equal(v, bytes.Repeat([]byte{49}, 8)) {
+		t.Fatalf("Get k9 = %v, %v", v, err)
+	}
+	if v, err := s.Get("k10"); err != nil || string(v) != "87654321" {
+		t.Fatalf("Get k10 = %q, %v", v, err)
+	}
+}
+
+func TestDiskStorageCompression(t *testing.T) {
+	dir := t.TempDir()
+	value := bytes.Repeat([]byte("geecache"), 1000)
+	s := openDisk(t, StorageOptions{Path: dir, Compression: true})
+	s.Set("a", value)
+	s.Close()
+
+	if info, _ := os.Stat(filepath.Join(dir, diskDataFile)); info.Size() >= int64(len(value)) {
+		t.Fatalf("data file size %d, want compressed value", info.Size())
+	}
+	// 关闭压缩后仍能读取之前压缩的值
+	s = openDisk(t, StorageOptions{Path: dir})
+	defer s.Close()
+	if v, err := s.Get("a"); err != nil || !bytes.Equal(v, value) {
+		t.Fatalf("Get compressed value error: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"container/list"
 	"fmt"
 	"log"
 	"sync"
@@ -15,6 +16,10 @@ type MemoryStorage struct {
 	size     int64
 	mu       sync.RWMutex
 
+	// 按写入顺序排列的键，超出容量时先淘汰最早写入的键
+	order    *list.List
+	elements map[string]*list.Element
+
 	// 清理相关
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
@@ -27,6 +32,8 @@ func NewMemoryStorage(options StorageOptions) (*MemoryStorage, error) {
 		data:            make(map[string][]byte),
 		expiries:        make(map[string]time.Time),
 		maxSize:         options.MaxSize,
+		order:           list.New(),
+		elements:        make(map[string]*list.Element),
 		cleanupInterval: 5 * time.Minute, // 默认5分钟清理一次过期数据
 		stopCleanup:     make(chan struct{}),
 	}
@@ -42,16 +49,14 @@ func (s *MemoryStorage) Get(key string) ([]byte, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 检查是否过期
+	// 检查是否过期，过期数据由定时清理删除，读锁下不能修改
 	if expiry, ok := s.expiries[key]; ok && time.Now().After(expiry) {
-		delete(s.data, key)
-		delete(s.expiries, key)
-		return nil, fmt.Errorf("key %s not found", key)
+		return nil, fmt.Errorf("key %s: %w", key, ErrNotFound)
 	}
 
 	value, ok := s.data[key]
 	if !ok {
-		return nil, fmt.Errorf("key %s not found", key)
+		return nil, fmt.Errorf("key %s: %w", key, ErrNotFound)
 	}
 
 	return value, nil
@@ -67,23 +72,22 @@ func (s *MemoryStorage) SetWithExpire(key string, value []byte, expire time.Dura
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 检查存储大小
-	if s.maxSize > 0 {
-		// 如果已存在，先减去旧值的大小
-		if oldValue, ok := s.data[key]; ok {
-			s.size -= int64(len(key) + len(oldValue))
-		}
+	// 单个键值超过容量时无法写入
+	entrySize := int64(len(key) + len(value))
+	if s.maxSize > 0 && entrySize > s.maxSize {
+		return fmt.Errorf("storage size limit exceeded")
+	}
 
-		// 计算新值的大小
-		newSize := s.size + int64(len(key)+len(value))
-		if newSize > s.maxSize {
-			return fmt.Errorf("storage size limit exceeded")
-		}
-		s.size = newSize
+	s.removeLocked(key)
+	// 超出容量时淘汰最早写入的键
+	for s.maxSize > 0 && s.size+entrySize > s.maxSize {
+		s.removeLocked(s.order.Back().Value.(string))
 	}
 
 	// 设置值
 	s.data[key] = value
+	s.elements[key] = s.order.PushFront(key)
+	s.size += entrySize
 
 	// 设置过期时间
 	if expire > 0 {
@@ -100,15 +104,23 @@ func (s *MemoryStorage) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if oldValue, ok := s.data[key]; ok {
-		s.size -= int64(len(key) + len(oldValue))
-		delete(s.data, key)
-		delete(s.expiries, key)
-	}
-
+	s.removeLocked(key)
 	return nil
 }
 
+// removeLocked 删除键并更新存储大小，调用方需持有写锁
+func (s *MemoryStorage) removeLocked(key string) {
+	value, ok := s.data[key]
+	if !ok {
+		return
+	}
+	s.size -= int64(len(key) + len(value))
+	delete(s.data, key)
+	delete(s.expiries, key)
+	s.order.Remove(s.elements[key])
+	delete(s.elements, key)
+}
+
 // Has 判断指定键是否存在
 func (s *MemoryStorage) Has(key string) (bool, error) {
 	s.mu.RLock()
@@ -116,8 +128,6 @@ func (s *MemoryStorage) Has(key string) (bool, error) {
 
 	// 检查是否过期
 	if expiry, ok := s.expiries[key]; ok && time.Now().After(expiry) {
-		delete(s.data, key)
-		delete(s.expiries, key)
 		return false, nil
 	}
 
@@ -150,6 +160,8 @@ func (s *MemoryStorage) Clear() error {
 
 	s.data = make(map[string][]byte)
 	s.expiries = make(map[string]time.Time)
+	s.order.Init()
+	s.elements = make(map[string]*list.Element)
 	s.size = 0
 
 	return nil
@@ -196,8 +208,9 @@ func (s *MemoryStorage) stopCleanupTimer() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// 清理协程可能正在等待锁，关闭通道而不是发送，避免持锁阻塞
 	if s.cleanupRunning {
-		s.stopCleanup <- struct{}{}
+		close(s.stopCleanup)
 		s.cleanupRunning = false
 	}
 }
@@ -213,18 +226,13 @@ func (s *MemoryStorage) cleanExpired() {
 
 	for key, expiry := range s.expiries {
 		if now.After(expiry) {
-			if value, ok := s.data[key]; ok {
-				expiredSize += int64(len(key) + len(value))
-				delete(s.data, key)
-				expiredCount++
-			}
-			delete(s.expiries, key)
+			expiredSize += int64(len(key) + len(s.data[key]))
+			s.removeLocked(key)
+			expiredCount++
 		}
 	}
 
-	// 更新存储大小
 	if expiredCount > 0 {
-		s.size -= expiredSize
 		log.Printf("[MemoryStorage] Cleaned %d expired items, freed %d bytes", expiredCount, expiredSize)
 	}
 }
@@ -1,13 +1,17 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
 
+// ErrNotFound 键不存在或已过期
+var ErrNotFound = errors.New("key not found")
+
 // Storage 存储引擎接口
 type Storage interface {
-	// Get 获取指定键的值
+	// Get 获取指定键的值，键不存在或已过期时返回 ErrNotFound
 	Get(key string) ([]byte, error)
 	// Set 设置指定键的值
 	Set(key string, value []byte) error
@@ -45,6 +49,8 @@ const (
 	StorageTypeCppLevelDB StorageType = "cpp_leveldb"
 	// StorageTypeCppRocksDB C++ RocksDB存储
 	StorageTypeCppRocksDB StorageType = "cpp_rocksdb"
+	// StorageTypeDisk 纯 Go 实现的日志结构磁盘存储
+	StorageTypeDisk StorageType = "disk"
 )
 
 // StorageOptions 存储引擎选项
@@ -55,6 +61,8 @@ type StorageOptions struct {
 	MaxSize int64
 	// Compression 是否启用压缩
 	Compression bool
+	// SyncWrites 每次写入后同步到磁盘，否则每秒后台同步一次(仅磁盘存储)
+	SyncWrites bool
 }
 
 // NewStorage 创建一个新的存储引擎
@@ -62,16 +70,21 @@ func NewStorage(storageType StorageType, options StorageOptions) (Storage, error
 	switch storageType {
 	case StorageTypeMemory:
 		return NewMemoryStorage(options)
-	case StorageTypeCppSkipList:
-		return NewCppSkipListStorage(options)
+	case StorageTypeDisk:
+		s, err := NewDiskStorage(options)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
 	case StorageTypeCppMemory:
-		// return NewCppMemoryStorage(options)
-		return nil, fmt.Errorf("storage type %s not implemented yet", storageType)
-	case StorageTypeCppLevelDB:
-		// return NewCppLevelDBStorage(options)
-		return nil, fmt.Errorf("storage type %s not implemented yet", storageType)
-	case StorageTypeCppRocksDB:
-		// return NewCppRocksDBStorage(options)
+		// C++ 存储引擎需要 cppstorage 构建标签，未启用时返回错误
+		s, err := NewCppMemoryStorage(options)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case StorageTypeCppSkipList, StorageTypeCppLevelDB, StorageTypeCppRocksDB:
+		// C++ 端尚未实现跳表、LevelDB 和 RocksDB 引擎
 		return nil, fmt.Errorf("storage type %s not implemented yet", storageType)
 	case StorageTypeLevelDB, StorageTypeRocksDB, StorageTypeBadger:
 		return nil, fmt.Errorf("storage type %s not implemented yet", storageType)
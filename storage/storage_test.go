@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -358,3 +359,33 @@ func BenchmarkMemoryStorage(b *testing.B) {
 		}
 	})
 }
+
+// 测试不存在和过期的键返回 ErrNotFound，超出容量时淘汰最早写入的键
+func TestMemoryStorageNotFoundAndEviction(t *testing.T) {
+	storage, err := NewMemoryStorage(StorageOptions{MaxSize: 20})
+	if err != nil {
+		t.Fatalf("Create memory storage error: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err := storage.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing key error = %v, want ErrNotFound", err)
+	}
+	storage.SetWithExpire("expiring", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := storage.Get("expiring"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get expired key error = %v, want ErrNotFound", err)
+	}
+
+	storage.Set("a", []byte("123456789"))
+	storage.Set("b", []byte("123456789"))
+	if has, _ := storage.Has("expiring"); has {
+		t.Fatal("oldest key should be evicted")
+	}
+	if has, _ := storage.Has("a"); !has {
+		t.Fatal("a should fit together with b")
+	}
+	if err := storage.Set("big", make([]byte, 30)); err == nil {
+		t.Fatal("expected error for value larger than MaxSize")
+	}
+}
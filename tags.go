@@ -0,0 +1,233 @@
+package geecache
+
+import (
+	"fmt"
+	pb "geecache/geecachepb"
+	"geecache/lru"
+	"sync"
+)
+
+// A GetterWithTags loads data for a key together with tags naming the
+// entities the value was derived from, e.g. "user:42". Group.InvalidateTag
+// removes every cached key loaded with a tag.
+//
+// Deprecated: implement GetterWithMetadata instead.
+type GetterWithTags interface {
+	GetWithTags(key string) ([]byte, []string, error)
+}
+
+// A GetterWithTagsFunc implements Getter and GetterWithTags with a function.
+type GetterWithTagsFunc func(key string) ([]byte, []string, error)
+
+// Get implements Getter interface function
+func (f GetterWithTagsFunc) Get(key string) ([]byte, error) {
+	v, _, err := f(key)
+	return v, err
+}
+
+// GetWithTags implements GetterWithTags interface function
+func (f GetterWithTagsFunc) GetWithTags(key string) ([]byte, []string, error) {
+	return f(key)
+}
+
+const (
+	// tag索引占组缓存容量的比例(1/8)
+	tagIndexRatio = 8
+	// 索引中每个key的额外开销估计
+	tagKeyOverhead = 16
+)
+
+// tagEntry 是索引中一个tag对应的key集合，按值保存以便 lru.Cache 重新计算大小
+type tagEntry struct {
+	keys  map[string]struct{}
+	bytes int
+}
+
+func (e tagEntry) Len() int {
+	return e.bytes
+}
+
+// tagIndex 是拥有者节点上 tag→keys 的索引，用 lru.Cache 限制大小。
+// 缓存淘汰key时从索引中删除，索引淘汰tag时其下的key也从缓存中删除，
+// 保证缓存中带tag的key都能通过索引找到
+type tagIndex struct {
+	mu       sync.Mutex
+	maxBytes int64
+	tags     *lru.Cache
+	keyTags  map[string][]string // key所带的tag，用于从索引中删除key
+	dropped  []string            // 因tag被淘汰需要从缓存中删除的key
+}
+
+func newTagIndex(maxBytes int64) *tagIndex {
+	t := &tagIndex{maxBytes: maxBytes}
+	t.resetLocked()
+	return t
+}
+
+func (t *tagIndex) resetLocked() {
+	t.tags = lru.New(t.maxBytes, t.onEvicted)
+	t.keyTags = make(map[string][]string)
+	t.dropped = nil
+}
+
+// onEvicted 是 lru.Cache 的回调，记录被淘汰的tag下的key，由 drainLocked 处理
+func (t *tagIndex) onEvicted(tag string, value lru.Value) {
+	for key := range value.(tagEntry).keys {
+		t.dropped = append(t.dropped, key)
+	}
+}
+
+// add 记录key带有的tag，返回因索引已满需要从缓存中删除的key
+func (t *tagIndex) add(key string, tags []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tag := range tags {
+		if containsString(t.keyTags[key], tag) {
+			continue
+		}
+		t.keyTags[key] = append(t.keyTags[key], tag)
+		entry := tagEntry{keys: make(map[string]struct{})}
+		if v, ok := t.tags.Get(tag); ok {
+			entry = v.(tagEntry)
+		}
+		entry.keys[key] = struct{}{}
+		entry.bytes += len(key) + tagKeyOverhead
+		t.tags.Add(tag, entry)
+	}
+	return t.drainLocked()
+}
+
+// drainLocked 将被淘汰的tag下的key从其余tag中删除，返回这些key
+func (t *tagIndex) drainLocked() []string {
+	var dropped []string
+	for len(t.dropped) > 0 {
+		key := t.dropped[0]
+		t.dropped = t.dropped[1:]
+		if _, ok := t.keyTags[key]; ok {
+			t.forgetLocked(key)
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
+
+// forget 在key从缓存中删除时将其从索引中删除，返回key是否带有tag
+func (t *tagIndex) forget(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, tagged := t.keyTags[key]
+	t.forgetLocked(key)
+	return tagged
+}
+
+func (t *tagIndex) forgetLocked(key string) {
+	tags := t.keyTags[key]
+	delete(t.keyTags, key)
+	for _, tag := range tags {
+		v, ok := t.tags.Get(tag)
+		if !ok {
+			continue
+		}
+		entry := v.(tagEntry)
+		if _, ok := entry.keys[key]; !ok {
+			continue
+		}
+		delete(entry.keys, key)
+		entry.bytes -= len(key) + tagKeyOverhead
+		if len(entry.keys) == 0 {
+			t.tags.Remove(tag)
+			continue
+		}
+		t.tags.Add(tag, entry)
+	}
+}
+
+// take 从索引中删除tag，返回带有该tag的key
+func (t *tagIndex) take(tag string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.tags.Get(tag)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(v.(tagEntry).keys))
+	for key := range v.(tagEntry).keys {
+		keys = append(keys, key)
+	}
+	// 先删除key，tag随最后一个key一起从索引中删除，不会触发淘汰
+	for _, key := range keys {
+		t.forgetLocked(key)
+	}
+	return keys
+}
+
+// clear 清空索引
+func (t *tagIndex) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resetLocked()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tagKey 将回源得到的tag加入索引，索引已满时删除被淘汰的tag下的key
+func (g *Group) tagKey(key string, tags []string) {
+	if g.tags == nil || len(tags) == 0 {
+		return
+	}
+	for _, dropped := range g.tags.add(key, tags) {
+		g.stats.tagEvictions.Add(1)
+		g.mainCache.remove(dropped)
+		g.removeFromL2(dropped)
+	}
+}
+
+// onEvicted 处理一级缓存因容量淘汰的数据：从tag索引中删除，不带tag的数据写入二级缓存。
+// 带tag的数据不写入二级缓存，避免从二级缓存取回后无法通过索引找到
+func (g *Group) onEvicted(key string, value ByteView) {
+	if g.tags != nil && g.tags.forget(key) {
+		return
+	}
+	if g.l2 != nil {
+		g.spillToL2(key, value)
+	}
+}
+
+// InvalidateTag 删除整个集群中带有 tag 的key。tag索引保存在回源的拥有者节点上，
+// 请求发送给所有节点(PeerPicker 需要实现 PeerLister)，各节点通过 Remove 删除
+// 自己索引中的key及其副本、热点备份和近端缓存
+func (g *Group) InvalidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("tag is required")
+	}
+	err := g.invalidateTagLocally(tag)
+	if berr := g.broadcast(&pb.Request{
+		Group:      g.name,
+		Tag:        tag,
+		Generation: g.Generation(),
+	}); err == nil {
+		err = berr
+	}
+	return err
+}
+
+// invalidateTagLocally 删除本节点索引中带有 tag 的key
+func (g *Group) invalidateTagLocally(tag string) error {
+	if g.tags == nil {
+		return nil
+	}
+	var firstErr error
+	for _, key := range g.tags.take(tag) {
+		if err := g.Remove(key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
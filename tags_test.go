@@ -0,0 +1,151 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTaggedGroup 的每个key带有 "user:<key中冒号之后的部分>" 的tag
+func newTaggedGroup(name string, cacheBytes int64, loads *int) *Group {
+	return NewGroup(name, cacheBytes, GetterWithTagsFunc(func(key string) ([]byte, []string, error) {
+		*loads++
+		id := key[strings.IndexByte(key, ':')+1:]
+		return []byte("v-" + key), []string{"user:" + id}, nil
+	}))
+}
+
+func TestInvalidateTag(t *testing.T) {
+	loads := 0
+	g := newTaggedGroup("tag-invalidate", 2<<10, &loads)
+	for _, key := range []string{"profile:42", "feed:42", "profile:7"} {
+		g.Get(key)
+	}
+
+	// 带有该tag的key都被删除，其他key不受影响
+	picker := &listPicker{fakePicker{owner: &fakePeer{}}}
+	g.RegisterPeers(picker)
+	if err := g.InvalidateTag("user:42"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"profile:42", "feed:42"} {
+		if _, ok := g.mainCache.get(key); ok {
+			t.Fatalf("%s should be invalidated", key)
+		}
+	}
+	if _, ok := g.mainCache.get("profile:7"); !ok {
+		t.Fatal("profile:7 should stay cached")
+	}
+
+	// 请求广播给其他节点，索引在其他节点上的key由它们删除
+	deleted := append([]string(nil), picker.owner.deleted...)
+	sort.Strings(deleted)
+	if want := []string{"", "feed:42", "profile:42"}; fmt.Sprint(deleted) != fmt.Sprint(want) {
+		t.Fatalf("peer deletes = %q, want %q", deleted, want)
+	}
+	if keys := g.tags.take("user:42"); len(keys) != 0 {
+		t.Fatalf("tag should be removed from the index, got %v", keys)
+	}
+}
+
+func TestTagIndexBounded(t *testing.T) {
+	idx := newTagIndex(80)
+	if dropped := idx.add("a", []string{"t1", "t2"}); len(dropped) != 0 {
+		t.Fatalf("unexpected drops %v", dropped)
+	}
+	idx.add("b", []string{"t2"})
+
+	// 缓存淘汰key时从所有tag中删除
+	if !idx.forget("a") || idx.forget("a") {
+		t.Fatal("forget should report whether the key was tagged")
+	}
+	if keys := idx.take("t2"); fmt.Sprint(keys) != "[b]" {
+		t.Fatalf("take(t2) = %v, want [b]", keys)
+	}
+
+	// 索引已满时淘汰最久未使用的tag，其下的key需要从缓存中删除
+	idx.add("c", []string{"t3"})
+	idx.add("d", []string{"t3", "t4"})
+	dropped := idx.add("some-long-key", []string{"t5"})
+	sort.Strings(dropped)
+	if fmt.Sprint(dropped) != "[c d]" {
+		t.Fatalf("dropped = %v, want the keys of the evicted tag", dropped)
+	}
+	if keys := idx.take("t4"); len(keys) != 0 {
+		t.Fatalf("dropped keys should be removed from their other tags, got %v", keys)
+	}
+}
+
+func TestTagIndexFollowsCache(t *testing.T) {
+	// 一级缓存只能容纳约 20 个值，淘汰的key从索引中删除
+	g := NewGroup("tag-evict", 8000, GetterWithTagsFunc(func(key string) ([]byte, []string, error) {
+		return make([]byte, 400), []string{"tag-" + key}, nil
+	}))
+	for i := 0; i < 200; i++ {
+		g.Get(fmt.Sprintf("k:%d", i))
+	}
+	cached := 0
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("k:%d", i)
+		_, inCache := g.mainCache.get(key)
+		if inCache {
+			cached++
+		}
+		g.tags.mu.Lock()
+		_, indexed := g.tags.keyTags[key]
+		g.tags.mu.Unlock()
+		if indexed != inCache {
+			t.Fatalf("%s cached=%v indexed=%v", key, inCache, indexed)
+		}
+	}
+	if cached == 0 || cached == 200 || g.GetStats().TagEvictions != 0 {
+		t.Fatalf("expected the cache to evict keys, %d cached, %+v", cached, g.GetStats())
+	}
+}
+
+// ttlTagGetter 同时实现 GetterWithTTL 和 GetterWithTags
+type ttlTagGetter struct{}
+
+func (ttlTagGetter) Get(key string) ([]byte, error) { return []byte(key), nil }
+
+func (ttlTagGetter) GetWithTTL(key string) ([]byte, time.Time, error) {
+	return []byte(key), time.Now().Add(time.Minute), nil
+}
+
+func (ttlTagGetter) GetWithTags(key string) ([]byte, []string, error) {
+	return []byte(key), []string{"t"}, nil
+}
+
+func TestGetterWithMetadata(t *testing.T) {
+	var gotCtx context.Context
+	ctx := context.WithValue(context.Background(), ttlTagGetter{}, 1)
+	g := NewGroup("tag-metadata", 2<<10, GetterWithMetadataFunc(
+		func(ctx context.Context, key string) ([]byte, Metadata, error) {
+			gotCtx = ctx
+			return []byte(key), Metadata{Expire: time.Now().Add(time.Minute), Tags: []string{"user:" + key}}, nil
+		}))
+
+	// 过期时间、tag和ctx都生效
+	view, err := g.GetContext(ctx, "42")
+	if err != nil || view.Expire().IsZero() || gotCtx == nil || gotCtx.Value(ttlTagGetter{}) != 1 {
+		t.Fatalf("GetContext(42) = %q expire %v, %v, getter ctx %v", view, view.Expire(), err, gotCtx)
+	}
+	if err := g.InvalidateTag("user:42"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.mainCache.get("42"); ok {
+		t.Fatal("key loaded with metadata tags should be invalidated")
+	}
+
+	// 同时实现多个已弃用的接口时，按文档中的顺序只使用 GetterWithTTL
+	mixed := NewGroup("tag-ttl-mixed", 2<<10, ttlTagGetter{})
+	if view, err := mixed.Get("k"); err != nil || view.Expire().IsZero() {
+		t.Fatalf("Get(k) = %q expire %v, %v; want the TTL getter to be used", view, view.Expire(), err)
+	}
+	if mixed.tags != nil {
+		t.Fatal("tag index should not be enabled when GetWithTags is never called")
+	}
+}
@@ -0,0 +1,127 @@
+package geecache
+
+import (
+	"encoding/binary"
+	"errors"
+	"geecache/storage"
+	"log"
+	"time"
+)
+
+// L2Options 二级缓存的容量和过期策略，容量上限由存储引擎自身的 MaxSize 控制
+type L2Options struct {
+	// MaxEntryBytes 超过该大小的值不写入二级缓存，0 表示不限制
+	MaxEntryBytes int
+	// TTL 值在二级缓存中的最长存活时间，0 表示只使用值自身的过期时间
+	TTL time.Duration
+}
+
+// l2Tier 是组的二级缓存，保存从一级缓存中淘汰的数据
+type l2Tier struct {
+	store storage.Storage
+	opts  L2Options
+}
+
+// SetL2 为组配置二级缓存：一级缓存因容量淘汰的数据写入 store，
+// 一级缓存未命中时先查询二级缓存，再查询其他节点和 Getter。
+// 多个组可以共用同一个存储，键以组名为前缀。应在使用组之前调用
+func (g *Group) SetL2(store storage.Storage, opts L2Options) {
+	g.l2 = &l2Tier{store: store, opts: opts}
+	g.mainCache.onEvicted = g.onEvicted
+}
+
+// l2Key 返回key在二级缓存中的键
+func (g *Group) l2Key(key string) string {
+	return g.name + "/" + key
+}
+
+// spillToL2 将一级缓存淘汰的数据写入二级缓存
+func (g *Group) spillToL2(key string, value ByteView) {
+	l2 := g.l2
+	if l2.opts.MaxEntryBytes > 0 && value.Len() > l2.opts.MaxEntryBytes {
+		return
+	}
+
+	// 二级缓存中的存活时间不超过值自身的过期时间
+	ttl := l2.opts.TTL
+	if !value.e.IsZero() {
+		remaining := time.Until(value.e)
+		if remaining <= 0 {
+			return
+		}
+		if ttl == 0 || remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	if err := l2.store.SetWithExpire(g.l2Key(key), encodeL2(value), ttl); err != nil {
+		log.Printf("[GeeCache] Failed to spill %s to L2: %v", key, err)
+		return
+	}
+	g.stats.l2Spills.Add(1)
+}
+
+// getFromL2 查询二级缓存，命中的数据移回一级缓存
+func (g *Group) getFromL2(key string) (ByteView, bool) {
+	if g.l2 == nil {
+		return ByteView{}, false
+	}
+	data, err := g.l2.store.Get(g.l2Key(key))
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[GeeCache] Failed to get %s from L2: %v", key, err)
+		}
+		g.stats.l2Misses.Add(1)
+		return ByteView{}, false
+	}
+	value, ok := decodeL2(data)
+	if !ok || value.expired(time.Now()) {
+		g.stats.l2Misses.Add(1)
+		return ByteView{}, false
+	}
+	g.stats.l2Hits.Add(1)
+
+	// 数据只保存在其中一层，再次被淘汰时重新写入二级缓存
+	g.l2.store.Delete(g.l2Key(key))
+	g.mainCache.add(key, value)
+	return value, true
+}
+
+// removeFromL2 删除二级缓存中的key
+func (g *Group) removeFromL2(key string) {
+	if g.l2 == nil {
+		return
+	}
+	if err := g.l2.store.Delete(g.l2Key(key)); err != nil {
+		log.Printf("[GeeCache] Failed to remove %s from L2: %v", key, err)
+	}
+}
+
+// lookupLocally 依次查询本节点的一级和二级缓存
+func (g *Group) lookupLocally(key string) (ByteView, bool) {
+	if view, ok := g.mainCache.get(key); ok {
+		return view, true
+	}
+	return g.getFromL2(key)
+}
+
+// encodeL2 将过期时间(UnixNano，0 表示永不过期)编码在值之前
+func encodeL2(value ByteView) []byte {
+	data := make([]byte, 8+value.Len())
+	if !value.e.IsZero() {
+		binary.BigEndian.PutUint64(data, uint64(value.e.UnixNano()))
+	}
+	copy(data[8:], value.b)
+	return data
+}
+
+func decodeL2(data []byte) (ByteView, bool) {
+	if len(data) < 8 {
+		return ByteView{}, false
+	}
+	view := ByteView{b: cloneBytes(data[8:])}
+	if expire := binary.BigEndian.Uint64(data); expire > 0 {
+		view.e = time.Unix(0, int64(expire))
+	}
+	return view, true
+}
@@ -0,0 +1,89 @@
+package geecache
+
+import (
+	"fmt"
+	"geecache/storage"
+	"testing"
+	"time"
+)
+
+func newL2Group(t *testing.T, name string, opts L2Options) (*Group, storage.Storage, *int) {
+	t.Helper()
+	loads := 0
+	// 每个值占用 len(key)+10 字节，一级缓存只能容纳两个值
+	g := NewGroup(name, 2*(2+10), GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(fmt.Sprintf("%-10s", key)), nil
+	}))
+	store, err := storage.NewMemoryStorage(storage.StorageOptions{})
+	if err != nil {
+		t.Fatalf("Create memory storage error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	g.SetL2(store, opts)
+	return g, store, &loads
+}
+
+func TestL2SpillAndPromote(t *testing.T) {
+	g, store, loads := newL2Group(t, "l2-spill", L2Options{})
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		if _, err := g.Get(key); err != nil {
+			t.Fatalf("Get(%s) error: %v", key, err)
+		}
+	}
+	// k1 被淘汰后写入二级缓存
+	if has, _ := store.Has("l2-spill/k1"); !has {
+		t.Fatal("evicted k1 should be spilled to L2")
+	}
+
+	// 一级缓存未命中时从二级缓存读取，不回源
+	if view, err := g.Get("k1"); err != nil || view.String() != "k1        " || *loads != 3 {
+		t.Fatalf("Get(k1) = %q, %v with %d loads", view, err, *loads)
+	}
+	if _, ok := g.mainCache.get("k1"); !ok {
+		t.Fatal("L2 hit should be promoted to L1")
+	}
+	stats := g.GetStats()
+	if stats.L2Hits != 1 || stats.L2Spills < 2 || stats.Misses != 4 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 删除key时同时删除二级缓存中的副本
+	if err := g.Remove("k2"); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if has, _ := store.Has("l2-spill/k2"); has {
+		t.Fatal("removed key should be deleted from L2")
+	}
+	if _, err := g.Get("k2"); err != nil || *loads != 4 {
+		t.Fatalf("Get(k2) after remove should load from source, loads %d", *loads)
+	}
+}
+
+func TestL2Policies(t *testing.T) {
+	g, store, loads := newL2Group(t, "l2-policy", L2Options{MaxEntryBytes: 10, TTL: 50 * time.Millisecond})
+
+	g.Get("k1")
+	g.Get("k2")
+	g.Get("k3")
+	if has, _ := store.Has("l2-policy/k1"); !has {
+		t.Fatal("k1 should be spilled to L2")
+	}
+	// 超过 TTL 后二级缓存中的值失效
+	time.Sleep(60 * time.Millisecond)
+	if _, err := g.Get("k1"); err != nil || *loads != 4 {
+		t.Fatalf("expired L2 entry should be reloaded, loads %d", *loads)
+	}
+
+	// 超过 MaxEntryBytes 的值不写入二级缓存
+	big := NewGroup("l2-big", 20, GetterFunc(func(key string) ([]byte, error) {
+		return make([]byte, 16), nil
+	}))
+	big.SetL2(store, L2Options{MaxEntryBytes: 10})
+	big.Get("a")
+	big.Get("b")
+	if stats := big.GetStats(); stats.L2Spills != 0 {
+		t.Fatalf("large values should not be spilled, stats %+v", stats)
+	}
+}
@@ -0,0 +1,209 @@
+package tinylfu
+
+import (
+	"container/list"
+	"geecache/cmsketch"
+	"geecache/lru"
+)
+
+const (
+	windowPercent    = 1  // 窗口区占总容量的百分比
+	protectedPercent = 80 // 保护区占主区容量的百分比
+	sketchDepth      = 4
+	// 假定的平均条目大小，用于根据字节容量估算计数器数量
+	avgEntryBytes = 64
+	minSketchSize = 1024
+	maxSketchSize = 1 << 20
+)
+
+// Cache is a W-TinyLFU cache bounded by bytes. New entries enter a small LRU
+// window; when they leave the window, a count-min sketch decides whether
+// they are admitted into the main segmented LRU (probation + protected) by
+// comparing their frequency with the main victim's. It is not safe for
+// concurrent access.
+type Cache struct {
+	maxBytes     int64
+	windowBytes  int64 // 窗口区容量
+	mainBytes    int64 // 主区容量
+	protectBytes int64 // 保护区容量
+
+	window, probation, protected *segment
+	cache                        map[string]*list.Element
+
+	sketch    *cmsketch.Sketch
+	additions int // 自上次衰减以来的访问次数
+	resetAt   int // 访问次数达到该值时将所有计数减半
+
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value Value)
+}
+
+// Value use Len to count how many bytes it takes
+type Value = lru.Value
+
+type entry struct {
+	key   string
+	value Value
+	size  int64
+	seg   *segment
+}
+
+// segment 是一个带字节统计的 LRU 链表，表头为最近使用
+type segment struct {
+	ll    *list.List
+	bytes int64
+}
+
+func newSegment() *segment {
+	return &segment{ll: list.New()}
+}
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
+	width := int(maxBytes / avgEntryBytes)
+	if width < minSketchSize {
+		width = minSketchSize
+	}
+	if width > maxSketchSize {
+		width = maxSketchSize
+	}
+	windowBytes := maxBytes * windowPercent / 100
+	mainBytes := maxBytes - windowBytes
+	sketch := cmsketch.New(width, sketchDepth)
+	return &Cache{
+		maxBytes:     maxBytes,
+		windowBytes:  windowBytes,
+		mainBytes:    mainBytes,
+		protectBytes: mainBytes * protectedPercent / 100,
+		window:       newSegment(),
+		probation:    newSegment(),
+		protected:    newSegment(),
+		cache:        make(map[string]*list.Element),
+		sketch:       sketch,
+		resetAt:      10 * sketch.Width(),
+		OnEvicted:    onEvicted,
+	}
+}
+
+// Add adds a value to the cache.
+func (c *Cache) Add(key string, value Value) {
+	c.increment(key)
+	size := int64(len(key)) + int64(value.Len())
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		kv.seg.bytes += size - kv.size
+		kv.value, kv.size = value, size
+		c.touch(ele)
+	} else {
+		kv := &entry{key: key, value: value, size: size, seg: c.window}
+		c.cache[key] = c.window.ll.PushFront(kv)
+		c.window.bytes += size
+	}
+	c.evict()
+}
+
+// Get look ups a key's value
+func (c *Cache) Get(key string) (value Value, ok bool) {
+	c.increment(key)
+	if ele, ok := c.cache[key]; ok {
+		c.touch(ele)
+		return ele.Value.(*entry).value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache.
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+	}
+}
+
+// Len the number of cache entries
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Size returns the number of bytes used by the cache
+func (c *Cache) Size() int64 {
+	return c.window.bytes + c.probation.bytes + c.protected.bytes
+}
+
+// increment 记录一次访问，访问次数达到阈值后将所有计数减半，使历史频率逐渐衰减
+func (c *Cache) increment(key string) {
+	c.sketch.Increment(key)
+	c.additions++
+	if c.additions >= c.resetAt {
+		c.sketch.Halve()
+		c.additions /= 2
+	}
+}
+
+// touch 处理一次命中：观察区的条目晋升到保护区，其余条目移到所在链表表头
+func (c *Cache) touch(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	if kv.seg != c.probation {
+		kv.seg.ll.MoveToFront(ele)
+		return
+	}
+	c.move(ele, c.protected)
+	// 保护区超出容量时，将最久未使用的条目降级回观察区
+	for c.protected.bytes > c.protectBytes && c.protected.ll.Len() > 1 {
+		c.move(c.protected.ll.Back(), c.probation)
+	}
+}
+
+// evict 窗口区超出容量时，将窗口中最久未使用的条目作为候选者，
+// 与主区的淘汰对象比较访问频率，频率更高者留下
+func (c *Cache) evict() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.window.bytes > c.windowBytes && c.window.ll.Len() > 0 {
+		c.move(c.window.ll.Back(), c.probation)
+		candidate := c.probation.ll.Front()
+		ck := candidate.Value.(*entry)
+		for c.probation.bytes+c.protected.bytes > c.mainBytes {
+			victim := c.probation.ll.Back()
+			if victim == candidate {
+				// 观察区中只有候选者，从保护区选择淘汰对象
+				victim = c.protected.ll.Back()
+			}
+			if victim == nil || c.sketch.Estimate(ck.key) <= c.sketch.Estimate(victim.Value.(*entry).key) {
+				c.removeElement(candidate)
+				break
+			}
+			c.removeElement(victim)
+		}
+	}
+	// 兜底：总容量仍超出时从窗口和观察区淘汰
+	for c.Size() > c.maxBytes {
+		switch {
+		case c.window.ll.Len() > 0:
+			c.removeElement(c.window.ll.Back())
+		case c.probation.ll.Len() > 0:
+			c.removeElement(c.probation.ll.Back())
+		default:
+			c.removeElement(c.protected.ll.Back())
+		}
+	}
+}
+
+func (c *Cache) move(ele *list.Element, to *segment) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.bytes -= kv.size
+	kv.seg = to
+	c.cache[kv.key] = to.ll.PushFront(kv)
+	to.bytes += kv.size
+}
+
+func (c *Cache) removeElement(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.bytes -= kv.size
+	delete(c.cache, kv.key)
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
@@ -0,0 +1,76 @@
+package tinylfu
+
+import (
+	"fmt"
+	"testing"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	c := New(int64(0), nil)
+	c.Add("key1", String("1234"))
+	if v, ok := c.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := c.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	// 每个条目 10 字节，最多容纳 10 个条目
+	c := New(int64(100), nil)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("hot%02d", i)
+		for j := 0; j < 5; j++ {
+			c.Add(key, String("aaaa"))
+		}
+	}
+
+	// 只访问一次的冷数据不应挤掉高频数据
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("cold%d", i), String("bbb"))
+	}
+
+	hits := 0
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot%02d", i)); ok {
+			hits++
+		}
+	}
+	if hits < 8 {
+		t.Fatalf("expected most hot keys to survive, got %d hits", hits)
+	}
+	if c.Size() > 100 {
+		t.Fatalf("cache size %d exceeds limit", c.Size())
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	evicted := 0
+	c := New(int64(20), func(key string, value Value) {
+		evicted++
+	})
+	for i := 0; i < 10; i++ {
+		c.Add(fmt.Sprintf("k%d", i), String("12345678"))
+	}
+	if c.Len()+evicted != 10 {
+		t.Fatalf("expected %d evictions, got %d", 10-c.Len(), evicted)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	c := New(int64(0), nil)
+	c.Add("key1", String("1234"))
+	c.Get("key1")
+	c.Remove("key1")
+
+	if c.Len() != 0 || c.Size() != 0 {
+		t.Fatalf("Remove key1 failed, len=%d size=%d", c.Len(), c.Size())
+	}
+}
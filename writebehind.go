@@ -0,0 +1,285 @@
+package geecache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"geecache/storage"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteBehindOptions 写回队列的批量和重试策略
+type WriteBehindOptions struct {
+	// Store 持久化尚未写入数据源的数据，nil 时只保存在内存中，进程退出时丢失。
+	// 可以与二级缓存共用同一个存储
+	Store storage.Storage
+	// BatchSize 每批写入的最大数量，积压达到该数量时立即写入，默认 100
+	BatchSize int
+	// FlushInterval 定期写入的间隔，默认 1 秒
+	FlushInterval time.Duration
+	// RetryBackoff 写入失败后的初始重试间隔，每次失败翻倍，默认 100 毫秒
+	RetryBackoff time.Duration
+	// MaxBackoff 重试间隔的上限，默认 30 秒
+	MaxBackoff time.Duration
+}
+
+const (
+	defaultWriteBatchSize     = 100
+	defaultWriteFlushInterval = time.Second
+	defaultWriteRetryBackoff  = 100 * time.Millisecond
+	defaultWriteMaxBackoff    = 30 * time.Second
+)
+
+// pendingWrite 是等待写入数据源的值，seq 区分同一key的先后写入
+type pendingWrite struct {
+	key   string
+	value []byte
+	seq   uint64
+}
+
+// writeBehind 是一个组的写回队列。同一key的多次写入只保留最新的值，
+// 按写入顺序分批交给 Setter，写入成功后才从队列和存储中删除
+type writeBehind struct {
+	setter Setter
+	opts   WriteBehindOptions
+	prefix string // 在存储中的键前缀
+
+	mu      sync.Mutex
+	pending map[string]*pendingWrite
+	seq     uint64
+
+	flushMu sync.Mutex // 保证同一时间只有一批数据在写入
+	notify  chan struct{}
+	stopc   chan struct{} // 关闭时后台写入退出
+	done    chan struct{} // 后台写入退出后关闭
+}
+
+func newWriteBehind(group string, setter Setter, opts WriteBehindOptions) (*writeBehind, error) {
+	if setter == nil {
+		panic("nil Setter")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultWriteBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultWriteFlushInterval
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultWriteRetryBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultWriteMaxBackoff
+	}
+	w := &writeBehind{
+		setter:  setter,
+		opts:    opts,
+		prefix:  "writebehind/" + group + "/",
+		pending: make(map[string]*pendingWrite),
+		notify:  make(chan struct{}, 1),
+	}
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	w.start()
+	return w, nil
+}
+
+// start 启动后台写入
+func (w *writeBehind) start() {
+	w.stopc = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.run(w.stopc, w.done)
+}
+
+// close 停止后台写入并写入队列中剩余的数据，写入失败时恢复后台写入并返回错误
+func (w *writeBehind) close() error {
+	close(w.stopc)
+	<-w.done
+	if err := w.flushAll(); err != nil {
+		w.start()
+		return err
+	}
+	return nil
+}
+
+// recover 从存储中恢复上次退出时尚未写入数据源的数据
+func (w *writeBehind) recover() error {
+	if w.opts.Store == nil {
+		return nil
+	}
+	keys, err := w.opts.Store.Keys()
+	if err != nil {
+		return fmt.Errorf("load write-behind queue: %v", err)
+	}
+	for _, storeKey := range keys {
+		if !strings.HasPrefix(storeKey, w.prefix) {
+			continue
+		}
+		data, err := w.opts.Store.Get(storeKey)
+		if err != nil || len(data) < 8 {
+			continue
+		}
+		p := &pendingWrite{
+			key:   strings.TrimPrefix(storeKey, w.prefix),
+			value: data[8:],
+			seq:   binary.BigEndian.Uint64(data),
+		}
+		w.pending[p.key] = p
+		if p.seq > w.seq {
+			w.seq = p.seq
+		}
+	}
+	if len(w.pending) > 0 {
+		log.Printf("[GeeCache] Recovered %d pending writes", len(w.pending))
+	}
+	return nil
+}
+
+// enqueue 将值加入队列，持久化成功后才返回
+func (w *writeBehind) enqueue(key string, value []byte) error {
+	w.mu.Lock()
+	w.seq++
+	p := &pendingWrite{key: key, value: value, seq: w.seq}
+	if w.opts.Store != nil {
+		data := make([]byte, 8+len(value))
+		binary.BigEndian.PutUint64(data, p.seq)
+		copy(data[8:], value)
+		if err := w.opts.Store.Set(w.prefix+key, data); err != nil {
+			w.mu.Unlock()
+			return fmt.Errorf("persist write of %s: %v", key, err)
+		}
+	}
+	w.pending[key] = p
+	full := len(w.pending) >= w.opts.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// get 返回尚未写入数据源的值，缓存淘汰后读取时不能从数据源加载旧值
+func (w *writeBehind) get(key string) ([]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p, ok := w.pending[key]; ok {
+		return p.value, true
+	}
+	return nil, false
+}
+
+// nextBatch 按写入顺序取出最多 BatchSize 个待写入的值
+func (w *writeBehind) nextBatch() []pendingWrite {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make([]pendingWrite, 0, len(w.pending))
+	for _, p := range w.pending {
+		batch = append(batch, *p)
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].seq < batch[j].seq })
+	if len(batch) > w.opts.BatchSize {
+		batch = batch[:w.opts.BatchSize]
+	}
+	return batch
+}
+
+// complete 删除已写入数据源的值，写入期间又被更新的key保留在队列中
+func (w *writeBehind) complete(p pendingWrite) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cur, ok := w.pending[p.key]; !ok || cur.seq != p.seq {
+		return
+	}
+	delete(w.pending, p.key)
+	if w.opts.Store != nil {
+		if err := w.opts.Store.Delete(w.prefix + p.key); err != nil {
+			log.Printf("[GeeCache] Failed to remove %s from write-behind queue: %v", p.key, err)
+		}
+	}
+}
+
+// flushBatch 写入一批数据，返回写入的数量
+func (w *writeBehind) flushBatch() (int, error) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	batch := w.nextBatch()
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if bs, ok := w.setter.(BatchSetter); ok {
+		values := make(map[string][]byte, len(batch))
+		for _, p := range batch {
+			values[p.key] = p.value
+		}
+		if err := bs.SetMany(values); err != nil {
+			return 0, err
+		}
+		for _, p := range batch {
+			w.complete(p)
+		}
+		return len(batch), nil
+	}
+	for i, p := range batch {
+		// 遇到错误时停止，保持写入顺序
+		if err := w.setter.Set(p.key, p.value); err != nil {
+			return i, err
+		}
+		w.complete(p)
+	}
+	return len(batch), nil
+}
+
+// flushAll 写入队列中的所有数据，遇到错误时返回
+func (w *writeBehind) flushAll() error {
+	for {
+		n, err := w.flushBatch()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// run 定期或积压达到批量大小时写入数据源，失败时按指数退避重试，stop 关闭时退出
+func (w *writeBehind) run(stop, done chan struct{}) {
+	defer close(done)
+	backoff := w.opts.RetryBackoff
+	for {
+		select {
+		case <-w.notify:
+		case <-time.After(w.opts.FlushInterval):
+		case <-stop:
+			return
+		}
+		for {
+			n, err := w.flushBatch()
+			if err == nil {
+				backoff = w.opts.RetryBackoff
+				if n == 0 {
+					break
+				}
+				continue
+			}
+			log.Printf("[GeeCache] Write-behind flush failed, retrying in %v: %v", backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-stop:
+				return
+			}
+			backoff *= 2
+			if backoff > w.opts.MaxBackoff {
+				backoff = w.opts.MaxBackoff
+			}
+		}
+	}
+}